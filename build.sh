@@ -19,6 +19,10 @@ cd ..
 echo "🐧 Building for Linux..."
 CGO_ENABLED=1 ~/go/bin/wails build -platform linux/amd64 -o ../bin/nmb -ldflags="-s -w"
 
+# Headless server for drones; a plain Go build needs no webview libraries
+echo "🖥️  Building headless server..."
+CGO_ENABLED=0 go build -o bin/nmb-server -ldflags="-s -w" .
+
 # Build for Windows with optimizations
 #echo "🪟 Building for Windows..."
 #CGO_ENABLED=1 GOOS=windows GOARCH=amd64 ~/go/bin/wails build -platform windows/amd64 -o ../bin/nmb.exe -ldflags="-s -w"
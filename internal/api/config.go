@@ -0,0 +1,299 @@
+package api
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"flag"
+	"fmt"
+	"math/big"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DefaultAddr only accepts connections from the machine running NMB
+const DefaultAddr = "127.0.0.1:8080"
+
+// tokenFile holds the API token in the user's NMB config folder
+const tokenFile = "api_token"
+
+// wsProtocol is the WebSocket subprotocol of /ws. Browsers cannot set
+// headers on WebSocket connections, so the UI offers the API token as a
+// second subprotocol, wsTokenProtocol followed by the token, which keeps it
+// out of the URL and the request log.
+const (
+	wsProtocol      = "nmb"
+	wsTokenProtocol = "nmb.token."
+)
+
+// defaultOrigins are the desktop UI and the React development server
+var defaultOrigins = []string{
+	"wails://wails",
+	"wails://wails.localhost",
+	"http://wails.localhost",
+	"http://localhost:3000",
+	"http://localhost:34115",
+}
+
+// Config configures the API server. Requests must carry the API token,
+// which is generated on first start and kept in the config folder.
+type Config struct {
+	// Addr is the address to listen on, DefaultAddr when empty
+	Addr string
+
+	// TLS serves HTTPS with CertFile and KeyFile, or with a self-signed
+	// certificate generated in the config folder when they are not set
+	TLS      bool
+	CertFile string
+	KeyFile  string
+
+	// Origins are allowed browser origins besides the desktop UI and the
+	// server's own origin
+	Origins []string
+
+	// ConfigDir holds the token and generated certificate, nmb in the
+	// user's config folder when empty
+	ConfigDir string
+
+	// NewToken replaces the saved API token with a new one
+	NewToken bool
+}
+
+// ParseFlags reads the server flags of a subcommand such as serve
+func ParseFlags(name string, cmdArgs []string) Config {
+	var config Config
+	var origins string
+
+	flags := flag.NewFlagSet(name, flag.ExitOnError)
+	flags.StringVar(&config.Addr, "listen", DefaultAddr, "Address to listen on (0.0.0.0:8080 for every interface)")
+	flags.BoolVar(&config.TLS, "tls", false, "Serve HTTPS, with a self-signed certificate unless -cert and -key are given")
+	flags.StringVar(&config.CertFile, "cert", "", "TLS certificate file")
+	flags.StringVar(&config.KeyFile, "key", "", "TLS private key file")
+	flags.StringVar(&origins, "origins", "", "Comma-separated browser origins allowed to use the API")
+	flags.BoolVar(&config.NewToken, "new-token", false, "Replace the API token with a new one")
+	flags.Usage = func() {
+		fmt.Printf("Usage: nmb %s [-listen addr] [-tls [-cert file -key file]] [-origins list] [-new-token]\n", name)
+		flags.PrintDefaults()
+	}
+	flags.Parse(cmdArgs)
+
+	for _, origin := range strings.Split(origins, ",") {
+		if origin = strings.TrimSpace(origin); origin != "" {
+			config.Origins = append(config.Origins, strings.TrimSuffix(origin, "/"))
+		}
+	}
+	return config
+}
+
+// Validate checks that the TLS files are given together
+func (c Config) Validate() error {
+	if (c.CertFile == "") != (c.KeyFile == "") {
+		return fmt.Errorf("-cert and -key must be given together")
+	}
+	if c.CertFile != "" && !c.TLS {
+		return fmt.Errorf("-cert and -key need -tls")
+	}
+	if _, _, err := net.SplitHostPort(c.Addr); c.Addr != "" && err != nil {
+		return fmt.Errorf("invalid listen address %s: %v", c.Addr, err)
+	}
+	return nil
+}
+
+func (c Config) configDir() (string, error) {
+	if c.ConfigDir != "" {
+		return c.ConfigDir, nil
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to find config folder: %v", err)
+	}
+	return filepath.Join(dir, "nmb"), nil
+}
+
+// loadToken returns the saved API token, generating and printing a new one
+// when there is none or a new one was asked for. The token is only printed
+// when it is generated.
+func loadToken(dir string, renew bool) (string, error) {
+	path := filepath.Join(dir, tokenFile)
+	if !renew {
+		data, err := os.ReadFile(path)
+		if err == nil && len(strings.TrimSpace(string(data))) > 0 {
+			return strings.TrimSpace(string(data)), nil
+		}
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return "", fmt.Errorf("failed to read API token: %v", err)
+		}
+	}
+
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	token := hex.EncodeToString(raw)
+
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", fmt.Errorf("failed to create config folder: %v", err)
+	}
+	if err := os.WriteFile(path, []byte(token+"\n"), 0600); err != nil {
+		return "", fmt.Errorf("failed to save API token: %v", err)
+	}
+
+	fmt.Printf("New API token, shown only this once (saved in %s):\n\n    %s\n\n", path, token)
+	return token, nil
+}
+
+// selfSignedCert returns the certificate generated for the server earlier,
+// or generates one valid for localhost, this host's name and the listen
+// address
+func selfSignedCert(dir, addr string) (certFile, keyFile string, err error) {
+	certFile = filepath.Join(dir, "tls", "cert.pem")
+	keyFile = filepath.Join(dir, "tls", "key.pem")
+	if _, err := os.Stat(certFile); err == nil {
+		if _, err := os.Stat(keyFile); err == nil {
+			return certFile, keyFile, nil
+		}
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return "", "", err
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return "", "", err
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{Organization: []string{"NMB"}, CommonName: "NMB API"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().AddDate(2, 0, 0),
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		DNSNames:              []string{"localhost"},
+		IPAddresses:           []net.IP{net.IPv4(127, 0, 0, 1), net.IPv6loopback},
+	}
+	if hostname, err := os.Hostname(); err == nil {
+		template.DNSNames = append(template.DNSNames, hostname)
+	}
+	if host, _, err := net.SplitHostPort(addr); err == nil && host != "" {
+		if ip := net.ParseIP(host); ip == nil {
+			template.DNSNames = append(template.DNSNames, host)
+		} else if !ip.IsUnspecified() {
+			template.IPAddresses = append(template.IPAddresses, ip)
+		}
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to create certificate: %v", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return "", "", err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(certFile), 0700); err != nil {
+		return "", "", fmt.Errorf("failed to create certificate folder: %v", err)
+	}
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0644); err != nil {
+		return "", "", fmt.Errorf("failed to write certificate: %v", err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600); err != nil {
+		return "", "", fmt.Errorf("failed to write certificate key: %v", err)
+	}
+
+	fingerprint := sha256.Sum256(der)
+	fmt.Printf("Generated a self-signed certificate in %s\nSHA-256 fingerprint: %s\n", certFile, hex.EncodeToString(fingerprint[:]))
+	return certFile, keyFile, nil
+}
+
+// originAllowed accepts requests without an Origin, which do not come from
+// a browser, the configured origins and the server's own origin. It guards
+// both CORS and the WebSocket upgrade.
+func (s *Server) originAllowed(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	for _, allowed := range s.origins {
+		if strings.EqualFold(origin, allowed) {
+			return true
+		}
+	}
+	u, err := url.Parse(origin)
+	return err == nil && strings.EqualFold(u.Host, r.Host)
+}
+
+// requireToken rejects requests without the API token. /ws also accepts it
+// as a WebSocket subprotocol, see wsTokenProtocol.
+func (s *Server) requireToken(c *gin.Context) {
+	token := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+	if token == "" && c.Request.URL.Path == "/ws" {
+		token = protocolToken(c.Request)
+	}
+
+	if subtle.ConstantTimeCompare([]byte(token), []byte(s.token)) != 1 {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "missing or invalid API token"})
+		return
+	}
+	c.Next()
+}
+
+// protocolToken returns the API token offered as a WebSocket subprotocol
+func protocolToken(r *http.Request) string {
+	for _, header := range r.Header.Values("Sec-WebSocket-Protocol") {
+		for _, protocol := range strings.Split(header, ",") {
+			if token, ok := strings.CutPrefix(strings.TrimSpace(protocol), wsTokenProtocol); ok {
+				return token
+			}
+		}
+	}
+	return ""
+}
+
+// logFormatter is gin's request log line with secrets removed from the query
+func logFormatter(param gin.LogFormatterParams) string {
+	return fmt.Sprintf("[GIN] %v | %3d | %13v | %15s | %-7s %#v\n%s",
+		param.TimeStamp.Format("2006/01/02 - 15:04:05"),
+		param.StatusCode,
+		param.Latency,
+		param.ClientIP,
+		param.Method,
+		redactQuery(param.Path),
+		param.ErrorMessage,
+	)
+}
+
+// redactQuery hides the value of a token query parameter in a logged path.
+// The token is not read from the query, but clients may still send it.
+func redactQuery(path string) string {
+	base, rawQuery, ok := strings.Cut(path, "?")
+	if !ok {
+		return path
+	}
+	query, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		return base + "?REDACTED"
+	}
+	if !query.Has("token") {
+		return path
+	}
+	query.Set("token", "REDACTED")
+	return base + "?" + query.Encode()
+}
@@ -0,0 +1,61 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestRedactQuery(t *testing.T) {
+	tests := map[string]string{
+		"/api/settings":                     "/api/settings",
+		"/ws?topics=log&since=4":            "/ws?topics=log&since=4",
+		"/ws?token=secret":                  "/ws?token=REDACTED",
+		"/ws?topics=log&token=secret&x=1":   "/ws?token=REDACTED&topics=log&x=1",
+		"/ws?token=secret;topics=%zz":       "/ws?REDACTED",
+		"/api/nessus/scans?token=a&token=b": "/api/nessus/scans?token=REDACTED",
+	}
+	for path, want := range tests {
+		if got := redactQuery(path); got != want {
+			t.Errorf("redactQuery(%q) = %q, want %q", path, got, want)
+		}
+	}
+}
+
+func TestWebSocketToken(t *testing.T) {
+	s := newTestServer(t)
+	server := httptest.NewServer(s.router)
+	defer server.Close()
+	wsURL := "ws" + strings.TrimPrefix(server.URL, "http") + "/ws"
+
+	dial := func(url string, protocols ...string) (*websocket.Conn, int) {
+		dialer := websocket.Dialer{Subprotocols: protocols}
+		conn, resp, err := dialer.Dial(url, nil)
+		if err != nil {
+			if resp == nil {
+				t.Fatalf("Dial %s: %v", url, err)
+			}
+			return nil, resp.StatusCode
+		}
+		return conn, resp.StatusCode
+	}
+
+	if _, status := dial(wsURL + "?token=" + testToken); status != http.StatusUnauthorized {
+		t.Errorf("token in the query = %d, want 401", status)
+	}
+	if _, status := dial(wsURL, wsProtocol, wsTokenProtocol+"wrong"); status != http.StatusUnauthorized {
+		t.Errorf("wrong token protocol = %d, want 401", status)
+	}
+
+	conn, status := dial(wsURL, wsProtocol, wsTokenProtocol+testToken)
+	if conn == nil {
+		t.Fatalf("token protocol = %d, want a connection", status)
+	}
+	defer conn.Close()
+	if conn.Subprotocol() != wsProtocol {
+		t.Errorf("subprotocol = %q, want %q", conn.Subprotocol(), wsProtocol)
+	}
+}
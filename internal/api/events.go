@@ -0,0 +1,64 @@
+package api
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ScanProgress is the data of scan.progress events, sent while a Nessus scan
+// runs. Event is progress, host_completed or new_critical.
+type ScanProgress struct {
+	ScanID       string  `json:"scanId"`
+	Name         string  `json:"name,omitempty"`
+	Event        string  `json:"event"`
+	Status       string  `json:"status"`
+	Progress     float64 `json:"progress"`
+	Host         string  `json:"host,omitempty"`
+	Critical     int     `json:"critical,omitempty"`
+	High         int     `json:"high,omitempty"`
+	NewCriticals int     `json:"newCriticals,omitempty"`
+}
+
+// ScanResult is the data of scan.result events, sent when a scan, export or
+// NMB run ends
+type ScanResult struct {
+	Status  string `json:"status"`
+	Message string `json:"message"`
+}
+
+// NessusStatus is the data of nessus.status events: a scan's new status, an
+// action taken on a scan, or the current list of scans
+type NessusStatus struct {
+	ScanID string       `json:"scanId,omitempty"`
+	Name   string       `json:"name,omitempty"`
+	Status string       `json:"status,omitempty"`
+	Action string       `json:"action,omitempty"`
+	Scans  []ScanDetail `json:"scans,omitempty"`
+}
+
+// subscription is a message a client sends to change its topics
+type subscription struct {
+	Action string   `json:"action"`
+	Topics []string `json:"topics"`
+	Since  uint64   `json:"since"`
+}
+
+// newJobID identifies an operation started through the API in its events
+func newJobID() string {
+	raw := make([]byte, 8)
+	rand.Read(raw)
+	return hex.EncodeToString(raw)
+}
+
+// nessusJobID is the job ID of the events of a Nessus scan
+func nessusJobID(scanID string) string {
+	return "nessus-" + scanID
+}
+
+// Get the event counters, including events dropped for slow clients
+func (s *Server) handleGetEventStats(c *gin.Context) {
+	c.JSON(http.StatusOK, s.wsManager.Stats())
+}
@@ -0,0 +1,46 @@
+package api
+
+import (
+	_ "embed"
+	"net/http"
+	"sort"
+
+	"github.com/gin-gonic/gin"
+)
+
+// openAPISpec describes every /api route. The tests check it against the
+// routes in setupRoutes and the responses of the handlers.
+//
+//go:embed openapi.json
+var openAPISpec []byte
+
+// SupportedPlugin is a plugin of the loaded config that NMB can verify
+type SupportedPlugin struct {
+	Name        string   `json:"name"`
+	IDs         []string `json:"ids"`
+	ScanType    string   `json:"scanType"`
+	Parameters  string   `json:"parameters"`
+	VerifyWords []string `json:"verifyWords"`
+}
+
+// Serve the OpenAPI document, which needs no API token
+func (s *Server) handleOpenAPI(c *gin.Context) {
+	c.Data(http.StatusOK, "application/json; charset=utf-8", openAPISpec)
+}
+
+// Get the plugins of the loaded config, sorted by name
+func (s *Server) handleGetSupportedPlugins(c *gin.Context) {
+	plugins := make([]SupportedPlugin, 0, len(s.plugins))
+	for name, plugin := range s.plugins {
+		plugins = append(plugins, SupportedPlugin{
+			Name:        name,
+			IDs:         plugin.IDs,
+			ScanType:    plugin.ScanType,
+			Parameters:  plugin.Parameters,
+			VerifyWords: plugin.VerifyWords,
+		})
+	}
+	sort.Slice(plugins, func(i, j int) bool { return plugins[i].Name < plugins[j].Name })
+
+	c.JSON(http.StatusOK, gin.H{"plugins": plugins})
+}
@@ -0,0 +1,465 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	nessusfile "NMB/internal/nessus"
+	"NMB/internal/settings"
+	"NMB/internal/vault"
+)
+
+const testToken = "test-token"
+
+// fakeNessus serves a scan with one host and one finding to requests with
+// the test profile's API keys
+func fakeNessus(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /session", func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, `{"id":2,"username":"nmb","name":"nmb","permissions":128,"lastlogin":1700000000}`)
+	})
+	mux.HandleFunc("GET /scans", func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, `{"folders":[{"id":3,"name":"My Scans","type":"main"}],
+			"scans":[{"id":5,"uuid":"abc","name":"weekly","status":"completed","folder_id":3,"owner":"nmb",
+				"creation_date":1700000000,"last_modification_date":1700000500}],
+			"timestamp":1700000600}`)
+	})
+	mux.HandleFunc("GET /scans/5", func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, `{"info":{"name":"weekly","status":"completed","targets":"10.0.0.1","hostcount":1},
+			"hosts":[{"host_id":1,"hostname":"10.0.0.1","scanprogresscurrent":100,"scanprogresstotal":100,"critical":1}],
+			"vulnerabilities":[{"plugin_id":20007,"plugin_name":"SSL Version 2 and 3 Protocol Detection","count":1,"severity":4}],
+			"history":[{"history_id":9,"status":"completed","creation_date":1690000000}]}`)
+	})
+	mux.HandleFunc("GET /scans/6", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		io.WriteString(w, `{"error":"The requested file was not found."}`)
+	})
+	mux.HandleFunc("GET /scans/5/hosts/1", func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, `{"info":{"host-ip":"10.0.0.1","operating-system":["Linux Kernel 5.4","Ubuntu 20.04"]},
+			"vulnerabilities":[{"plugin_id":20007,"plugin_name":"SSL Version 2 and 3 Protocol Detection","count":1,"severity":4}]}`)
+	})
+	mux.HandleFunc("GET /scans/5/hosts/1/plugins/20007", func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, `{"info":{"plugindescription":{"pluginid":"20007","pluginname":"SSL Version 2 and 3 Protocol Detection","severity":4,
+				"pluginattributes":{"synopsis":"Weak SSL","see_also":["https://example.com/a"],"risk_information":{"risk_factor":"Critical"}}}},
+			"outputs":[{"plugin_output":"SSLv3 is enabled","severity":4,"ports":{"443 / tcp / www":[{"hostname":"10.0.0.1"}]}}]}`)
+	})
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-ApiKeys") != "accessKey=a; secretKey=b" {
+			w.WriteHeader(http.StatusUnauthorized)
+			io.WriteString(w, `{"error":"Invalid Credentials"}`)
+			return
+		}
+		mux.ServeHTTP(w, r)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// newTestServer returns a server whose token, vault and settings live in a
+// temporary config folder
+func newTestServer(t *testing.T) *Server {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	dir := t.TempDir()
+	t.Setenv("HOME", dir)
+	t.Setenv("XDG_CONFIG_HOME", dir)
+	t.Setenv("AppData", dir)
+	t.Setenv(vault.EnvPassphrase, "")
+
+	configDir := filepath.Join(dir, "nmb")
+	if err := os.MkdirAll(configDir, 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(configDir, tokenFile), []byte(testToken), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := NewServer(Config{ConfigDir: configDir})
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+	return s
+}
+
+// unlockWithProfile unlocks the vault and saves a profile for a fake Nessus
+func unlockWithProfile(t *testing.T, s *Server) {
+	t.Helper()
+	nessus := fakeNessus(t)
+	if err := s.vault.Unlock("passphrase"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.vault.Put(vault.Profile{Name: "lab", URL: nessus.URL, AccessKey: "a", SecretKey: "b"}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// openAPI is the parsed OpenAPI document
+type openAPI map[string]interface{}
+
+func loadSpec(t *testing.T) openAPI {
+	t.Helper()
+	var spec openAPI
+	if err := json.Unmarshal(openAPISpec, &spec); err != nil {
+		t.Fatalf("openapi.json is not valid JSON: %v", err)
+	}
+	return spec
+}
+
+// resolve follows a local $ref such as #/components/schemas/Settings
+func (spec openAPI) resolve(node map[string]interface{}) map[string]interface{} {
+	for {
+		ref, ok := node["$ref"].(string)
+		if !ok {
+			return node
+		}
+		var current interface{} = map[string]interface{}(spec)
+		for _, part := range strings.Split(strings.TrimPrefix(ref, "#/"), "/") {
+			current = current.(map[string]interface{})[part]
+		}
+		node = current.(map[string]interface{})
+	}
+}
+
+// operation returns the operation of a path and method, or nil
+func (spec openAPI) operation(path, method string) map[string]interface{} {
+	paths := spec["paths"].(map[string]interface{})
+	item, ok := paths[path].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	op, _ := item[strings.ToLower(method)].(map[string]interface{})
+	return op
+}
+
+// responseSchema returns the JSON schema documented for a response status
+func (spec openAPI) responseSchema(path, method string, status int) (map[string]interface{}, error) {
+	op := spec.operation(path, method)
+	if op == nil {
+		return nil, fmt.Errorf("%s %s is not documented", method, path)
+	}
+	responses := op["responses"].(map[string]interface{})
+	response, ok := responses[fmt.Sprint(status)].(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%s %s does not document status %d", method, path, status)
+	}
+	response = spec.resolve(response)
+	content := response["content"].(map[string]interface{})
+	media := content["application/json"].(map[string]interface{})
+	return spec.resolve(media["schema"].(map[string]interface{})), nil
+}
+
+// validate checks a decoded JSON value against the subset of JSON schema
+// the document uses
+func (spec openAPI) validate(schema map[string]interface{}, value interface{}, at string) []string {
+	schema = spec.resolve(schema)
+	if value == nil {
+		if nullable, _ := schema["nullable"].(bool); nullable {
+			return nil
+		}
+		return []string{at + ": null is not allowed"}
+	}
+
+	var problems []string
+	if enum, ok := schema["enum"].([]interface{}); ok {
+		found := false
+		for _, allowed := range enum {
+			found = found || allowed == value
+		}
+		if !found {
+			problems = append(problems, fmt.Sprintf("%s: %v is not one of %v", at, value, enum))
+		}
+	}
+
+	switch schema["type"] {
+	case "object":
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return append(problems, fmt.Sprintf("%s: %T is not an object", at, value))
+		}
+		required, _ := schema["required"].([]interface{})
+		for _, name := range required {
+			if _, ok := object[name.(string)]; !ok {
+				problems = append(problems, fmt.Sprintf("%s: missing %s", at, name))
+			}
+		}
+		properties, _ := schema["properties"].(map[string]interface{})
+		additional, _ := schema["additionalProperties"].(map[string]interface{})
+		for name, field := range object {
+			if property, ok := properties[name].(map[string]interface{}); ok {
+				problems = append(problems, spec.validate(property, field, at+"."+name)...)
+			} else if additional != nil {
+				problems = append(problems, spec.validate(additional, field, at+"."+name)...)
+			} else if properties != nil {
+				problems = append(problems, fmt.Sprintf("%s: undocumented field %s", at, name))
+			}
+		}
+	case "array":
+		array, ok := value.([]interface{})
+		if !ok {
+			return append(problems, fmt.Sprintf("%s: %T is not an array", at, value))
+		}
+		items := schema["items"].(map[string]interface{})
+		for i, item := range array {
+			problems = append(problems, spec.validate(items, item, fmt.Sprintf("%s[%d]", at, i))...)
+		}
+	case "string":
+		if _, ok := value.(string); !ok {
+			problems = append(problems, fmt.Sprintf("%s: %T is not a string", at, value))
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			problems = append(problems, fmt.Sprintf("%s: %T is not a boolean", at, value))
+		}
+	case "integer", "number":
+		number, ok := value.(float64)
+		if !ok {
+			return append(problems, fmt.Sprintf("%s: %T is not a number", at, value))
+		}
+		if schema["type"] == "integer" && number != float64(int64(number)) {
+			problems = append(problems, fmt.Sprintf("%s: %v is not an integer", at, number))
+		}
+		if minimum, ok := schema["minimum"].(float64); ok && number < minimum {
+			problems = append(problems, fmt.Sprintf("%s: %v is below %v", at, number, minimum))
+		}
+		if maximum, ok := schema["maximum"].(float64); ok && number > maximum {
+			problems = append(problems, fmt.Sprintf("%s: %v is above %v", at, number, maximum))
+		}
+	}
+	return problems
+}
+
+// routeParam matches gin path parameters such as :id
+var routeParam = regexp.MustCompile(`:([A-Za-z]+)`)
+
+// specPath converts a gin route to an OpenAPI path
+func specPath(route string) string {
+	return routeParam.ReplaceAllString(route, "{$1}")
+}
+
+// call sends a request with the API token and checks that the status is
+// documented and the response matches its schema
+func call(t *testing.T, s *Server, spec openAPI, method, route, target string, body interface{}) (int, map[string]interface{}) {
+	t.Helper()
+
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		reader = bytes.NewReader(data)
+	}
+	req := httptest.NewRequest(method, target, reader)
+	req.Header.Set("Authorization", "Bearer "+testToken)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	rec := httptest.NewRecorder()
+	s.router.ServeHTTP(rec, req)
+
+	var decoded interface{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &decoded); err != nil {
+		t.Fatalf("%s %s: response is not JSON: %v\n%s", method, target, err, rec.Body.String())
+	}
+
+	schema, err := spec.responseSchema(specPath(route), method, rec.Code)
+	if err != nil {
+		t.Errorf("%s %s: %v: %s", method, target, err, rec.Body.String())
+		return rec.Code, nil
+	}
+	for _, problem := range spec.validate(schema, decoded, "response") {
+		t.Errorf("%s %s (%d): %s", method, target, rec.Code, problem)
+	}
+
+	object, _ := decoded.(map[string]interface{})
+	return rec.Code, object
+}
+
+func TestOpenAPIDocumentsEveryRoute(t *testing.T) {
+	s := newTestServer(t)
+	spec := loadSpec(t)
+
+	routes := make(map[string]bool)
+	for _, route := range s.router.Routes() {
+		if !strings.HasPrefix(route.Path, "/api/") {
+			continue
+		}
+		path := specPath(route.Path)
+		routes[route.Method+" "+path] = true
+		if spec.operation(path, route.Method) == nil {
+			t.Errorf("route %s %s is missing from openapi.json", route.Method, path)
+		}
+	}
+
+	for path, item := range spec["paths"].(map[string]interface{}) {
+		for method := range item.(map[string]interface{}) {
+			if method == "parameters" {
+				continue
+			}
+			if !routes[strings.ToUpper(method)+" "+path] {
+				t.Errorf("openapi.json documents %s %s, which has no route", strings.ToUpper(method), path)
+			}
+		}
+	}
+}
+
+func TestOpenAPIServedWithoutToken(t *testing.T) {
+	s := newTestServer(t)
+
+	rec := httptest.NewRecorder()
+	s.router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/openapi.json", nil))
+	if rec.Code != http.StatusOK || !bytes.Equal(rec.Body.Bytes(), openAPISpec) {
+		t.Fatalf("GET /api/openapi.json = %d, want the document", rec.Code)
+	}
+}
+
+func TestSupportedPlugins(t *testing.T) {
+	s := newTestServer(t)
+	spec := loadSpec(t)
+
+	status, body := call(t, s, spec, http.MethodGet, "/api/supported-plugins", "/api/supported-plugins", nil)
+	if status != http.StatusOK {
+		t.Fatalf("status = %d, want 200", status)
+	}
+	plugins := body["plugins"].([]interface{})
+	if len(plugins) != len(s.plugins) || len(plugins) == 0 {
+		t.Fatalf("got %d plugins, want the %d of the embedded config", len(plugins), len(s.plugins))
+	}
+	first := plugins[0].(map[string]interface{})
+	if _, ok := s.plugins[first["name"].(string)]; !ok {
+		t.Errorf("plugin %v is not in the config", first["name"])
+	}
+}
+
+func TestScanRequestValidation(t *testing.T) {
+	s := newTestServer(t)
+	spec := loadSpec(t)
+
+	if status, _ := call(t, s, spec, http.MethodPost, "/api/scan", "/api/scan", ScanRequest{ProjectFolder: "out"}); status != http.StatusBadRequest {
+		t.Errorf("POST /api/scan without a Nessus file = %d, want 400", status)
+	}
+	for _, file := range []string{"../run.cassette.json", "/etc/passwd"} {
+		request := ScanRequest{NessusFilePath: "scan.nessus", ReplayFile: file}
+		if status, _ := call(t, s, spec, http.MethodPost, "/api/scan", "/api/scan", request); status != http.StatusBadRequest {
+			t.Errorf("POST /api/scan replaying %s = %d, want 400", file, status)
+		}
+	}
+	for _, filter := range []nessusfile.Filter{
+		{Risks: []string{"Severe"}},
+		{Categories: []string{"no-such-plugin"}},
+		{IncludeHosts: []string{"10.0.0.0/33"}},
+		{Ports: []string{"443-80"}},
+	} {
+		request := ScanRequest{NessusFilePath: "scan.nessus", Filter: filter}
+		if status, _ := call(t, s, spec, http.MethodPost, "/api/scan", "/api/scan", request); status != http.StatusBadRequest {
+			t.Errorf("POST /api/scan with filter %+v = %d, want 400", filter, status)
+		}
+	}
+	request := ScanRequest{NessusFilePath: "scan.nessus", Profile: "lab"}
+	if status, _ := call(t, s, spec, http.MethodPost, "/api/scan", "/api/scan", request); status != http.StatusLocked {
+		t.Errorf("POST /api/scan with a profile while locked = %d, want 423", status)
+	}
+	for _, request := range []ScanRequest{
+		{NessusMode: "create", ScheduleStart: "tomorrow 10pm"},
+		{NessusMode: "create", ScheduleStart: "2026-03-01 22:00", Timezone: "Mars/Olympus"},
+		{NessusMode: "monitor", ScanWindow: "22:00-30:00"},
+	} {
+		if status, _ := call(t, s, spec, http.MethodPost, "/api/nessus-controller", "/api/nessus-controller", request); status != http.StatusBadRequest {
+			t.Errorf("POST /api/nessus-controller with schedule %q window %q = %d, want 400", request.ScheduleStart, request.ScanWindow, status)
+		}
+	}
+	request = ScanRequest{NessusMode: "launch", Profile: "lab"}
+	if status, _ := call(t, s, spec, http.MethodPost, "/api/nessus-controller", "/api/nessus-controller", request); status != http.StatusLocked {
+		t.Errorf("POST /api/nessus-controller with a profile while locked = %d, want 423", status)
+	}
+
+	unlockWithProfile(t, s)
+	request = ScanRequest{NessusFilePath: "scan.nessus", Profile: "missing"}
+	if status, _ := call(t, s, spec, http.MethodPost, "/api/scan", "/api/scan", request); status != http.StatusNotFound {
+		t.Errorf("POST /api/scan with an unknown profile = %d, want 404", status)
+	}
+}
+
+func TestNessusHandlers(t *testing.T) {
+	s := newTestServer(t)
+	spec := loadSpec(t)
+
+	if status, _ := call(t, s, spec, http.MethodGet, "/api/nessus/scans", "/api/nessus/scans", nil); status != http.StatusBadRequest {
+		t.Errorf("GET /api/nessus/scans without a profile = %d, want 400", status)
+	}
+	if status, _ := call(t, s, spec, http.MethodGet, "/api/nessus/scans", "/api/nessus/scans?profile=lab", nil); status != http.StatusLocked {
+		t.Errorf("GET /api/nessus/scans while locked = %d, want 423", status)
+	}
+	unlockWithProfile(t, s)
+
+	tests := []struct {
+		route, target string
+		status        int
+	}{
+		{"/api/nessus/scans", "/api/nessus/scans?profile=lab", http.StatusOK},
+		{"/api/nessus/scans", "/api/nessus/scans?profile=missing", http.StatusNotFound},
+		{"/api/nessus/scan/:id", "/api/nessus/scan/5?profile=lab", http.StatusOK},
+		{"/api/nessus/scan/:id/hosts", "/api/nessus/scan/5/hosts?profile=lab", http.StatusOK},
+		{"/api/nessus/scan/:id/hosts", "/api/nessus/scan/6/hosts?profile=lab", http.StatusNotFound},
+		{"/api/nessus/scan/:id/hosts/:host", "/api/nessus/scan/5/hosts/1?profile=lab", http.StatusOK},
+		{"/api/nessus/scan/:id/hosts/:host", "/api/nessus/scan/5/hosts/web?profile=lab", http.StatusBadRequest},
+		{"/api/nessus/scan/:id/hosts/:host/plugins/:plugin", "/api/nessus/scan/5/hosts/1/plugins/20007?profile=lab", http.StatusOK},
+		{"/api/nessus/scan/:id/history", "/api/nessus/scan/5/history?profile=lab", http.StatusOK},
+	}
+	for _, test := range tests {
+		if status, body := call(t, s, spec, http.MethodGet, test.route, test.target, nil); status != test.status {
+			t.Errorf("GET %s = %d %v, want %d", test.target, status, body, test.status)
+		}
+	}
+
+	// The default profile from the settings is used without a profile
+	// parameter
+	saved := settings.Defaults()
+	saved.NessusProfile = "lab"
+	if err := settings.Save(saved); err != nil {
+		t.Fatal(err)
+	}
+	if status, _ := call(t, s, spec, http.MethodGet, "/api/nessus/scans", "/api/nessus/scans", nil); status != http.StatusOK {
+		t.Errorf("GET /api/nessus/scans with a default profile = %d, want 200", status)
+	}
+
+	route := "/api/nessus/scan/:id/:action"
+	if status, _ := call(t, s, spec, http.MethodPost, route, "/api/nessus/scan/5/launch?profile=lab", nil); status != http.StatusBadRequest {
+		t.Errorf("POST an unknown scan action = %d, want 400", status)
+	}
+	export := map[string]interface{}{"formats": []string{"docx"}}
+	if status, _ := call(t, s, spec, http.MethodPost, route, "/api/nessus/scan/5/export?profile=lab", export); status != http.StatusBadRequest {
+		t.Errorf("POST an export with an unknown format = %d, want 400", status)
+	}
+}
+
+func TestEventStats(t *testing.T) {
+	s := newTestServer(t)
+	spec := loadSpec(t)
+
+	before := s.wsManager.Stats().Published
+	s.wsManager.Log("info", "", "test event")
+
+	status, body := call(t, s, spec, http.MethodGet, "/api/events/stats", "/api/events/stats", nil)
+	if status != http.StatusOK {
+		t.Fatalf("status = %d, want 200", status)
+	}
+	if published := uint64(body["published"].(float64)); published <= before {
+		t.Errorf("published = %d, want more than %d", published, before)
+	}
+}
@@ -2,113 +2,79 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"log"
+	"net"
 	"net/http"
-	"sync"
+	"path/filepath"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 
 	"NMB/internal/args"
+	pluginconfig "NMB/internal/config"
 	"NMB/internal/crash"
 	"NMB/internal/engine"
+	nessusfile "NMB/internal/nessus"
 	"NMB/internal/nessus-controller"
+	"NMB/internal/nessusapi"
+	"NMB/internal/settings"
+	"NMB/internal/vault"
 	websocket "NMB/internal/ws"
 )
 
-// Maintain a cache of active sessions to prevent multiple authentications
-type NessusSessionCache struct {
-	sessions map[string]*nessus.Nessus
-	mutex    sync.RWMutex
-	timeout  time.Duration
-}
-
-var sessionCache = &NessusSessionCache{
-	sessions: make(map[string]*nessus.Nessus),
-	timeout:  15 * time.Minute, // Sessions last 15 minutes
-}
-
-// GetSession gets or creates a Nessus session
-func (c *NessusSessionCache) GetSession(host, user, pass string) (*nessus.Nessus, error) {
-	sessionKey := fmt.Sprintf("%s:%s", host, user)
-
-	// Try to get session from cache
-	c.mutex.RLock()
-	session, exists := c.sessions[sessionKey]
-	c.mutex.RUnlock()
-
-	if exists {
-		// Session found in cache
-		return session, nil
-	}
-
-	// Create new session
-	c.mutex.Lock()
-	defer c.mutex.Unlock()
-
-	// Check again in case another goroutine created the session while we were waiting
-	session, exists = c.sessions[sessionKey]
-	if exists {
-		return session, nil
-	}
-
-	// Create a new session
-	newSession, err := nessus.New(host, user, pass, "", "", nil, false)
-	if err != nil {
-		return nil, err
-	}
-
-	// Store in cache
-	c.sessions[sessionKey] = newSession
-
-	// Start a timeout goroutine to remove the session after the timeout
-	go func() {
-		time.Sleep(c.timeout)
-		c.mutex.Lock()
-		delete(c.sessions, sessionKey)
-		c.mutex.Unlock()
-	}()
-
-	return newSession, nil
-}
-
 type ScanRequest struct {
 	NessusFilePath string `json:"nessusFilePath"`
 	ProjectFolder  string `json:"projectFolder"`
+	Profile        string `json:"profile,omitempty"`
 	RemoteHost     string `json:"remoteHost,omitempty"`
+	NessusURL      string `json:"nessusUrl,omitempty"`
 	RemoteUser     string `json:"remoteUser,omitempty"`
 	RemotePass     string `json:"remotePass,omitempty"`
 	RemoteKey      string `json:"remoteKey,omitempty"`
 	NumWorkers     int    `json:"numWorkers"`
 	ConfigFilePath string `json:"configFilePath,omitempty"`
+	RecordFile     string `json:"recordFile,omitempty"`
+	ReplayFile     string `json:"replayFile,omitempty"`
+	RetestFile     string `json:"retestFile,omitempty"`
 	ExcludeFile    string `json:"excludeFile,omitempty"`
 	NessusMode     string `json:"nessusMode,omitempty"`
 	TargetsFile    string `json:"targetsFile,omitempty"`
 	ProjectName    string `json:"projectName,omitempty"`
 	Discovery      bool   `json:"discovery"`
-}
-
-type Settings struct {
-	DefaultProjectFolder string  `json:"defaultProjectFolder"`
-	SSHKeyFile           string  `json:"sshKeyFile"`
-	MaxWorkers           int     `json:"maxWorkers"`
-	AutoStart            bool    `json:"autoStart"`
-	Telemetry            bool    `json:"telemetry"`
-	Drones               []Drone `json:"drones"`
-}
-
-type Drone struct {
-	Name string `json:"name"`
-	Host string `json:"host"`
-	User string `json:"user"`
+	TopPorts       int    `json:"topPorts,omitempty"`
+	PolicyPath     string `json:"policyPath,omitempty"`
+	PolicyName     string `json:"policyName,omitempty"`
+	ScannerName    string `json:"scannerName,omitempty"`
+	FolderName     string `json:"folderName,omitempty"`
+	ScheduleStart  string `json:"scheduleStart,omitempty"`
+	Timezone       string `json:"timezone,omitempty"`
+	RRules         string `json:"rrules,omitempty"`
+	ScanWindow     string `json:"scanWindow,omitempty"`
+
+	Filter nessusfile.Filter `json:"filter"`
 }
 
 type Server struct {
 	router    *gin.Engine
 	wsManager *websocket.WebSocketManager
+	vault     *vault.Vault
+	sessions  *NessusSessionCache
+
+	// Listening and access control, see Config
+	addr     string
+	certFile string
+	keyFile  string
+	token    string
+	origins  []string
+
+	// plugins is the plugin config scans use by default
+	plugins map[string]pluginconfig.Plugin
 }
 
 // New Scan structure for responses - removed Findings field
@@ -124,13 +90,54 @@ type ScanDetail struct {
 	AdditionalData map[string]interface{} `json:"additionalData,omitempty"`
 }
 
-func NewServer() *Server {
-	router := gin.Default()
+// NewServer returns an API server that requires the API token on every
+// request, generating the token on first start
+func NewServer(config Config) (*Server, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	dir, err := config.configDir()
+	if err != nil {
+		return nil, err
+	}
+	token, err := loadToken(dir, config.NewToken)
+	if err != nil {
+		return nil, err
+	}
+
+	// gin.Default without its logger, which would log query strings as is
+	router := gin.New()
+	router.Use(gin.LoggerWithFormatter(logFormatter), gin.Recovery())
 	wsManager := websocket.GetInstance()
 
-	// Configure CORS with WebSocket support
+	server := &Server{
+		router:    router,
+		wsManager: wsManager,
+		vault:     openVault(),
+		sessions:  newSessionCache(),
+		addr:      config.Addr,
+		certFile:  config.CertFile,
+		keyFile:   config.KeyFile,
+		token:     token,
+		origins:   append(append([]string{}, defaultOrigins...), config.Origins...),
+		plugins:   pluginconfig.LoadEmbeddedConfig().Plugins,
+	}
+	if server.addr == "" {
+		server.addr = DefaultAddr
+	}
+	if config.TLS && server.certFile == "" {
+		server.certFile, server.keyFile, err = selfSignedCert(dir, server.addr)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Only the desktop UI, the server's own origin and configured origins
+	// may call the API from a browser
 	router.Use(cors.New(cors.Config{
-		AllowOrigins:     []string{"*"}, // For development. In production, specify exact origin
+		AllowOriginWithContextFunc: func(c *gin.Context, origin string) bool {
+			return server.originAllowed(c.Request)
+		},
 		AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE", "HEAD", "OPTIONS"},
 		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization", "Sec-WebSocket-Protocol", "Sec-WebSocket-Version", "Sec-WebSocket-Key"},
 		ExposeHeaders:    []string{"Content-Length", "Content-Type"},
@@ -138,23 +145,40 @@ func NewServer() *Server {
 		MaxAge:           12 * time.Hour,
 	}))
 
-	server := &Server{
-		router:    router,
-		wsManager: wsManager,
-	}
-
 	server.setupRoutes()
-	return server
+	return server, nil
+}
+
+// ClientConfig is what a UI needs to reach the API
+type ClientConfig struct {
+	URL   string `json:"url"`
+	Token string `json:"token"`
+}
+
+// ClientConfig returns the server's base URL and API token
+func (s *Server) ClientConfig() ClientConfig {
+	scheme := "http"
+	if s.certFile != "" {
+		scheme = "https"
+	}
+	host, port, _ := net.SplitHostPort(s.addr)
+	if ip := net.ParseIP(host); host == "" || (ip != nil && ip.IsUnspecified()) {
+		host = "localhost"
+	}
+	return ClientConfig{
+		URL:   fmt.Sprintf("%s://%s", scheme, net.JoinHostPort(host, port)),
+		Token: s.token,
+	}
 }
 
 func (s *Server) handleWebSocket(c *gin.Context) {
 	log.Println("WebSocket connection attempt received")
 
-	// Set required headers
-	c.Header("Access-Control-Allow-Origin", "http://localhost:3000")
-	c.Header("Access-Control-Allow-Credentials", "true")
+	upgrader := websocket.Upgrader
+	upgrader.CheckOrigin = s.originAllowed
+	upgrader.Subprotocols = []string{wsProtocol}
 
-	conn, err := websocket.Upgrader.Upgrade(c.Writer, c.Request, nil)
+	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
 	if err != nil {
 		log.Printf("Failed to upgrade connection: %v", err)
 		return
@@ -169,12 +193,20 @@ func (s *Server) handleWebSocket(c *gin.Context) {
 		return nil
 	})
 
-	// Add the client to the manager
-	wsManager := websocket.GetInstance()
-	wsManager.AddClient(conn)
+	// Subscribe to the topics asked for, all by default, and replay the
+	// buffered events after since
+	var topics []string
+	for _, topic := range strings.Split(c.Query("topics"), ",") {
+		if topic = strings.TrimSpace(topic); topic != "" {
+			topics = append(topics, topic)
+		}
+	}
+	since, _ := strconv.ParseUint(c.Query("since"), 10, 64)
+
+	client := s.wsManager.AddClient(conn, topics, since)
 
 	defer func() {
-		wsManager.RemoveClient(conn)
+		s.wsManager.RemoveClient(client)
 		log.Println("WebSocket connection closed")
 	}()
 
@@ -206,7 +238,12 @@ func (s *Server) handleWebSocket(c *gin.Context) {
 					return
 				}
 			} else {
-				log.Printf("Received text message: %s", string(message))
+				var sub subscription
+				if err := json.Unmarshal(message, &sub); err != nil || sub.Action != "subscribe" {
+					log.Printf("Ignoring WebSocket message: %s", string(message))
+					break
+				}
+				s.wsManager.Subscribe(client, sub.Topics, sub.Since)
 			}
 		}
 
@@ -215,35 +252,136 @@ func (s *Server) handleWebSocket(c *gin.Context) {
 	}
 }
 
+// setupRoutes registers the API and WebSocket routes. The OpenAPI document
+// is served without a token so clients can be generated from it; every other
+// route needs the API token.
 func (s *Server) setupRoutes() {
-	s.router.GET("/ws", s.handleWebSocket)
-	s.router.POST("/api/scan", s.handleScan)
-	s.router.GET("/api/supported-plugins", s.handleGetSupportedPlugins)
-	s.router.POST("/api/nessus-controller", s.handleNessusController)
-	s.router.GET("/api/settings", s.handleGetSettings)
-	s.router.POST("/api/settings", s.handleSaveSettings)
+	s.router.GET("/ws", s.requireToken, s.handleWebSocket)
+	s.router.GET("/api/openapi.json", s.handleOpenAPI)
+
+	routes := s.router.Group("/api", s.requireToken)
+	routes.POST("/scan", s.handleScan)
+	routes.GET("/supported-plugins", s.handleGetSupportedPlugins)
+	routes.POST("/nessus-controller", s.handleNessusController)
+	routes.GET("/events/stats", s.handleGetEventStats)
+	routes.GET("/settings", s.handleGetSettings)
+	routes.POST("/settings", s.handleSaveSettings)
+
+	// Credential vault and the Nessus profiles stored in it
+	routes.GET("/vault", s.handleGetVault)
+	routes.POST("/vault/unlock", s.handleUnlockVault)
+	routes.POST("/vault/lock", s.handleLockVault)
+	routes.GET("/profiles", s.handleGetProfiles)
+	routes.PUT("/profiles/:name", s.handleSaveProfile)
+	routes.DELETE("/profiles/:name", s.handleDeleteProfile)
 
 	// New Nessus endpoints
-	s.router.GET("/api/nessus/scans", s.handleGetNessusScans)
-	s.router.GET("/api/nessus/scan/:id", s.handleGetNessusScanDetail)
-	s.router.POST("/api/nessus/scan/:id/:action", s.handleNessusScanAction)
+	routes.GET("/nessus/scans", s.handleGetNessusScans)
+	routes.GET("/nessus/scan/:id", s.handleGetNessusScanDetail)
+	routes.POST("/nessus/scan/:id/:action", s.handleNessusScanAction)
+
+	// Drill-down into a scan's results without exporting it
+	routes.GET("/nessus/scan/:id/hosts", s.handleGetNessusScanHosts)
+	routes.GET("/nessus/scan/:id/hosts/:host", s.handleGetNessusHostDetail)
+	routes.GET("/nessus/scan/:id/hosts/:host/plugins/:plugin", s.handleGetNessusPluginOutput)
+	routes.GET("/nessus/scan/:id/history", s.handleGetNessusScanHistory)
 }
 
-// Get all Nessus scans
-func (s *Server) handleGetNessusScans(c *gin.Context) {
-	remoteHost := c.DefaultQuery("host", "")
-	remoteUser := c.DefaultQuery("user", "bstg")
-	remotePass := c.DefaultQuery("pass", "BulletH@x")
+// nessusSession returns the cached Nessus session for the profile query
+// parameter, writing the error response when there is none
+func (s *Server) nessusSession(c *gin.Context) (*nessus.Nessus, bool) {
+	profile, ok := s.profile(c)
+	if !ok {
+		return nil, false
+	}
 
-	if remoteHost == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "host parameter is required"})
+	nessusSession, err := s.sessions.GetSession(profile)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to authenticate with Nessus: %v", err)})
+		return nil, false
+	}
+	return nessusSession, true
+}
+
+// nessusError maps a Nessus API error to a response status
+func nessusError(c *gin.Context, message string, err error) {
+	status := http.StatusInternalServerError
+	if nessusapi.IsNotFound(err) {
+		status = http.StatusNotFound
+	} else if nessusapi.IsInvalidArgument(err) {
+		status = http.StatusBadRequest
+	}
+	c.JSON(status, gin.H{"error": fmt.Sprintf("%s: %v", message, err)})
+}
+
+// Get the hosts of a scan with their finding counts
+func (s *Server) handleGetNessusScanHosts(c *gin.Context) {
+	nessusSession, ok := s.nessusSession(c)
+	if !ok {
 		return
 	}
 
-	// Use the session cache to get a Nessus session
-	nessusSession, err := sessionCache.GetSession(remoteHost, remoteUser, remotePass)
+	hosts, err := nessusSession.GetScanHosts(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to authenticate with Nessus: %v", err)})
+		nessusError(c, "Failed to get scan hosts", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"hosts": hosts})
+}
+
+// Get a host's information and findings, optionally for an earlier run
+func (s *Server) handleGetNessusHostDetail(c *gin.Context) {
+	nessusSession, ok := s.nessusSession(c)
+	if !ok {
+		return
+	}
+
+	details, err := nessusSession.GetHostDetails(c.Param("id"), c.Param("host"), c.Query("history"))
+	if err != nil {
+		nessusError(c, "Failed to get host details", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, details)
+}
+
+// Get the output of one plugin on one host, optionally for an earlier run
+func (s *Server) handleGetNessusPluginOutput(c *gin.Context) {
+	nessusSession, ok := s.nessusSession(c)
+	if !ok {
+		return
+	}
+
+	output, err := nessusSession.GetPluginOutput(c.Param("id"), c.Param("host"), c.Param("plugin"), c.Query("history"))
+	if err != nil {
+		nessusError(c, "Failed to get plugin output", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, output)
+}
+
+// Get the previous runs of a scan
+func (s *Server) handleGetNessusScanHistory(c *gin.Context) {
+	nessusSession, ok := s.nessusSession(c)
+	if !ok {
+		return
+	}
+
+	history, err := nessusSession.GetScanHistory(c.Param("id"))
+	if err != nil {
+		nessusError(c, "Failed to get scan history", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"history": history})
+}
+
+// Get all Nessus scans
+func (s *Server) handleGetNessusScans(c *gin.Context) {
+	nessusSession, ok := s.nessusSession(c)
+	if !ok {
 		return
 	}
 
@@ -255,7 +393,7 @@ func (s *Server) handleGetNessusScans(c *gin.Context) {
 	}
 
 	// Process scans to include additional information
-	scans := s.processScans(rawScans, remoteHost, remoteUser)
+	scans := s.processScans(rawScans)
 
 	c.JSON(http.StatusOK, gin.H{"scans": scans})
 
@@ -264,48 +402,39 @@ func (s *Server) handleGetNessusScans(c *gin.Context) {
 }
 
 // Process scans to add additional information - without findings
-func (s *Server) processScans(rawScans []map[string]interface{}, host, user string) []ScanDetail {
+func (s *Server) processScans(rawScans []nessusapi.Scan) []ScanDetail {
 	var scans []ScanDetail
 
 	for _, scan := range rawScans {
-		// Basic info
-		id := fmt.Sprintf("%v", scan["id"])
-		name := fmt.Sprintf("%v", scan["name"])
-		status := fmt.Sprintf("%v", scan["status"])
+		status := scan.Status
 
-		// Parse progress
+		// Completed scans are at 100%; the list does not carry progress
 		var progress float64
-		if p, ok := scan["progress"].(float64); ok {
-			progress = p
+		if status == "completed" {
+			progress = 100
 		}
 
 		// Parse creation time
 		createdAt := "Unknown"
-		if ts, ok := scan["creation_date"].(float64); ok {
-			createdAt = time.Unix(int64(ts), 0).Format("2006-01-02 15:04:05")
+		if scan.CreationDate > 0 {
+			createdAt = time.Unix(scan.CreationDate, 0).Format("2006-01-02 15:04:05")
 		}
 
 		// Parse completion time (if available)
 		completedAt := ""
-		if ts, ok := scan["last_modification_date"].(float64); ok && (status == "completed" || status == "failed" || status == "canceled") {
-			completedAt = time.Unix(int64(ts), 0).Format("2006-01-02 15:04:05")
-		}
-
-		// Parse targets (may need extraction from scan details)
-		targets := "Multiple targets"
-		if t, ok := scan["targets"].(string); ok && t != "" {
-			targets = t
+		if scan.LastModificationDate > 0 && (status == "completed" || status == "failed" || status == "canceled") {
+			completedAt = time.Unix(scan.LastModificationDate, 0).Format("2006-01-02 15:04:05")
 		}
 
 		scanDetail := ScanDetail{
-			ID:          id,
-			Name:        name,
+			ID:          strconv.Itoa(scan.ID),
+			Name:        scan.Name,
 			Status:      status,
 			Progress:    progress,
-			Targets:     targets,
+			Targets:     "Multiple targets",
 			CreatedAt:   createdAt,
 			CompletedAt: completedAt,
-			Owner:       user,
+			Owner:       scan.Owner,
 		}
 
 		scans = append(scans, scanDetail)
@@ -314,7 +443,7 @@ func (s *Server) processScans(rawScans []map[string]interface{}, host, user stri
 	return scans
 }
 
-func (s *Server) getNessusScans(n *nessus.Nessus) ([]map[string]interface{}, error) {
+func (s *Server) getNessusScans(n *nessus.Nessus) ([]nessusapi.Scan, error) {
 	// Use the exported GetScans method instead of directly calling makeRequest
 	return n.GetScans()
 }
@@ -323,14 +452,6 @@ func (s *Server) getNessusScans(n *nessus.Nessus) ([]map[string]interface{}, err
 func (s *Server) handleNessusScanAction(c *gin.Context) {
 	scanID := c.Param("id")
 	action := c.Param("action")
-	remoteHost := c.DefaultQuery("host", "")
-	remoteUser := c.DefaultQuery("user", "bstg")
-	remotePass := c.DefaultQuery("pass", "BulletH@x")
-
-	if remoteHost == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "host parameter is required"})
-		return
-	}
 
 	if scanID == "" {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "scan ID is required"})
@@ -354,10 +475,8 @@ func (s *Server) handleNessusScanAction(c *gin.Context) {
 		return
 	}
 
-	// Use the session cache to get a Nessus session
-	nessusSession, err := sessionCache.GetSession(remoteHost, remoteUser, remotePass)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to authenticate with Nessus: %v", err)})
+	nessusSession, ok := s.nessusSession(c)
+	if !ok {
 		return
 	}
 
@@ -373,16 +492,29 @@ func (s *Server) handleNessusScanAction(c *gin.Context) {
 	}
 
 	if action == "export" {
-		// Use exported ExportScanByID method
-		c.JSON(http.StatusOK, gin.H{"message": "Export started, files will be available in the evidence folder"})
+		// An optional body selects formats and filters
+		var exportOptions nessus.ExportOptions
+		if c.Request.ContentLength > 0 {
+			if err := c.ShouldBindJSON(&exportOptions); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+		}
+		if err := exportOptions.Validate(); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		jobID := newJobID()
+		c.JSON(http.StatusOK, gin.H{"message": "Export started, files will be available in the evidence folder", "jobId": jobID})
 
 		// Start export in background
 		go func() {
-			if err := nessusSession.ExportScanByID(scanID); err != nil {
+			if err := nessusSession.ExportScanWithOptions(scanID, exportOptions); err != nil {
 				log.Printf("Export error: %v", err)
-				s.wsManager.BroadcastMessage("error", fmt.Sprintf("Export failed: %v", err))
+				s.wsManager.Publish(websocket.TopicScanResult, jobID, ScanResult{Status: "failed", Message: fmt.Sprintf("Export failed: %v", err)})
 			} else {
-				s.wsManager.BroadcastMessage("success", "Export completed successfully")
+				s.wsManager.Publish(websocket.TopicScanResult, jobID, ScanResult{Status: "completed", Message: "Export completed successfully"})
 			}
 		}()
 
@@ -399,35 +531,25 @@ func (s *Server) handleNessusScanAction(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": fmt.Sprintf("Scan %s operation successful", action)})
 
 	// Broadcast update to connected clients
-	s.wsManager.BroadcastMessage("info", fmt.Sprintf("Scan %s operation started for scan ID %s", action, scanID))
+	s.wsManager.Publish(websocket.TopicNessusStatus, nessusJobID(scanID), NessusStatus{ScanID: scanID, Action: action})
 
 	// Start background scan status monitoring if needed
 	if action == "start" || action == "resume" {
-		go s.monitorScanProgress(nessusSession, scanID, remoteHost, remoteUser, remotePass)
+		go s.monitorScanProgress(nessusSession, scanID, c.Query("profile"))
 	}
 }
 
 // Get detailed information about a specific scan
 func (s *Server) handleGetNessusScanDetail(c *gin.Context) {
 	scanID := c.Param("id")
-	remoteHost := c.DefaultQuery("host", "")
-	remoteUser := c.DefaultQuery("user", "bstg")
-	remotePass := c.DefaultQuery("pass", "BulletH@x")
-
-	if remoteHost == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "host parameter is required"})
-		return
-	}
 
 	if scanID == "" {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "scan ID is required"})
 		return
 	}
 
-	// Use the session cache to get a Nessus session
-	nessusSession, err := sessionCache.GetSession(remoteHost, remoteUser, remotePass)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to authenticate with Nessus: %v", err)})
+	nessusSession, ok := s.nessusSession(c)
+	if !ok {
 		return
 	}
 
@@ -442,96 +564,124 @@ func (s *Server) handleGetNessusScanDetail(c *gin.Context) {
 }
 
 // Monitor scan progress in background
-func (s *Server) monitorScanProgress(n *nessus.Nessus, scanID, host, user, pass string) {
+func (s *Server) monitorScanProgress(n *nessus.Nessus, scanID, profile string) {
 	// Create a crash reporter
 	reporter := crash.NewReporter("crash_reports")
 
 	// Add extra information for crash reports
 	extra := map[string]string{
-		"scanID": scanID,
-		"host":   host,
-		"user":   user,
+		"scanID":  scanID,
+		"profile": profile,
 	}
 
 	// Recover from panics with crash reporting
 	defer reporter.RecoverWithCrashReport("ScanProgressMonitor", extra)
 
-	ticker := time.NewTicker(10 * time.Second)
-	defer ticker.Stop()
-
-	for {
-		select {
-		case <-ticker.C:
-			// Use the session cache to get a Nessus session instead of creating a new one each time
-			nessusSession, err := sessionCache.GetSession(host, user, pass)
-			if err != nil {
-				s.wsManager.BroadcastMessage("error", fmt.Sprintf("Failed to connect to Nessus: %v", err))
-				return
-			}
+	jobID := nessusJobID(scanID)
+	id, err := strconv.Atoi(scanID)
+	if err != nil {
+		s.wsManager.Log("error", jobID, fmt.Sprintf("Invalid scan ID %q", scanID))
+		return
+	}
 
-			// Get scan status using exported method
-			result, err := nessusSession.GetScanDetails(scanID)
-			if err != nil {
-				s.wsManager.BroadcastMessage("error", fmt.Sprintf("Failed to get scan status: %v", err))
-				return
-			}
+	watcher := n.NewWatcher(id)
+	watcher.Interval = 10 * time.Second
+	watcher.Subscribe(scanBroadcaster{s: s, scanID: scanID})
 
-			// Extract status info
-			info, ok := result["info"].(map[string]interface{})
-			if !ok {
-				continue
-			}
+	result, err := watcher.Watch(context.Background())
+	if err != nil {
+		s.wsManager.Publish(websocket.TopicScanResult, jobID, ScanResult{Status: "failed", Message: fmt.Sprintf("Failed to get scan status: %v", err)})
+		return
+	}
 
-			status, _ := info["status"].(string)
-			progress, _ := info["progress"].(float64)
+	status := result.Info.Status
+	s.wsManager.Publish(websocket.TopicScanResult, jobID, ScanResult{
+		Status:  status,
+		Message: fmt.Sprintf("Scan %s %s - %.0f%% complete", scanID, status, result.Progress()),
+	})
 
-			// Broadcast status update via WebSocket
-			s.wsManager.BroadcastMessage("scan_update", fmt.Sprintf("Scan %s: %s - %.0f%% complete", scanID, status, progress))
+	// Get all scans and broadcast updated list
+	rawScans, err := n.GetScans()
+	if err == nil {
+		scans := s.processScans(rawScans)
+		s.broadcastScansUpdate(scans)
+	}
+}
 
-			// If scan is no longer running, stop monitoring
-			if status == "completed" || status == "canceled" || status == "stopped" || status == "failed" {
-				s.wsManager.BroadcastMessage("scan_complete", fmt.Sprintf("Scan %s %s", scanID, status))
+// scanBroadcaster sends a watched scan's events to the WebSocket clients
+type scanBroadcaster struct {
+	s      *Server
+	scanID string
+}
 
-				// Get all scans and broadcast updated list
-				rawScans, err := nessusSession.GetScans()
-				if err == nil {
-					scans := s.processScans(rawScans, host, user)
-					s.broadcastScansUpdate(scans)
-				}
-				return
-			}
-		}
+func (b scanBroadcaster) OnScanEvent(e nessus.Event) {
+	ws := b.s.wsManager
+	jobID := nessusJobID(b.scanID)
+	progress := ScanProgress{
+		ScanID:   b.scanID,
+		Name:     e.ScanName,
+		Status:   e.Status,
+		Progress: e.Progress,
+	}
+	switch e.Type {
+	case nessus.EventProgress:
+		progress.Event = "progress"
+	case nessus.EventStatusChanged:
+		ws.Publish(websocket.TopicNessusStatus, jobID, NessusStatus{ScanID: b.scanID, Name: e.ScanName, Status: e.Status})
+		return
+	case nessus.EventHostCompleted:
+		progress.Event = "host_completed"
+		progress.Host = e.Host.Hostname
+		progress.Critical, progress.High = e.Host.Critical, e.Host.High
+	case nessus.EventNewCritical:
+		progress.Event = "new_critical"
+		progress.Host = e.Host.Hostname
+		progress.NewCriticals = e.NewCriticals
+	default:
+		return
 	}
+	ws.Publish(websocket.TopicScanProgress, jobID, progress)
 }
 
 // Broadcast scan list updates via WebSocket
 func (s *Server) broadcastScansUpdate(scans []ScanDetail) {
-	// Convert scan list to JSON
-	scanData, err := json.Marshal(scans)
-	if err != nil {
-		log.Printf("Failed to marshal scan data: %v", err)
-		return
-	}
-
-	s.wsManager.BroadcastMessage("scans_list", string(scanData))
+	s.wsManager.Publish(websocket.TopicNessusStatus, "", NessusStatus{Scans: scans})
 }
 
 func (s *Server) handleGetSettings(c *gin.Context) {
-	settings := Settings{
-		DefaultProjectFolder: "/evidence",
-		MaxWorkers:           4,
-		AutoStart:            true,
+	saved, err := settings.Load()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
 	}
 
-	c.JSON(http.StatusOK, settings)
+	c.JSON(http.StatusOK, saved)
 }
 
+// Validate and save the settings. The default Nessus profile is checked
+// against the vault when it is unlocked.
 func (s *Server) handleSaveSettings(c *gin.Context) {
-	var settings Settings
-	if err := c.BindJSON(&settings); err != nil {
+	var saved settings.Settings
+	if err := c.BindJSON(&saved); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := saved.Validate(); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	if saved.NessusProfile != "" && !s.vault.Locked() {
+		if _, err := s.vault.Get(saved.NessusProfile); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	if err := settings.Save(saved); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "Settings saved successfully"})
 }
@@ -546,9 +696,40 @@ func (s *Server) handleScan(c *gin.Context) {
 		return
 	}
 
-	// Validate required fields
-	if req.NessusFilePath == "" || req.ProjectFolder == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "nessusFilePath and projectFolder are required"})
+	// Validate required fields; the project folder defaults to the one in
+	// the settings
+	if req.NessusFilePath == "" && req.RetestFile == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "nessusFilePath (or retestFile) is required"})
+		return
+	}
+
+	// Cassettes are read and written on the server, so keep them in the
+	// project folder
+	for _, file := range []*string{&req.RecordFile, &req.ReplayFile} {
+		if *file == "" {
+			continue
+		}
+		path, err := projectFile(req.ProjectFolder, *file)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		*file = path
+	}
+
+	// A bad filter would only fail once the run has started, so check it
+	// against the plugin categories of the config the run will use
+	plugins := s.plugins
+	if req.ConfigFilePath != "" {
+		cfg, err := pluginconfig.ReadConfigFile(req.ConfigFilePath)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		plugins = cfg.Plugins
+	}
+	if err := req.Filter.Validate(plugins); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid filter: %v", err)})
 		return
 	}
 
@@ -562,29 +743,54 @@ func (s *Server) handleScan(c *gin.Context) {
 		RemoteKey:      req.RemoteKey,
 		NumWorkers:     req.NumWorkers,
 		ConfigFilePath: req.ConfigFilePath,
+		RecordFile:     req.RecordFile,
+		ReplayFile:     req.ReplayFile,
+		RetestFile:     req.RetestFile,
 		ExcludeFile:    req.ExcludeFile,
+		Filter:         req.Filter,
+	}
+	if err := s.applyProfile(&req, parsedArgs); err != nil {
+		vaultError(c, err)
+		return
 	}
 
 	// Add extra information for crash reports
 	extra := map[string]string{
 		"nessusFilePath": req.NessusFilePath,
 		"projectFolder":  req.ProjectFolder,
-		"host":           req.RemoteHost,
+		"profile":        req.Profile,
+		"host":           parsedArgs.RemoteHost,
 		"clientIP":       c.ClientIP(),
 	}
 
+	jobID := newJobID()
+	parsedArgs.JobID = jobID
 	go func() {
 		// Enhanced panic recovery with crash reporting
 		defer reporter.RecoverWithCrashReport("Scan", extra)
 
-		engine.RunNMB(parsedArgs)
+		if err := engine.RunNMB(parsedArgs); err != nil {
+			log.Printf("Scan failed: %v", err)
+			s.wsManager.Publish(websocket.TopicScanResult, jobID, ScanResult{Status: "failed", Message: fmt.Sprintf("Scan failed: %v", err)})
+			return
+		}
+		s.wsManager.Publish(websocket.TopicScanResult, jobID, ScanResult{Status: "completed", Message: "Scan finished"})
 	}()
 
-	c.JSON(http.StatusOK, gin.H{"message": "Scan started successfully"})
+	c.JSON(http.StatusOK, gin.H{"message": "Scan started successfully", "jobId": jobID})
 }
 
-func (s *Server) handleGetSupportedPlugins(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{"message": "Supported plugins retrieved successfully"})
+// projectFile resolves a file name relative to a scan's project folder,
+// the one in the settings by default, and rejects names outside it
+func projectFile(project, file string) (string, error) {
+	if project == "" {
+		saved, _ := settings.Load()
+		project = saved.DefaultProjectFolder
+	}
+	if !filepath.IsLocal(file) {
+		return "", fmt.Errorf("%s must be a file name in the project folder", file)
+	}
+	return filepath.Join(project, file), nil
 }
 
 func (s *Server) handleNessusController(c *gin.Context) {
@@ -600,35 +806,86 @@ func (s *Server) handleNessusController(c *gin.Context) {
 	parsedArgs := &args.Args{
 		NessusMode:  req.NessusMode,
 		RemoteHost:  req.RemoteHost,
+		NessusURL:   req.NessusURL,
 		RemoteUser:  req.RemoteUser,
 		RemotePass:  req.RemotePass,
 		ProjectName: req.ProjectName,
 		TargetsFile: req.TargetsFile,
 		ExcludeFile: req.ExcludeFile,
 		Discovery:   req.Discovery,
+		TopPorts:    req.TopPorts,
+		PolicyPath:  req.PolicyPath,
+		PolicyName:  req.PolicyName,
+		ScannerName: req.ScannerName,
+		FolderName:  req.FolderName,
+
+		ScheduleStart: req.ScheduleStart,
+		Timezone:      req.Timezone,
+		RRules:        req.RRules,
+		ScanWindow:    req.ScanWindow,
+	}
+	// Check the schedule and window before starting, as the run cannot
+	// report them back
+	if req.ScheduleStart != "" {
+		if _, err := nessus.ParseSchedule(req.ScheduleStart, req.Timezone, req.RRules); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid scan schedule: %v", err)})
+			return
+		}
+	}
+	if req.ScanWindow != "" {
+		if _, err := nessus.ParseWindow(req.ScanWindow, req.Timezone); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid scan window: %v", err)})
+			return
+		}
+	}
+
+	if req.Profile == "" && req.RemoteHost == "" && req.NessusURL == "" {
+		req.Profile = defaultProfile()
+	}
+	if err := s.applyProfile(&req, parsedArgs); err != nil {
+		vaultError(c, err)
+		return
 	}
 
 	// Add extra information for crash reports
 	extra := map[string]string{
 		"mode":     req.NessusMode,
-		"host":     req.RemoteHost,
-		"user":     req.RemoteUser,
+		"profile":  req.Profile,
+		"host":     parsedArgs.RemoteHost,
+		"user":     parsedArgs.RemoteUser,
 		"project":  req.ProjectName,
 		"targets":  req.TargetsFile,
 		"clientIP": c.ClientIP(),
 	}
 
+	jobID := newJobID()
+	parsedArgs.JobID = jobID
 	go func() {
 		// Add panic recovery with crash reporting
 		defer reporter.RecoverWithCrashReport("NessusController", extra)
 
-		engine.HandleNessusController(parsedArgs)
+		if err := engine.HandleNessusController(parsedArgs); err != nil {
+			log.Printf("Nessus %s operation failed: %v", req.NessusMode, err)
+			s.wsManager.Publish(websocket.TopicScanResult, jobID, ScanResult{
+				Status:  "failed",
+				Message: fmt.Sprintf("Nessus %s operation failed: %v", req.NessusMode, err),
+			})
+			return
+		}
+		s.wsManager.Publish(websocket.TopicScanResult, jobID, ScanResult{
+			Status:  "completed",
+			Message: fmt.Sprintf("Nessus %s operation finished", req.NessusMode),
+		})
 	}()
 
-	c.JSON(http.StatusOK, gin.H{"message": fmt.Sprintf("Nessus %s operation started", req.NessusMode)})
+	c.JSON(http.StatusOK, gin.H{"message": fmt.Sprintf("Nessus %s operation started", req.NessusMode), "jobId": jobID})
 }
+
+// Run serves the API until it fails
 func (s *Server) Run() error {
-	go s.wsManager.Start()
-	log.Println("WebSocket manager started on :8080/ws")
-	return s.router.Run(":8080")
+	log.Printf("API server listening on %s", s.ClientConfig().URL)
+	if s.certFile != "" {
+		return s.router.RunTLS(s.addr, s.certFile, s.keyFile)
+	}
+	return s.router.Run(s.addr)
 }
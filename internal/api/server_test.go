@@ -0,0 +1,46 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+
+	"testing"
+
+	"NMB/internal/settings"
+)
+
+func TestSettingsHandlers(t *testing.T) {
+	s := newTestServer(t)
+	spec := loadSpec(t)
+
+	status, body := call(t, s, spec, http.MethodGet, "/api/settings", "/api/settings", nil)
+	if status != http.StatusOK || body["defaultProjectFolder"] != "output" {
+		t.Fatalf("GET /api/settings = %d %v, want the defaults", status, body)
+	}
+
+	invalid := settings.Defaults()
+	invalid.MaxWorkers = 0
+	invalid.PlextracServers = []string{"not a name"}
+	status, body = call(t, s, spec, http.MethodPost, "/api/settings", "/api/settings", invalid)
+	if status != http.StatusBadRequest || !strings.Contains(body["error"].(string), "max workers") {
+		t.Errorf("POST invalid settings = %d %v, want 400", status, body)
+	}
+
+	unlockWithProfile(t, s)
+	unknown := settings.Defaults()
+	unknown.NessusProfile = "missing"
+	if status, _ := call(t, s, spec, http.MethodPost, "/api/settings", "/api/settings", unknown); status != http.StatusBadRequest {
+		t.Errorf("POST settings with an unknown profile = %d, want 400", status)
+	}
+
+	valid := settings.Defaults()
+	valid.MaxWorkers = 4
+	valid.NessusProfile = "lab"
+	if status, _ := call(t, s, spec, http.MethodPost, "/api/settings", "/api/settings", valid); status != http.StatusOK {
+		t.Fatalf("POST valid settings = %d, want 200", status)
+	}
+	status, body = call(t, s, spec, http.MethodGet, "/api/settings", "/api/settings", nil)
+	if status != http.StatusOK || body["maxWorkers"] != float64(4) || body["nessusProfile"] != "lab" {
+		t.Errorf("GET /api/settings after saving = %d %v", status, body)
+	}
+}
@@ -0,0 +1,33 @@
+package api
+
+import (
+	"io/fs"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ServeUI serves a build of ui-core for browsers, as the headless server
+// has no desktop window. Paths that are not files are the UI's own routes
+// and get index.html. The UI's files are public; the API it calls needs
+// the API token.
+func (s *Server) ServeUI(ui fs.FS) {
+	files := http.FileServer(http.FS(ui))
+
+	s.router.NoRoute(func(c *gin.Context) {
+		path := strings.TrimPrefix(c.Request.URL.Path, "/")
+		method := c.Request.Method
+		if (method != http.MethodGet && method != http.MethodHead) || path == "ws" || strings.HasPrefix(path, "api/") {
+			c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+			return
+		}
+
+		if path != "" {
+			if info, err := fs.Stat(ui, path); err != nil || info.IsDir() {
+				c.Request.URL.Path = "/"
+			}
+		}
+		files.ServeHTTP(c.Writer, c.Request)
+	})
+}
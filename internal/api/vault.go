@@ -0,0 +1,233 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+
+	"NMB/internal/args"
+	"NMB/internal/nessus-controller"
+	"NMB/internal/settings"
+	"NMB/internal/vault"
+)
+
+// NessusSessionCache keeps one authenticated Nessus session per vault
+// profile. Sessions renew themselves when Nessus rejects them, so they are
+// kept until the profile changes or the vault is locked.
+type NessusSessionCache struct {
+	sessions map[string]*nessus.Nessus
+	mutex    sync.Mutex
+}
+
+func newSessionCache() *NessusSessionCache {
+	return &NessusSessionCache{sessions: make(map[string]*nessus.Nessus)}
+}
+
+// GetSession gets or creates the session of a profile. Sessions only use
+// the Nessus API, so no SSH connection is made for them.
+func (c *NessusSessionCache) GetSession(profile vault.Profile) (*nessus.Nessus, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if session, exists := c.sessions[profile.Name]; exists {
+		return session, nil
+	}
+
+	url := profile.URL
+	if url == "" {
+		url = profile.Host
+	}
+	session, err := nessus.NewWithOptions(nessus.Options{
+		URL:       url,
+		Username:  profile.Username,
+		Password:  profile.Password,
+		AccessKey: profile.AccessKey,
+		SecretKey: profile.SecretKey,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	c.sessions[profile.Name] = session
+	return session, nil
+}
+
+// Forget drops the session of a profile
+func (c *NessusSessionCache) Forget(name string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	delete(c.sessions, name)
+}
+
+// Clear drops every session
+func (c *NessusSessionCache) Clear() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.sessions = make(map[string]*nessus.Nessus)
+}
+
+// openVault returns the credential vault, unlocked when the passphrase is
+// set in the environment
+func openVault() *vault.Vault {
+	path, err := vault.DefaultPath()
+	if err != nil {
+		log.Printf("Using %s in the working folder: %v", vault.FileName, err)
+		path = vault.FileName
+	}
+
+	v := vault.New(path)
+	if passphrase := os.Getenv(vault.EnvPassphrase); passphrase != "" {
+		if err := v.Unlock(passphrase); err != nil {
+			log.Printf("Failed to unlock credential vault: %v", err)
+		}
+	}
+	return v
+}
+
+// vaultError maps a vault error to a response status
+func vaultError(c *gin.Context, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, vault.ErrLocked):
+		status = http.StatusLocked
+	case errors.Is(err, vault.ErrWrongPassphrase):
+		status = http.StatusUnauthorized
+	case errors.Is(err, vault.ErrNoProfile):
+		status = http.StatusNotFound
+	}
+	c.JSON(status, gin.H{"error": err.Error()})
+}
+
+// defaultProfile returns the Nessus profile chosen in the settings
+func defaultProfile() string {
+	saved, err := settings.Load()
+	if err != nil {
+		log.Printf("Ignoring saved settings: %v", err)
+	}
+	return saved.NessusProfile
+}
+
+// profile returns the vault profile named by the profile query parameter, or
+// the default profile from the settings, writing the error response when
+// there is none
+func (s *Server) profile(c *gin.Context) (vault.Profile, bool) {
+	name := c.Query("profile")
+	if name == "" {
+		name = defaultProfile()
+	}
+	if name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "profile parameter is required without a default profile in the settings"})
+		return vault.Profile{}, false
+	}
+
+	profile, err := s.vault.Get(name)
+	if err != nil {
+		vaultError(c, err)
+		return vault.Profile{}, false
+	}
+	return profile, true
+}
+
+// applyProfile fills the connection arguments of a scan request from its
+// vault profile. Fields set in the request itself are kept.
+func (s *Server) applyProfile(req *ScanRequest, parsedArgs *args.Args) error {
+	if req.Profile == "" {
+		return nil
+	}
+	profile, err := s.vault.Get(req.Profile)
+	if err != nil {
+		return err
+	}
+
+	fill := func(field *string, value string) {
+		if *field == "" {
+			*field = value
+		}
+	}
+	fill(&parsedArgs.RemoteHost, profile.Host)
+	fill(&parsedArgs.NessusURL, profile.URL)
+	fill(&parsedArgs.RemoteUser, profile.Username)
+	fill(&parsedArgs.RemotePass, profile.Password)
+	fill(&parsedArgs.RemoteKey, profile.KeyFile)
+	fill(&parsedArgs.AccessKey, profile.AccessKey)
+	fill(&parsedArgs.SecretKey, profile.SecretKey)
+	return nil
+}
+
+// Get whether the vault is locked and where it is stored
+func (s *Server) handleGetVault(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"locked": s.vault.Locked(), "path": s.vault.Path()})
+}
+
+// Unlock the vault, creating it on first use
+func (s *Server) handleUnlockVault(c *gin.Context) {
+	var req struct {
+		Passphrase string `json:"passphrase"`
+	}
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := s.vault.Unlock(req.Passphrase); err != nil {
+		vaultError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Vault unlocked"})
+}
+
+// Lock the vault and drop the sessions opened with its profiles
+func (s *Server) handleLockVault(c *gin.Context) {
+	s.vault.Lock()
+	s.sessions.Clear()
+	c.JSON(http.StatusOK, gin.H{"message": "Vault locked"})
+}
+
+// Get the profiles without their secrets
+func (s *Server) handleGetProfiles(c *gin.Context) {
+	profiles, err := s.vault.Profiles()
+	if err != nil {
+		vaultError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"profiles": profiles})
+}
+
+// Add or replace a profile. Secrets left empty keep their saved values.
+func (s *Server) handleSaveProfile(c *gin.Context) {
+	var profile vault.Profile
+	if err := c.BindJSON(&profile); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	profile.Name = c.Param("name")
+
+	if err := profile.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := s.vault.Put(profile); err != nil {
+		vaultError(c, err)
+		return
+	}
+	s.sessions.Forget(profile.Name)
+
+	c.JSON(http.StatusOK, gin.H{"message": fmt.Sprintf("Profile %s saved", profile.Name)})
+}
+
+// Delete a profile
+func (s *Server) handleDeleteProfile(c *gin.Context) {
+	name := c.Param("name")
+	if err := s.vault.Delete(name); err != nil {
+		vaultError(c, err)
+		return
+	}
+	s.sessions.Forget(name)
+
+	c.JSON(http.StatusOK, gin.H{"message": fmt.Sprintf("Profile %s deleted", name)})
+}
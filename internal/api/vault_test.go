@@ -0,0 +1,52 @@
+package api
+
+import (
+	"net/http"
+
+	"testing"
+
+	"NMB/internal/vault"
+)
+
+func TestVaultHandlers(t *testing.T) {
+	s := newTestServer(t)
+	spec := loadSpec(t)
+
+	if status, body := call(t, s, spec, http.MethodGet, "/api/vault", "/api/vault", nil); status != http.StatusOK || body["locked"] != true {
+		t.Errorf("GET /api/vault = %d %v, want locked", status, body)
+	}
+	if status, _ := call(t, s, spec, http.MethodGet, "/api/profiles", "/api/profiles", nil); status != http.StatusLocked {
+		t.Errorf("GET /api/profiles while locked = %d, want 423", status)
+	}
+	if status, _ := call(t, s, spec, http.MethodPost, "/api/vault/unlock", "/api/vault/unlock", map[string]string{}); status != http.StatusInternalServerError {
+		t.Errorf("unlock without a passphrase = %d, want 500", status)
+	}
+	if status, _ := call(t, s, spec, http.MethodPost, "/api/vault/unlock", "/api/vault/unlock", map[string]string{"passphrase": "secret"}); status != http.StatusOK {
+		t.Fatalf("unlock = %d, want 200", status)
+	}
+
+	route := "/api/profiles/:name"
+	if status, _ := call(t, s, spec, http.MethodPut, route, "/api/profiles/lab", vault.Profile{Username: "nmb"}); status != http.StatusBadRequest {
+		t.Errorf("PUT a profile without a server = %d, want 400", status)
+	}
+	if status, _ := call(t, s, spec, http.MethodPut, route, "/api/profiles/lab", vault.Profile{URL: "https://nessus:8834", Password: "pw"}); status != http.StatusOK {
+		t.Errorf("PUT a profile = %d, want 200", status)
+	}
+	status, body := call(t, s, spec, http.MethodGet, "/api/profiles", "/api/profiles", nil)
+	profiles, _ := body["profiles"].([]interface{})
+	if status != http.StatusOK || len(profiles) != 1 {
+		t.Fatalf("GET /api/profiles = %d %v, want one profile", status, body)
+	}
+	if _, leaked := profiles[0].(map[string]interface{})["password"]; leaked {
+		t.Error("GET /api/profiles returned a password")
+	}
+	if status, _ := call(t, s, spec, http.MethodDelete, route, "/api/profiles/other", nil); status != http.StatusNotFound {
+		t.Errorf("DELETE an unknown profile = %d, want 404", status)
+	}
+	if status, _ := call(t, s, spec, http.MethodDelete, route, "/api/profiles/lab", nil); status != http.StatusOK {
+		t.Errorf("DELETE a profile = %d, want 200", status)
+	}
+	if status, _ := call(t, s, spec, http.MethodPost, "/api/vault/lock", "/api/vault/lock", nil); status != http.StatusOK {
+		t.Errorf("lock = %d, want 200", status)
+	}
+}
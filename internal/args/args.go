@@ -4,6 +4,11 @@ package args
 import (
 	"flag"
 	"fmt"
+	"os"
+	"strings"
+
+	"NMB/internal/nessus"
+	"NMB/internal/settings"
 )
 
 type Args struct {
@@ -12,6 +17,10 @@ type Args struct {
 	ConfigFilePath string
 	ProjectFolder  string
 	NumWorkers     int
+	RecordFile     string
+	ReplayFile     string
+	RetestFile     string
+	Filter         nessus.Filter
 
 	// Remote connection flags
 	RemoteHost string
@@ -21,45 +30,121 @@ type Args struct {
 
 	// Nessus controller specific flags
 	NessusMode  string
+	NessusURL   string
 	PolicyPath  string
+	PolicyName  string
+	ScannerName string
+	FolderName  string
 	TargetsFile string
 	ExcludeFile string
 	Discovery   bool
+	TopPorts    int
 	ProjectName string
 
+	// Nessus authentication, see nessus.LoadCredentials. AccessKey and
+	// SecretKey have no flags; the API server sets them from a vault profile.
+	CredentialsFile string
+	AccessKey       string
+	SecretKey       string
+
+	// Batch mode, see nessus.BatchOptions
+	BatchSize        int
+	BatchSubnet      int
+	BatchConcurrency int
+
+	// Scheduling
+	ScheduleStart string
+	Timezone      string
+	RRules        string
+	ScanWindow    string
+
+	// Export selection
+	ExportFormats    []string
+	ExportColumns    []string
+	ExportSeverities []string
+	ExportPluginIDs  []string
+	ExportHosts      []string
+	ExportTemplate   string
+	ExportDBPassword string
+	OutputDir        string
+
 	// Plugin manager specific flags
 	Plugin bool
+
+	// JobID has no flag; the API server sets it so the run's log events
+	// can be told apart
+	JobID string
 }
 
 func ParseArgs() *Args {
 	args := &Args{}
 
+	// Saved settings replace the built-in defaults of the flags they cover
+	defaults, err := settings.Load()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Ignoring saved settings: %v\n", err)
+	}
+
 	// NMB-specific flags
-	flag.StringVar(&args.NessusFilePath, "nessus", "path/to/nessus.csv", "Path to the Nessus CSV file")
-	flag.StringVar(&args.NessusFilePath, "n", "path/to/nessus.csv", "Path to the Nessus CSV file (short)")
+	flag.StringVar(&args.NessusFilePath, "nessus", "path/to/nessus.csv", "Nessus CSV/.nessus files or directories (comma separated)")
+	flag.StringVar(&args.NessusFilePath, "n", "path/to/nessus.csv", "Nessus CSV/.nessus files or directories (comma separated) (short)")
 
 	flag.StringVar(&args.ConfigFilePath, "config", "", "Path to the configuration file (optional)")
 	flag.StringVar(&args.ConfigFilePath, "c", "", "Path to the configuration file (optional) (short)")
 
-	flag.StringVar(&args.ProjectFolder, "project", "output", "Path to the project folder")
-	flag.StringVar(&args.ProjectFolder, "p", "output", "Path to the project folder (short)")
+	flag.StringVar(&args.ProjectFolder, "project", defaults.DefaultProjectFolder, "Path to the project folder")
+	flag.StringVar(&args.ProjectFolder, "p", defaults.DefaultProjectFolder, "Path to the project folder (short)")
+
+	flag.IntVar(&args.NumWorkers, "workers", defaults.MaxWorkers, "Number of concurrent workers")
+	flag.IntVar(&args.NumWorkers, "w", defaults.MaxWorkers, "Number of concurrent workers (short)")
 
-	flag.IntVar(&args.NumWorkers, "workers", 10, "Number of concurrent workers")
-	flag.IntVar(&args.NumWorkers, "w", 10, "Number of concurrent workers (short)")
+	flag.StringVar(&args.RecordFile, "record", "", "Record every command and its output to a cassette file")
+	flag.StringVar(&args.ReplayFile, "replay", "", "Replay command output from a cassette file instead of executing")
+	flag.StringVar(&args.RetestFile, "retest", "", "Retest the verified findings of a previous NMB_scan_report.json")
+
+	// Finding filters
+	flag.Var((*listFlag)(&args.Filter.Risks), "risk", "Only verify findings with these risks (e.g. Critical,High)")
+	flag.Var((*listFlag)(&args.Filter.PluginIDs), "plugin-ids", "Only verify these plugin IDs")
+	flag.Var((*listFlag)(&args.Filter.ExcludePluginIDs), "skip-plugins", "Skip these plugin IDs")
+	flag.Var((*listFlag)(&args.Filter.Categories), "category", "Only verify plugins in these config categories")
+	flag.Var((*listFlag)(&args.Filter.IncludeHosts), "hosts", "Only verify these hosts or CIDRs")
+	flag.Var((*listFlag)(&args.Filter.ExcludeHosts), "skip-hosts", "Skip these hosts or CIDRs")
+	flag.Var((*listFlag)(&args.Filter.Ports), "ports", "Only verify these ports or port ranges (e.g. 21,8000-8100)")
 
 	// Remote connection flags
 	flag.StringVar(&args.RemoteHost, "remote", "", "Remote host to execute commands")
 	flag.StringVar(&args.RemoteUser, "user", "", "Remote user for SSH connection")
-	flag.StringVar(&args.RemotePass, "password", "", "Remote password for SSH connection")
-	flag.StringVar(&args.RemoteKey, "key", "", "Path to SSH private key file (optional)")
+	flag.StringVar(&args.RemotePass, "password", "", "Remote password for SSH connection (Nessus controller: use -credentials or NESSUS_PASSWORD)")
+	flag.StringVar(&args.RemoteKey, "key", defaults.SSHKeyFile, "Path to SSH private key file (optional)")
 
 	// Nessus controller flags
-	flag.StringVar(&args.NessusMode, "mode", "", "Nessus operation mode (deploy, create, launch, monitor, pause, resume, export)")
-	flag.StringVar(&args.PolicyPath, "policy", "", "Path to Nessus policy file (.nessus)")
+	flag.StringVar(&args.NessusMode, "mode", "", "Nessus operation mode (deploy, create, launch, monitor, pause, resume, export, full, batch, policies, scanners)")
+	flag.StringVar(&args.NessusURL, "nessus-url", "", "Nessus URL (default: https://<remote>:8834), no SSH needed without -remote")
+	flag.StringVar(&args.CredentialsFile, "credentials", "", "Nessus credentials file (JSON with username, password, accessKey, secretKey)")
+	flag.StringVar(&args.PolicyPath, "policy", "", "Path to Nessus policy file (.nessus) to import and use")
+	flag.StringVar(&args.PolicyName, "policy-name", "", "Existing Nessus policy to use, by name or ID")
+	flag.StringVar(&args.ScannerName, "scanner", "", "Nessus scanner to use, by name or ID")
+	flag.StringVar(&args.FolderName, "folder", "", "Nessus folder to create the scan in, by name or ID")
+	flag.StringVar(&args.ScheduleStart, "start", "", "Schedule the scan to start at this time (YYYY-MM-DD HH:MM)")
+	flag.StringVar(&args.Timezone, "timezone", "", "Timezone for -start and -window (e.g. America/New_York)")
+	flag.StringVar(&args.RRules, "rrules", "", "Recurrence rules for a scheduled scan (e.g. FREQ=WEEKLY;BYDAY=SA)")
+	flag.Var((*listFlag)(&args.ExportFormats), "export-formats", "Formats to export (csv, nessus, html, pdf, db)")
+	flag.Var((*listFlag)(&args.ExportColumns), "export-columns", "CSV columns to export (e.g. id,risk,hostname,port,plugin_name)")
+	flag.Var((*listFlag)(&args.ExportSeverities), "export-severity", "Only export findings of these severities (critical, high, medium, low, info)")
+	flag.Var((*listFlag)(&args.ExportPluginIDs), "export-plugins", "Only export these plugin IDs")
+	flag.Var((*listFlag)(&args.ExportHosts), "export-hosts", "Only export these hosts or CIDRs")
+	flag.StringVar(&args.ExportTemplate, "export-template", "", "Report template for html/pdf exports")
+	flag.StringVar(&args.ExportDBPassword, "export-db-password", "", "Password for db exports")
+	flag.StringVar(&args.OutputDir, "output", "", "Directory to write exported evidence to (default: the project folder with -discovery, -window, full or batch, otherwise next to the binary)")
+	flag.StringVar(&args.ScanWindow, "window", "", "Only let the scan run inside this window (e.g. \"Mon-Fri 22:00-06:00\")")
 	flag.StringVar(&args.TargetsFile, "targets", "", "Path to targets file")
 	flag.StringVar(&args.ExcludeFile, "exclude", "", "Path to exclude targets file")
 	flag.BoolVar(&args.Discovery, "discovery", false, "Enable host discovery scan")
+	flag.IntVar(&args.TopPorts, "top-ports", 0, "Also scan the top N TCP ports during discovery")
 	flag.StringVar(&args.ProjectName, "name", "", "Project name for the scan")
+	flag.IntVar(&args.BatchSize, "batch-size", 0, "Targets per scan in batch mode")
+	flag.IntVar(&args.BatchSubnet, "batch-subnet", 0, "Split targets by subnet of this prefix length in batch mode (e.g. 24)")
+	flag.IntVar(&args.BatchConcurrency, "batch-concurrency", 2, "Scans running at once in batch mode")
 
 	// plugin manager
 	flag.BoolVar(&args.Plugin, "plugin", false, "Enable plugin manager mode")
@@ -68,42 +153,124 @@ func ParseArgs() *Args {
 	flag.Usage = customUsage
 
 	flag.Parse()
+
+	// A password on the command line ends up in the shell history and the
+	// process list
+	if args.NessusMode != "" && args.RemotePass != "" {
+		fmt.Fprintln(os.Stderr, "Warning: -password is deprecated for Nessus controller modes and only used when -credentials and NESSUS_PASSWORD give none")
+	}
 	return args
 }
 
+// listFlag collects a comma separated flag value, and may be repeated
+type listFlag []string
+
+func (l *listFlag) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *listFlag) Set(value string) error {
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			*l = append(*l, item)
+		}
+	}
+	return nil
+}
+
 func customUsage() {
 	fmt.Printf("Usage: %s [options]\n\n", flag.CommandLine.Name())
 
 	fmt.Println("NMB Mode Options:")
-	fmt.Println("  -n, -nessus     Path to the Nessus CSV file")
+	fmt.Println("  -n, -nessus     Nessus CSV/.nessus files or directories (comma separated)")
 	fmt.Println("  -c, -config     Path to the configuration file")
 	fmt.Println("  -p, -project    Path to the project folder")
 	fmt.Println("  -w, -workers    Number of concurrent workers")
+	fmt.Println("  -record         Record commands and output to a cassette file")
+	fmt.Println("  -replay         Replay command output from a cassette file")
+	fmt.Println("  -retest         Retest the verified findings of a previous report (JSON)")
+
+	fmt.Println("\nFinding Filters:")
+	fmt.Println("  -risk           Only verify findings with these risks (e.g. Critical,High)")
+	fmt.Println("  -plugin-ids     Only verify these plugin IDs")
+	fmt.Println("  -skip-plugins   Skip these plugin IDs")
+	fmt.Println("  -category       Only verify plugins in these config categories")
+	fmt.Println("  -hosts          Only verify these hosts or CIDRs")
+	fmt.Println("  -skip-hosts     Skip these hosts or CIDRs")
+	fmt.Println("  -ports          Only verify these ports or port ranges")
 
 	fmt.Println("\nRemote Connection Options:")
 	fmt.Println("  -remote         Remote host to execute commands")
 	fmt.Println("  -user           Remote user for SSH connection")
-	fmt.Println("  -password       Remote password for SSH connection")
+	fmt.Println("  -password       Remote password for SSH connection in NMB mode")
 	fmt.Println("  -key            Path to SSH private key file")
 
 	fmt.Println("\nNessus Controller Options:")
-	fmt.Println("  -mode           Nessus operation mode (deploy, create, launch, monitor, pause, resume, export)")
-	fmt.Println("  -policy         Path to Nessus policy file")
+	fmt.Println("  -mode           Nessus operation mode (deploy, create, launch, monitor, pause, resume, export, full, batch, policies, scanners)")
+	fmt.Println("  -nessus-url     Nessus URL (default: https://<remote>:8834), no SSH needed without -remote")
+	fmt.Println("  -credentials    Nessus credentials file (JSON with username, password, accessKey, secretKey)")
+	fmt.Println("  -policy         Path to Nessus policy file to import and use")
+	fmt.Println("  -policy-name    Existing Nessus policy to use, by name or ID")
+	fmt.Println("  -scanner        Nessus scanner to use, by name or ID")
+	fmt.Println("  -folder         Nessus folder to create the scan in, by name or ID")
+	fmt.Println("  -start          Schedule the scan to start at this time (YYYY-MM-DD HH:MM)")
+	fmt.Println("  -timezone       Timezone for -start and -window (e.g. America/New_York)")
+	fmt.Println("  -rrules         Recurrence rules for a scheduled scan (e.g. FREQ=WEEKLY;BYDAY=SA)")
+	fmt.Println("  -window         Pause the scan outside this window (e.g. \"Mon-Fri 22:00-06:00\")")
+	fmt.Println("  -export-formats Formats to export (csv, nessus, html, pdf, db)")
+	fmt.Println("  -export-columns CSV columns to export (e.g. id,risk,hostname,port,plugin_name)")
+	fmt.Println("  -export-severity Only export these severities (critical, high, medium, low, info)")
+	fmt.Println("  -export-plugins Only export these plugin IDs")
+	fmt.Println("  -export-hosts   Only export these hosts or CIDRs")
+	fmt.Println("  -export-template Report template for html/pdf exports")
+	fmt.Println("  -export-db-password Password for db exports")
+	fmt.Println("  -output         Directory to write exported evidence to")
 	fmt.Println("  -targets        Path to targets file")
 	fmt.Println("  -exclude        Path to exclude targets file")
 	fmt.Println("  -discovery      Enable host discovery scan")
+	fmt.Println("  -top-ports      Also scan the top N TCP ports during discovery")
 	fmt.Println("  -name           Project name for the scan")
+	fmt.Println("  -batch-size     Targets per scan in batch mode")
+	fmt.Println("  -batch-subnet   Split targets by subnet of this prefix length in batch mode (e.g. 24)")
+	fmt.Println("  -batch-concurrency Scans running at once in batch mode (default 2)")
 
 	fmt.Println("\nExamples:")
 	fmt.Println("  NMB Mode:")
 	fmt.Println("    nmb -nessus scan.csv -project ./output")
 	fmt.Println("    nmb -n scan.csv -p ./output -w 20")
+	fmt.Println("    nmb -n internal.csv,external.nessus -p ./output")
+	fmt.Println("    nmb -n ./exports -p ./output")
+	fmt.Println("    nmb -n scan.csv -p ./output -risk Critical,High -hosts 10.0.0.0/24 -skip-plugins 10079")
+	fmt.Println("    nmb -n scan.csv -p ./output -record run.cassette.json")
+	fmt.Println("    nmb -n scan.csv -p ./demo -replay run.cassette.json")
+	fmt.Println("    nmb -retest ./engagement/NMB_scan_report.json -p ./retest")
 
 	fmt.Println("\n  Nessus Controller Mode:")
-	fmt.Println("    nmb -mode deploy -remote 192.168.1.10 -user admin -password secret -name TestScan -targets hosts.txt")
-	fmt.Println("    nmb -mode create -remote 192.168.1.10 -user admin -password secret -name TestScan -targets hosts.txt -discovery")
-	fmt.Println("    nmb -mode launch -remote 192.168.1.10 -user admin -password secret -name TestScan")
+	fmt.Println("    nmb -mode deploy -remote 192.168.1.10 -credentials nessus.json -name TestScan -targets hosts.txt")
+	fmt.Println("    nmb -mode create -remote 192.168.1.10 -credentials nessus.json -name TestScan -targets hosts.txt -discovery")
+	fmt.Println("    nmb -mode launch -remote 192.168.1.10 -credentials nessus.json -name TestScan")
+	fmt.Println("    nmb -mode full -remote 192.168.1.10 -credentials nessus.json -name TestScan -targets hosts.txt -p ./engagement")
+	fmt.Println("    nmb -mode batch -remote 192.168.1.10 -credentials nessus.json -name TestScan -targets hosts.txt -batch-subnet 24 -batch-concurrency 3 -p ./engagement")
+	fmt.Println("    nmb -mode create -remote 192.168.1.10 -credentials nessus.json -name TestScan -targets hosts.txt -policy client.nessus -folder Engagements")
+	fmt.Println("    nmb -mode export -remote 192.168.1.10 -credentials nessus.json -name TestScan -export-formats csv,pdf -export-severity critical,high -output ./evidence")
+	fmt.Println("    nmb -mode policies -remote 192.168.1.10 -credentials nessus.json")
+	fmt.Println("    nmb -mode deploy -nessus-url https://nessus.example.com:8834 -credentials nessus.json -name TestScan -targets hosts.txt")
+	fmt.Println("    nmb -mode deploy -remote 192.168.1.10 -user admin -key ~/.ssh/id_rsa -credentials nessus.json -name TestScan -targets hosts.txt")
+	fmt.Println("    nmb -mode create -remote 192.168.1.10 -credentials nessus.json -name TestScan -targets hosts.txt -start \"2026-11-07 22:00\" -timezone America/New_York")
+	fmt.Println("    nmb -mode monitor -remote 192.168.1.10 -credentials nessus.json -name TestScan -window \"Mon-Fri 22:00-06:00\" -timezone America/New_York")
+
+	fmt.Println("\n  Plugin Test Mode:")
+	fmt.Println("    nmb plugin test")
+	fmt.Println("    nmb plugin test -c custom_config.json Anon_FTP_Checks")
+
+	fmt.Println("\n  Diff Mode:")
+	fmt.Println("    nmb diff ./engagement ./retest")
+	fmt.Println("    nmb diff -format html -o diff.html ./engagement ./retest")
 
 	fmt.Println("\n UI Mode:")
 	fmt.Println("    nmb serve")
+	fmt.Println("    nmb serve -listen 0.0.0.0:8080 -tls -origins https://nmb.example.com")
+
+	fmt.Println("\n  Headless Server Mode:")
+	fmt.Println("    nmb server -listen 0.0.0.0:8080 -tls")
 }
@@ -0,0 +1,216 @@
+// Package cassette records the command/output pairs of a scan so the run can
+// be replayed offline, without access to the client network.
+package cassette
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// ErrNotRecorded is returned when replaying a command the cassette has no
+// recording for
+var ErrNotRecorded = errors.New("command not recorded in cassette")
+
+// Interaction is a single executed command and what it produced
+type Interaction struct {
+	Command string `json:"command"`
+	Output  string `json:"output"`
+	Error   string `json:"error,omitempty"`
+}
+
+// Cassette holds the interactions of a run, either being recorded or replayed
+type Cassette struct {
+	RecordedAt   time.Time     `json:"recorded_at"`
+	Interactions []Interaction `json:"interactions"`
+
+	path      string
+	replaying bool
+	played    map[string]int
+	mu        sync.Mutex
+
+	// file is the cassette being recorded and end the offset of the closing
+	// bracket that each recording is written over
+	file *os.File
+	end  int64
+}
+
+// trailer closes the interactions list and the cassette object, so the file
+// is a complete cassette after every recording
+const trailer = "\n  ]\n}\n"
+
+// New creates an empty cassette that records into the given file from the
+// first Save or Record
+func New(path string) *Cassette {
+	return &Cassette{
+		RecordedAt: time.Now(),
+		path:       path,
+	}
+}
+
+// Load reads a recorded cassette for replay
+func Load(path string) (*Cassette, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cassette: %w", err)
+	}
+
+	c := &Cassette{
+		path:      path,
+		replaying: true,
+		played:    make(map[string]int),
+	}
+	if err := json.Unmarshal(data, c); err != nil {
+		return nil, fmt.Errorf("failed to parse cassette: %w", err)
+	}
+
+	return c, nil
+}
+
+// Replaying reports whether commands should be answered from the cassette
+// instead of being executed
+func (c *Cassette) Replaying() bool {
+	return c.replaying
+}
+
+// Record stores the result of an executed command and appends it to the
+// cassette file, so a run that crashes or is interrupted keeps what it
+// recorded
+func (c *Cassette) Record(command, output string, err error) error {
+	interaction := Interaction{
+		Command: command,
+		Output:  output,
+	}
+	if err != nil {
+		interaction.Error = err.Error()
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.Interactions = append(c.Interactions, interaction)
+	if c.file == nil {
+		return c.save()
+	}
+	return c.append(interaction, len(c.Interactions) > 1)
+}
+
+// Play returns the recorded result for a command. A command recorded several
+// times (e.g. a retry) is answered in recording order, and the last recording
+// is repeated once they are used up.
+func (c *Cassette) Play(command string) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var matches []Interaction
+	for _, interaction := range c.Interactions {
+		if interaction.Command == command {
+			matches = append(matches, interaction)
+		}
+	}
+	if len(matches) == 0 {
+		return "", fmt.Errorf("%w: %s", ErrNotRecorded, command)
+	}
+
+	index := c.played[command]
+	if index >= len(matches) {
+		index = len(matches) - 1
+	}
+	c.played[command] = index + 1
+
+	interaction := matches[index]
+	if interaction.Error != "" {
+		return interaction.Output, errors.New(interaction.Error)
+	}
+	return interaction.Output, nil
+}
+
+// Save writes the recorded interactions to the cassette file and keeps it
+// open for the interactions recorded after
+func (c *Cassette) Save() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.save()
+}
+
+// save needs c.mu. It writes the whole cassette once; later recordings are
+// appended by append.
+func (c *Cassette) save() error {
+	if c.file != nil {
+		return nil
+	}
+
+	header, err := json.Marshal(c.RecordedAt)
+	if err != nil {
+		return fmt.Errorf("failed to encode cassette: %w", err)
+	}
+
+	file, err := os.Create(c.path)
+	if err != nil {
+		return fmt.Errorf("failed to write cassette: %w", err)
+	}
+	if _, err := fmt.Fprintf(file, "{\n  \"recorded_at\": %s,\n  \"interactions\": [", header); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to write cassette: %w", err)
+	}
+	c.file = file
+	if c.end, err = file.Seek(0, io.SeekCurrent); err != nil {
+		return fmt.Errorf("failed to write cassette: %w", err)
+	}
+
+	for i, interaction := range c.Interactions {
+		if err := c.append(interaction, i > 0); err != nil {
+			return err
+		}
+	}
+	if len(c.Interactions) == 0 {
+		if _, err := file.WriteAt([]byte(trailer), c.end); err != nil {
+			return fmt.Errorf("failed to write cassette: %w", err)
+		}
+	}
+	return nil
+}
+
+// append needs c.mu. It writes an interaction over the trailer and the
+// trailer after it.
+func (c *Cassette) append(interaction Interaction, comma bool) error {
+	data, err := json.Marshal(interaction)
+	if err != nil {
+		return fmt.Errorf("failed to encode cassette: %w", err)
+	}
+
+	var entry []byte
+	if comma {
+		entry = append(entry, ',')
+	}
+	entry = append(entry, "\n    "...)
+	entry = append(entry, data...)
+
+	if _, err := c.file.WriteAt(append(entry, trailer...), c.end); err != nil {
+		return fmt.Errorf("failed to write cassette: %w", err)
+	}
+	c.end += int64(len(entry))
+	return nil
+}
+
+// Close closes the cassette file being recorded
+func (c *Cassette) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.file == nil {
+		return nil
+	}
+	err := c.file.Close()
+	c.file = nil
+	return err
+}
+
+// Len returns the number of recorded interactions
+func (c *Cassette) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.Interactions)
+}
@@ -0,0 +1,104 @@
+package cassette
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRecordAndReplay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "run.cassette.json")
+
+	tape := New(path)
+	if err := tape.Save(); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	if _, err := Load(path); err != nil {
+		t.Fatalf("Load of an empty cassette: %v", err)
+	}
+
+	recordings := []struct {
+		command, output string
+		err             error
+	}{
+		{"nmap -p 443 10.0.0.1", "443/tcp open", nil},
+		{"curl -k https://10.0.0.1", "", errors.New("connection refused")},
+		{"curl -k https://10.0.0.1", "<html>retry</html>", nil},
+		{"nmap -p 22 10.0.0.2", "22/tcp \"filtered\"\n", nil},
+	}
+	for i, r := range recordings {
+		if err := tape.Record(r.command, r.output, r.err); err != nil {
+			t.Fatalf("Record: %v", err)
+		}
+
+		// The file is a complete cassette after every recording
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var saved Cassette
+		if err := json.Unmarshal(data, &saved); err != nil {
+			t.Fatalf("cassette after %d recordings is not valid JSON: %v\n%s", i+1, err, data)
+		}
+		if len(saved.Interactions) != i+1 {
+			t.Fatalf("cassette after %d recordings has %d interactions", i+1, len(saved.Interactions))
+		}
+	}
+	if err := tape.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	replay, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if !replay.Replaying() || replay.Len() != len(recordings) {
+		t.Fatalf("loaded cassette replaying %v with %d interactions", replay.Replaying(), replay.Len())
+	}
+	if !replay.RecordedAt.Equal(tape.RecordedAt) {
+		t.Errorf("recorded at %v, want %v", replay.RecordedAt, tape.RecordedAt)
+	}
+
+	// A command recorded twice is answered in recording order, and the
+	// last answer is repeated after that
+	plays := []struct {
+		command, output, err string
+	}{
+		{"curl -k https://10.0.0.1", "", "connection refused"},
+		{"curl -k https://10.0.0.1", "<html>retry</html>", ""},
+		{"curl -k https://10.0.0.1", "<html>retry</html>", ""},
+		{"nmap -p 22 10.0.0.2", "22/tcp \"filtered\"\n", ""},
+		{"nmap -p 443 10.0.0.1", "443/tcp open", ""},
+	}
+	for _, p := range plays {
+		output, err := replay.Play(p.command)
+		var gotErr string
+		if err != nil {
+			gotErr = err.Error()
+		}
+		if output != p.output || gotErr != p.err {
+			t.Errorf("Play(%q) = %q, %q, want %q, %q", p.command, output, gotErr, p.output, p.err)
+		}
+	}
+
+	if _, err := replay.Play("nmap -p 80 10.0.0.3"); !errors.Is(err, ErrNotRecorded) {
+		t.Errorf("Play of a command not recorded = %v, want ErrNotRecorded", err)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := Load(filepath.Join(dir, "missing.json")); err == nil {
+		t.Error("Load accepted a missing cassette")
+	}
+
+	broken := filepath.Join(dir, "broken.json")
+	if err := os.WriteFile(broken, []byte(`{"interactions": [`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(broken); err == nil {
+		t.Error("Load accepted a truncated cassette")
+	}
+}
@@ -3,6 +3,8 @@ package config
 import (
 	"embed"
 	"encoding/json"
+	"fmt"
+	"io/fs"
 	"log"
 	"os"
 )
@@ -10,6 +12,9 @@ import (
 //go:embed config.json
 var configFile embed.FS
 
+//go:embed fixtures
+var fixtureFiles embed.FS
+
 type Plugin struct {
 	IDs         []string `json:"ids"`
 	ScanType    string   `json:"scan_type"`
@@ -35,14 +40,33 @@ func LoadEmbeddedConfig() Config {
 }
 
 func LoadConfigFromFile(filePath string) Config {
+	config, err := ReadConfigFile(filePath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return config
+}
+
+// ReadConfigFile reads a plugin config file, returning an error where
+// LoadConfigFromFile exits
+func ReadConfigFile(filePath string) (Config, error) {
 	var config Config
 	data, err := os.ReadFile(filePath)
 	if err != nil {
-		log.Fatalf("Failed to read config file: %v", err)
+		return config, fmt.Errorf("failed to read config file: %v", err)
 	}
-	err = json.Unmarshal(data, &config)
+	if err := json.Unmarshal(data, &config); err != nil {
+		return config, fmt.Errorf("failed to parse config file %s: %v", filePath, err)
+	}
+	return config, nil
+}
+
+// EmbeddedFixtures returns the recorded plugin output samples that ship
+// alongside the embedded config, rooted at the fixtures directory.
+func EmbeddedFixtures() fs.FS {
+	fixtures, err := fs.Sub(fixtureFiles, "fixtures")
 	if err != nil {
-		log.Fatalf("Failed to parse config file: %v", err)
+		log.Fatalf("Failed to read embedded fixtures: %v", err)
 	}
-	return config
+	return fixtures
 }
@@ -0,0 +1,297 @@
+// Package diff compares the results of two NMB runs, e.g. an engagement and
+// its retest, per plugin, host and port.
+package diff
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"sort"
+	"strings"
+	"time"
+
+	"NMB/internal/report"
+)
+
+// Classification of a (plugin, host, port) between two runs
+const (
+	NewlyVerified    = "Newly Verified"
+	Fixed            = "Fixed"
+	StillVulnerable  = "Still Vulnerable"
+	NewlyUnreachable = "Newly Unreachable"
+	NotRechecked     = "Not Rechecked"
+)
+
+// Statuses recorded by the scanner
+const (
+	statusVerified    = "Verified"
+	statusUnverified  = "Verification Failed"
+	statusPortClosed  = "Port Closed"
+	statusTimeout     = "Timeout"
+	statusCommandFail = "Command Failed"
+)
+
+// Classes lists the classifications in the order they are reported
+var Classes = []string{NewlyVerified, StillVulnerable, Fixed, NewlyUnreachable, NotRechecked}
+
+// Change is a single (plugin, host, port) whose state is reported
+type Change struct {
+	PluginID  string `json:"pluginId"`
+	Host      string `json:"host"`
+	Port      string `json:"port"`
+	Name      string `json:"name"`
+	Class     string `json:"class"`
+	OldStatus string `json:"oldStatus,omitempty"`
+	NewStatus string `json:"newStatus,omitempty"`
+
+	// Evidence from both runs
+	OldCommand string `json:"oldCommand,omitempty"`
+	OldOutput  string `json:"oldOutput,omitempty"`
+	NewCommand string `json:"newCommand,omitempty"`
+	NewOutput  string `json:"newOutput,omitempty"`
+}
+
+// Diff is the comparison of two runs
+type Diff struct {
+	Old     string    `json:"old"`
+	New     string    `json:"new"`
+	Date    time.Time `json:"date"`
+	Changes []Change  `json:"changes"`
+}
+
+type key struct {
+	pluginID, host, port string
+}
+
+// Compare classifies every result of the new run against the old one. A
+// result is Newly Verified when it verifies now but did not before, Still
+// Vulnerable when it verifies in both, Fixed when a previously verified
+// issue now fails verification, and Newly Unreachable when a previously
+// verified issue could not be checked (closed port, timeout, failed command).
+// A previously verified issue missing from the new run is Not Rechecked; the
+// scanner checks one host per plugin, and which one can differ between runs.
+func Compare(oldName string, oldReport *report.Report, newName string, newReport *report.Report) *Diff {
+	oldResults := index(oldReport)
+	newResults := index(newReport)
+
+	d := &Diff{
+		Old:  oldName,
+		New:  newName,
+		Date: time.Now(),
+	}
+
+	for k, result := range newResults {
+		previous, seen := oldResults[k]
+		wasVerified := seen && previous.Status == statusVerified
+
+		var class string
+		switch {
+		case result.Status == statusVerified && wasVerified:
+			class = StillVulnerable
+		case result.Status == statusVerified:
+			class = NewlyVerified
+		case !wasVerified:
+			continue
+		case result.Status == statusUnverified:
+			class = Fixed
+		default:
+			class = NewlyUnreachable
+		}
+
+		change := Change{
+			PluginID:  result.PluginID,
+			Host:      result.Host,
+			Port:      result.Port,
+			Name:      result.Name,
+			Class:     class,
+			NewStatus: result.Status,
+
+			NewCommand: result.Command,
+			NewOutput:  result.Output,
+		}
+		if seen {
+			change.OldStatus = previous.Status
+			change.OldCommand = previous.Command
+			change.OldOutput = previous.Output
+		}
+		d.Changes = append(d.Changes, change)
+	}
+
+	for k, previous := range oldResults {
+		if _, checked := newResults[k]; checked || previous.Status != statusVerified {
+			continue
+		}
+		d.Changes = append(d.Changes, Change{
+			PluginID:  previous.PluginID,
+			Host:      previous.Host,
+			Port:      previous.Port,
+			Name:      previous.Name,
+			Class:     NotRechecked,
+			OldStatus: previous.Status,
+
+			OldCommand: previous.Command,
+			OldOutput:  previous.Output,
+		})
+	}
+
+	sort.Slice(d.Changes, func(i, j int) bool {
+		a, b := d.Changes[i], d.Changes[j]
+		if a.Class != b.Class {
+			return classOrder(a.Class) < classOrder(b.Class)
+		}
+		if a.PluginID != b.PluginID {
+			return a.PluginID < b.PluginID
+		}
+		if a.Host != b.Host {
+			return a.Host < b.Host
+		}
+		return a.Port < b.Port
+	})
+
+	return d
+}
+
+// index keeps one result per (plugin, host, port). A key checked several
+// times (e.g. the -Pn retry) keeps its most conclusive status.
+func index(r *report.Report) map[key]report.ScanResult {
+	results := make(map[key]report.ScanResult)
+	for _, result := range r.ScanResults {
+		k := key{result.PluginID, result.Host, result.Port}
+		if existing, ok := results[k]; ok && statusRank(existing.Status) >= statusRank(result.Status) {
+			continue
+		}
+		results[k] = result
+	}
+	return results
+}
+
+func statusRank(status string) int {
+	switch status {
+	case statusVerified:
+		return 3
+	case statusUnverified:
+		return 2
+	case statusPortClosed, statusTimeout, statusCommandFail:
+		return 1
+	default:
+		return 0
+	}
+}
+
+func classOrder(class string) int {
+	for i, c := range Classes {
+		if c == class {
+			return i
+		}
+	}
+	return len(Classes)
+}
+
+// Count returns the number of changes with the given classification
+func (d *Diff) Count(class string) int {
+	count := 0
+	for _, change := range d.Changes {
+		if change.Class == class {
+			count++
+		}
+	}
+	return count
+}
+
+// JSON renders the diff as indented JSON
+func (d *Diff) JSON() (string, error) {
+	data, err := json.MarshalIndent(d, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to encode diff: %w", err)
+	}
+	return string(data), nil
+}
+
+// Markdown renders the diff in the style of the markdown scan report
+func (d *Diff) Markdown() string {
+	var sb strings.Builder
+
+	sb.WriteString("# NMB Scan Diff\n\n")
+	sb.WriteString(fmt.Sprintf("**Date:** %s\n\n", d.Date.Format(time.RFC1123)))
+	sb.WriteString(fmt.Sprintf("**Old:** %s\n\n", d.Old))
+	sb.WriteString(fmt.Sprintf("**New:** %s\n\n", d.New))
+
+	sb.WriteString("## Summary\n")
+	for _, class := range Classes {
+		sb.WriteString(fmt.Sprintf("- %s: %d\n", class, d.Count(class)))
+	}
+
+	for _, class := range Classes {
+		sb.WriteString(fmt.Sprintf("\n## %s\n", class))
+		if d.Count(class) == 0 {
+			sb.WriteString("None\n")
+			continue
+		}
+		sb.WriteString("| Plugin ID | Name | Host | Port | Old Status | New Status |\n")
+		sb.WriteString("|---|---|---|---|---|---|\n")
+		for _, change := range d.Changes {
+			if change.Class != class {
+				continue
+			}
+			sb.WriteString(fmt.Sprintf("| %s | %s | %s | %s | %s | %s |\n",
+				change.PluginID, change.Name, change.Host, change.Port, orNone(change.OldStatus), orNone(change.NewStatus)))
+		}
+	}
+
+	return sb.String()
+}
+
+// HTML renders the diff in the style of the HTML scan report
+func (d *Diff) HTML() string {
+	var sb strings.Builder
+
+	sb.WriteString("<html><head><title>NMB Scan Diff</title>")
+	sb.WriteString(`<link href="https://cdnjs.cloudflare.com/ajax/libs/tailwindcss/2.2.19/tailwind.min.css" rel="stylesheet">`)
+	sb.WriteString(`<style>
+		body { background-color: #1a202c; color: #cbd5e0; }
+		td, th { border: 1px solid #4a5568; padding: 0.25rem 0.5rem; text-align: left; }
+	</style>`)
+	sb.WriteString("</head><body>")
+	sb.WriteString("<div class='container mx-auto mt-5'>")
+	sb.WriteString("<h1 class='text-4xl font-bold mb-4'>NMB Scan Diff</h1>")
+	sb.WriteString(fmt.Sprintf("<p><strong>Date:</strong> %s</p>", d.Date.Format(time.RFC1123)))
+	sb.WriteString(fmt.Sprintf("<p><strong>Old:</strong> %s</p>", html.EscapeString(d.Old)))
+	sb.WriteString(fmt.Sprintf("<p class='mb-4'><strong>New:</strong> %s</p>", html.EscapeString(d.New)))
+
+	sb.WriteString("<h2 class='text-2xl font-semibold mt-4'>Summary</h2>")
+	sb.WriteString("<ul class='list-disc list-inside'>")
+	for _, class := range Classes {
+		sb.WriteString(fmt.Sprintf("<li>%s: %d</li>", class, d.Count(class)))
+	}
+	sb.WriteString("</ul>")
+
+	for _, class := range Classes {
+		sb.WriteString(fmt.Sprintf("<h2 class='text-2xl font-semibold mt-4'>%s</h2>", class))
+		if d.Count(class) == 0 {
+			sb.WriteString("<p class='text-gray-500'>None</p>")
+			continue
+		}
+		sb.WriteString("<table class='mb-4'><tr><th>Plugin ID</th><th>Name</th><th>Host</th><th>Port</th><th>Old Status</th><th>New Status</th></tr>")
+		for _, change := range d.Changes {
+			if change.Class != class {
+				continue
+			}
+			sb.WriteString(fmt.Sprintf("<tr><td>%s</td><td>%s</td><td>%s</td><td>%s</td><td>%s</td><td>%s</td></tr>",
+				html.EscapeString(change.PluginID), html.EscapeString(change.Name), html.EscapeString(change.Host),
+				html.EscapeString(change.Port), html.EscapeString(orNone(change.OldStatus)), html.EscapeString(orNone(change.NewStatus))))
+		}
+		sb.WriteString("</table>")
+	}
+
+	sb.WriteString("</div>")
+	sb.WriteString("</body></html>")
+
+	return sb.String()
+}
+
+func orNone(status string) string {
+	if status == "" {
+		return "Not Checked"
+	}
+	return status
+}
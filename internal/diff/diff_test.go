@@ -0,0 +1,68 @@
+package diff
+
+import (
+	"testing"
+
+	"NMB/internal/report"
+)
+
+func result(pluginID, host, status string) report.ScanResult {
+	return report.ScanResult{PluginID: pluginID, Host: host, Port: "443", Status: status}
+}
+
+func TestCompare(t *testing.T) {
+	oldReport := &report.Report{ScanResults: []report.ScanResult{
+		result("1", "10.0.0.1", statusVerified),   // both, still verified
+		result("2", "10.0.0.1", statusVerified),   // both, now fails
+		result("3", "10.0.0.1", statusVerified),   // both, now times out
+		result("4", "10.0.0.1", statusVerified),   // old only
+		result("5", "10.0.0.1", statusUnverified), // old only, not verified
+		result("6", "10.0.0.1", statusUnverified), // both, verified now
+	}}
+	newReport := &report.Report{ScanResults: []report.ScanResult{
+		result("1", "10.0.0.1", statusVerified),
+		result("2", "10.0.0.1", statusUnverified),
+		result("3", "10.0.0.1", statusTimeout),
+		result("4", "10.0.0.2", statusVerified), // new only, another host
+		result("6", "10.0.0.1", statusVerified),
+		result("7", "10.0.0.1", statusUnverified), // new only, not verified
+	}}
+
+	tests := []struct {
+		pluginID, host, class string
+	}{
+		{"1", "10.0.0.1", StillVulnerable},
+		{"2", "10.0.0.1", Fixed},
+		{"3", "10.0.0.1", NewlyUnreachable},
+		{"4", "10.0.0.1", NotRechecked},
+		{"4", "10.0.0.2", NewlyVerified},
+		{"6", "10.0.0.1", NewlyVerified},
+	}
+
+	d := Compare("old", oldReport, "new", newReport)
+	if len(d.Changes) != len(tests) {
+		t.Fatalf("got %d changes, want %d: %+v", len(d.Changes), len(tests), d.Changes)
+	}
+
+	classes := make(map[key]Change)
+	for _, change := range d.Changes {
+		classes[key{change.PluginID, change.Host, change.Port}] = change
+	}
+	for _, test := range tests {
+		change, ok := classes[key{test.pluginID, test.host, "443"}]
+		if !ok {
+			t.Errorf("plugin %s on %s is not reported", test.pluginID, test.host)
+			continue
+		}
+		if change.Class != test.class {
+			t.Errorf("plugin %s on %s is %s, want %s", test.pluginID, test.host, change.Class, test.class)
+		}
+	}
+
+	if got := classes[key{"4", "10.0.0.1", "443"}]; got.OldStatus != statusVerified || got.NewStatus != "" {
+		t.Errorf("not rechecked change has statuses %q and %q", got.OldStatus, got.NewStatus)
+	}
+	if d.Changes[len(d.Changes)-1].Class != NotRechecked {
+		t.Errorf("not rechecked changes are not reported last")
+	}
+}
@@ -0,0 +1,144 @@
+package diff
+
+import (
+	"fmt"
+	"html"
+	"strings"
+	"time"
+)
+
+// remediationClasses are the classes of previously verified issues, in the
+// order they are reported
+var remediationClasses = []string{StillVulnerable, Fixed, NewlyUnreachable, NotRechecked}
+
+// RemediationMarkdown renders a remediation validation report: each retested
+// issue with the original evidence and the retest evidence one after another
+func (d *Diff) RemediationMarkdown() string {
+	var sb strings.Builder
+
+	sb.WriteString("# NMB Remediation Validation Report\n\n")
+	sb.WriteString(fmt.Sprintf("**Date:** %s\n\n", d.Date.Format(time.RFC1123)))
+	sb.WriteString(fmt.Sprintf("**Original run:** %s\n\n", d.Old))
+
+	sb.WriteString("## Summary\n")
+	sb.WriteString(fmt.Sprintf("- Remediated: %d\n", d.Count(Fixed)))
+	sb.WriteString(fmt.Sprintf("- Still Vulnerable: %d\n", d.Count(StillVulnerable)))
+	sb.WriteString(fmt.Sprintf("- Could Not Be Retested: %d\n", d.Count(NewlyUnreachable)))
+	sb.WriteString(fmt.Sprintf("- Not Retested: %d\n", d.Count(NotRechecked)))
+
+	for _, class := range remediationClasses {
+		sb.WriteString(fmt.Sprintf("\n## %s\n", remediationTitle(class)))
+		if d.Count(class) == 0 {
+			sb.WriteString("None\n")
+			continue
+		}
+
+		for _, change := range d.Changes {
+			if change.Class != class {
+				continue
+			}
+			sb.WriteString(fmt.Sprintf("- **Plugin ID:** %s\n", change.PluginID))
+			sb.WriteString(fmt.Sprintf("  - **Host:** %s\n", change.Host))
+			sb.WriteString(fmt.Sprintf("  - **Port:** %s\n", change.Port))
+			sb.WriteString(fmt.Sprintf("  - **Name:** %s\n", change.Name))
+			sb.WriteString(fmt.Sprintf("  - **Before:** %s, `%s`\n", orNone(change.OldStatus), change.OldCommand))
+			sb.WriteString(fmt.Sprintf("```\n%s\n```\n", change.OldOutput))
+			sb.WriteString(fmt.Sprintf("  - **After:** %s, `%s`\n", orNone(change.NewStatus), change.NewCommand))
+			sb.WriteString(fmt.Sprintf("```\n%s\n```\n", change.NewOutput))
+			sb.WriteString("\n")
+		}
+	}
+
+	return sb.String()
+}
+
+// RemediationHTML renders the remediation validation report with the before
+// and after evidence side by side
+func (d *Diff) RemediationHTML() string {
+	var sb strings.Builder
+
+	sb.WriteString("<html><head><title>NMB Remediation Validation Report</title>")
+	sb.WriteString(`<link href="https://cdnjs.cloudflare.com/ajax/libs/tailwindcss/2.2.19/tailwind.min.css" rel="stylesheet">`)
+	sb.WriteString(`<link href="https://cdnjs.cloudflare.com/ajax/libs/highlight.js/11.3.1/styles/github-dark.min.css" rel="stylesheet">`)
+	sb.WriteString(`<script src="https://cdnjs.cloudflare.com/ajax/libs/highlight.js/11.3.1/highlight.min.js"></script>`)
+	sb.WriteString(`<script>hljs.highlightAll();</script>`)
+	sb.WriteString(`<style>
+		body { background-color: #1a202c; color: #cbd5e0; }
+		.card { background-color: #2d3748; border-color: #4a5568; }
+		.status-verified { color: #e53e3e; } /* Red, still vulnerable */
+		.status-fixed { color: #38a169; } /* Green, remediated */
+		pre { white-space: pre-wrap; word-break: break-all; }
+	</style>`)
+	sb.WriteString("</head><body>")
+	sb.WriteString("<div class='container mx-auto mt-5'>")
+	sb.WriteString("<h1 class='text-4xl font-bold mb-4'>NMB Remediation Validation Report</h1>")
+	sb.WriteString(fmt.Sprintf("<p><strong>Date:</strong> %s</p>", d.Date.Format(time.RFC1123)))
+	sb.WriteString(fmt.Sprintf("<p class='mb-4'><strong>Original run:</strong> %s</p>", html.EscapeString(d.Old)))
+
+	sb.WriteString("<h2 class='text-2xl font-semibold mt-4'>Summary</h2>")
+	sb.WriteString("<ul class='list-disc list-inside'>")
+	sb.WriteString(fmt.Sprintf("<li>Remediated: %d</li>", d.Count(Fixed)))
+	sb.WriteString(fmt.Sprintf("<li>Still Vulnerable: %d</li>", d.Count(StillVulnerable)))
+	sb.WriteString(fmt.Sprintf("<li>Could Not Be Retested: %d</li>", d.Count(NewlyUnreachable)))
+	sb.WriteString(fmt.Sprintf("<li>Not Retested: %d</li>", d.Count(NotRechecked)))
+	sb.WriteString("</ul>")
+
+	for _, class := range remediationClasses {
+		sb.WriteString(fmt.Sprintf("<h2 class='text-2xl font-semibold mt-4'>%s</h2>", remediationTitle(class)))
+		if d.Count(class) == 0 {
+			sb.WriteString("<p class='text-gray-500'>None</p>")
+			continue
+		}
+
+		for _, change := range d.Changes {
+			if change.Class != class {
+				continue
+			}
+
+			statusClass := ""
+			switch class {
+			case StillVulnerable:
+				statusClass = "status-verified"
+			case Fixed:
+				statusClass = "status-fixed"
+			}
+
+			sb.WriteString("<div class='card border border-gray-600 rounded-lg p-4 mb-4'>")
+			sb.WriteString(fmt.Sprintf("<p><strong>Plugin ID:</strong> %s</p>", html.EscapeString(change.PluginID)))
+			sb.WriteString(fmt.Sprintf("<p><strong>Host:</strong> %s:%s</p>", html.EscapeString(change.Host), html.EscapeString(change.Port)))
+			sb.WriteString(fmt.Sprintf("<p><strong>Name:</strong> %s</p>", html.EscapeString(change.Name)))
+			sb.WriteString("<div class='grid grid-cols-2 gap-4 mt-2'>")
+			writeEvidence(&sb, "Before", orNone(change.OldStatus), change.OldCommand, change.OldOutput, "")
+			writeEvidence(&sb, "After", orNone(change.NewStatus), change.NewCommand, change.NewOutput, statusClass)
+			sb.WriteString("</div>")
+			sb.WriteString("</div>")
+		}
+	}
+
+	sb.WriteString("</div>") // Close container
+	sb.WriteString("</body></html>")
+
+	return sb.String()
+}
+
+func writeEvidence(sb *strings.Builder, title, status, command, output, statusClass string) {
+	sb.WriteString("<div>")
+	sb.WriteString(fmt.Sprintf("<h3 class='text-xl font-semibold'>%s</h3>", title))
+	sb.WriteString(fmt.Sprintf("<p class='%s'><strong>Status:</strong> %s</p>", statusClass, html.EscapeString(status)))
+	sb.WriteString(fmt.Sprintf("<p><strong>Command:</strong> <code>%s</code></p>", html.EscapeString(command)))
+	sb.WriteString(fmt.Sprintf("<pre><code class='language-bash'>%s</code></pre>", html.EscapeString(output)))
+	sb.WriteString("</div>")
+}
+
+func remediationTitle(class string) string {
+	switch class {
+	case Fixed:
+		return "Remediated"
+	case NewlyUnreachable:
+		return "Could Not Be Retested"
+	case NotRechecked:
+		return "Not Retested"
+	default:
+		return class
+	}
+}
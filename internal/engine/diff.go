@@ -0,0 +1,64 @@
+package engine
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"NMB/internal/diff"
+	"NMB/internal/logging"
+	"NMB/internal/report"
+)
+
+// HandleDiffCommand runs "nmb diff", comparing the saved reports of two runs
+func HandleDiffCommand(cmdArgs []string) {
+	flags := flag.NewFlagSet("diff", flag.ExitOnError)
+	format := flags.String("format", "md", "Output format (md, html, json)")
+	output := flags.String("o", "", "Write the diff to a file instead of stdout")
+	flags.Usage = func() {
+		fmt.Println("Usage: nmb diff [-format md|html|json] [-o file] <old project or report> <new project or report>")
+	}
+	flags.Parse(cmdArgs)
+
+	if flags.NArg() != 2 {
+		flags.Usage()
+		os.Exit(2)
+	}
+	oldPath, newPath := flags.Arg(0), flags.Arg(1)
+
+	oldReport, err := report.Load(oldPath)
+	if err != nil {
+		logging.ErrorLogger.Fatalf("Failed to load old run: %v", err)
+	}
+	newReport, err := report.Load(newPath)
+	if err != nil {
+		logging.ErrorLogger.Fatalf("Failed to load new run: %v", err)
+	}
+
+	d := diff.Compare(oldPath, oldReport, newPath, newReport)
+
+	var rendered string
+	switch *format {
+	case "md", "markdown":
+		rendered = d.Markdown()
+	case "html":
+		rendered = d.HTML()
+	case "json":
+		rendered, err = d.JSON()
+		if err != nil {
+			logging.ErrorLogger.Fatalf("Failed to render diff: %v", err)
+		}
+	default:
+		logging.ErrorLogger.Fatalf("Invalid diff format: %s", *format)
+	}
+
+	if *output == "" {
+		fmt.Println(rendered)
+		return
+	}
+
+	if err := os.WriteFile(*output, []byte(rendered), 0644); err != nil {
+		logging.ErrorLogger.Fatalf("Failed to write diff: %v", err)
+	}
+	logging.InfoLogger.Printf("Diff written to %s", *output)
+}
@@ -1,6 +1,7 @@
 package engine
 
 import (
+	"errors"
 	"fmt"
 	"log"
 	"os"
@@ -8,7 +9,10 @@ import (
 	"strings"
 
 	"NMB/internal/args"
+	"NMB/internal/cassette"
 	"NMB/internal/config"
+	"NMB/internal/diff"
+	"NMB/internal/inventory"
 	"NMB/internal/logging"
 	"NMB/internal/nessus"
 	NessusController "NMB/internal/nessus-controller"
@@ -16,6 +20,7 @@ import (
 	"NMB/internal/render"
 	"NMB/internal/report"
 	"NMB/internal/scanner"
+	"NMB/internal/settings"
 	"NMB/internal/workerpool"
 
 	"github.com/fatih/color"
@@ -34,20 +39,97 @@ func init() {
 	}
 }
 
-func HandleNessusController(parsedArgs *args.Args) {
-	validateNessusArgs(parsedArgs)
-
-	controller, err := NessusController.New(
-		parsedArgs.RemoteHost,
-		parsedArgs.RemoteUser,
-		parsedArgs.RemotePass,
-		parsedArgs.ProjectName,
-		parsedArgs.TargetsFile,
-		getExcludeFiles(parsedArgs),
-		parsedArgs.Discovery,
-	)
+// HandleNessusController runs the Nessus controller in the mode of the
+// arguments. Errors are returned rather than exiting, as the API server runs
+// it too.
+func HandleNessusController(parsedArgs *args.Args) error {
+	lg := logging.ForJob(parsedArgs.JobID)
+	applySettings(parsedArgs, lg)
+	credentials, err := nessusCredentials(parsedArgs)
+	if err != nil {
+		return err
+	}
+	if err := validateNessusArgs(parsedArgs, credentials); err != nil {
+		return err
+	}
+
+	var schedule *NessusController.Schedule
+	if parsedArgs.ScheduleStart != "" {
+		var err error
+		schedule, err = NessusController.ParseSchedule(parsedArgs.ScheduleStart, parsedArgs.Timezone, parsedArgs.RRules)
+		if err != nil {
+			return fmt.Errorf("invalid scan schedule: %v", err)
+		}
+	}
+
+	var window *NessusController.Window
+	if parsedArgs.ScanWindow != "" {
+		var err error
+		window, err = NessusController.ParseWindow(parsedArgs.ScanWindow, parsedArgs.Timezone)
+		if err != nil {
+			return fmt.Errorf("invalid scan window: %v", err)
+		}
+	}
+
+	opts := NessusController.Options{
+		URL:          parsedArgs.NessusURL,
+		Host:         parsedArgs.RemoteHost,
+		Username:     credentials.Username,
+		Password:     credentials.Password,
+		AccessKey:    credentials.AccessKey,
+		SecretKey:    credentials.SecretKey,
+		KeyFile:      parsedArgs.RemoteKey,
+		ProjectName:  parsedArgs.ProjectName,
+		TargetsFile:  parsedArgs.TargetsFile,
+		ExcludeFiles: getExcludeFiles(parsedArgs),
+		Discovery:    parsedArgs.Discovery,
+		TopPorts:     parsedArgs.TopPorts,
+		PolicyFile:   parsedArgs.PolicyPath,
+		Policy:       parsedArgs.PolicyName,
+		Scanner:      parsedArgs.ScannerName,
+		Folder:       parsedArgs.FolderName,
+		Schedule:     schedule,
+		Window:       window,
+		Export: NessusController.ExportOptions{
+			Formats:    parsedArgs.ExportFormats,
+			Columns:    parsedArgs.ExportColumns,
+			Severities: parsedArgs.ExportSeverities,
+			PluginIDs:  parsedArgs.ExportPluginIDs,
+			Hosts:      parsedArgs.ExportHosts,
+			Template:   parsedArgs.ExportTemplate,
+			DBPassword: parsedArgs.ExportDBPassword,
+		},
+		OutputDir: parsedArgs.OutputDir,
+		Log:       lg,
+	}
+
+	// -mode full keeps everything in the project folder and skips the
+	// discovery scan once the scan has been deployed
+	var state *pipelineState
+	if parsedArgs.NessusMode == "full" {
+		var err error
+		state, err = loadPipelineState(parsedArgs.ProjectFolder, parsedArgs.ProjectName)
+		if err != nil {
+			return fmt.Errorf("failed to load pipeline state: %v", err)
+		}
+		if state.done(stageDeploy) {
+			opts.Discovery = false
+		} else if parsedArgs.TargetsFile == "" {
+			return fmt.Errorf("targets file (-targets) is required until the full pipeline has deployed the scan")
+		}
+		if opts.OutputDir == "" {
+			opts.OutputDir = parsedArgs.ProjectFolder
+		}
+	}
+	// The batch plan, the host inventory and the window pause marker are
+	// read back from the project folder by later runs and the reports
+	if opts.OutputDir == "" && (parsedArgs.NessusMode == "batch" || opts.Discovery || window != nil) {
+		opts.OutputDir = parsedArgs.ProjectFolder
+	}
+
+	controller, err := NessusController.NewWithOptions(opts)
 	if err != nil {
-		logging.ErrorLogger.Fatalf("Failed to initialize Nessus controller: %v", err)
+		return fmt.Errorf("failed to initialize Nessus controller: %v", err)
 	}
 
 	var execErr error
@@ -66,52 +148,154 @@ func HandleNessusController(parsedArgs *args.Args) {
 		execErr = controller.Monitor()
 	case "export":
 		execErr = controller.Export()
+	case "full":
+		execErr = runPipeline(controller, parsedArgs, credentials, state, lg)
+	case "batch":
+		execErr = runBatch(controller, parsedArgs, credentials, lg)
+	case "policies":
+		execErr = printNessusList("Nessus Policies", controller.ListPolicies)
+	case "scanners":
+		execErr = printNessusList("Nessus Scanners", controller.ListScanners)
 	default:
-		logging.ErrorLogger.Fatalf("Invalid Nessus mode: %s", parsedArgs.NessusMode)
+		return fmt.Errorf("invalid Nessus mode: %s", parsedArgs.NessusMode)
 	}
 
 	if execErr != nil {
-		logging.ErrorLogger.Fatalf("Failed to execute Nessus %s mode: %v", parsedArgs.NessusMode, execErr)
+		return fmt.Errorf("failed to execute Nessus %s mode: %v", parsedArgs.NessusMode, execErr)
 	}
 
-	logging.SuccessLogger.Printf("Successfully completed Nessus %s operation", parsedArgs.NessusMode)
+	lg.Success.Printf("Successfully completed Nessus %s operation", parsedArgs.NessusMode)
+	return nil
 }
 
-func RunNMB(parsedArgs *args.Args) {
-	if parsedArgs.NessusFilePath == "" || parsedArgs.NessusFilePath == "path/to/nessus.csv" {
-		logging.ErrorLogger.Fatal("Nessus file path (-nessus) is required for NMB operation")
+// RunNMB verifies the findings of the Nessus files or the report to retest
+// and writes the reports to the project folder. Errors are returned rather
+// than exiting, as the API server runs it too.
+func RunNMB(parsedArgs *args.Args) error {
+	lg := logging.ForJob(parsedArgs.JobID)
+	applySettings(parsedArgs, lg)
+	retest := parsedArgs.RetestFile != ""
+	if !retest && (parsedArgs.NessusFilePath == "" || parsedArgs.NessusFilePath == "path/to/nessus.csv") {
+		return fmt.Errorf("Nessus file path (-nessus) is required for NMB operation")
 	}
 
 	var cfg config.Config
 	if parsedArgs.ConfigFilePath != "" {
-		if _, err := os.Stat(parsedArgs.ConfigFilePath); os.IsNotExist(err) {
-			logging.ErrorLogger.Fatalf("Config file %s not found", parsedArgs.ConfigFilePath)
+		var err error
+		if cfg, err = config.ReadConfigFile(parsedArgs.ConfigFilePath); err != nil {
+			return err
 		}
-		cfg = config.LoadConfigFromFile(parsedArgs.ConfigFilePath)
-		logging.InfoLogger.Println("Using provided config file")
+		lg.Info.Println("Using provided config file")
 	} else {
 		cfg = config.LoadEmbeddedConfig()
-		logging.InfoLogger.Println("Using embedded config")
+		lg.Info.Println("Using embedded config")
 	}
 
 	if err := os.MkdirAll(parsedArgs.ProjectFolder, 0755); err != nil {
-		logging.ErrorLogger.Fatalf("Failed to create project folder: %v", err)
+		return fmt.Errorf("failed to create project folder: %v", err)
 	}
 
-	findings, pluginData, err := nessus.ParseCSV(parsedArgs.NessusFilePath)
-	if err != nil {
-		logging.ErrorLogger.Fatalf("Failed to parse Nessus CSV: %v", err)
+	var (
+		sources     []string
+		allFindings []nessus.Finding
+		previous    *report.Report
+		err         error
+	)
+	if retest {
+		previous, err = report.Load(parsedArgs.RetestFile)
+		if err != nil {
+			return fmt.Errorf("failed to load report to retest: %v", err)
+		}
+		sources = []string{parsedArgs.RetestFile}
+		allFindings = retestFindings(previous, parsedArgs.RetestFile, lg)
+		lg.Info.Printf("Retesting %d previously verified findings", len(allFindings))
+	} else {
+		sources, err = nessus.ResolveSources(parsedArgs.NessusFilePath)
+		if err != nil {
+			return fmt.Errorf("failed to resolve Nessus files: %v", err)
+		}
+		if len(sources) > 1 {
+			lg.Info.Printf("Merging findings from %d Nessus files", len(sources))
+		}
+
+		allFindings, err = nessus.ParseAll(sources)
+		if err != nil {
+			return fmt.Errorf("failed to parse Nessus files: %v", err)
+		}
+	}
+
+	if !parsedArgs.Filter.IsEmpty() {
+		if len(parsedArgs.Filter.Risks) > 0 {
+			unknown := 0
+			for _, finding := range allFindings {
+				if finding.Risk == "" {
+					unknown++
+				}
+			}
+			if unknown > 0 {
+				lg.Warning.Printf("%d findings have no risk and are kept by the risk filter", unknown)
+			}
+		}
+		filtered, err := parsedArgs.Filter.Apply(allFindings, cfg.Plugins)
+		if err != nil {
+			return fmt.Errorf("invalid finding filter: %v", err)
+		}
+		lg.Info.Printf("Filter kept %d of %d findings", len(filtered), len(allFindings))
+		allFindings = filtered
+	}
+
+	findings, pluginData := nessus.Dedupe(allFindings)
+	if retest {
+		// Every previously verified host is checked again, not just one per plugin
+		findings = allFindings
 	}
 
 	report := &report.Report{
 		ProjectFolder: parsedArgs.ProjectFolder,
+		Sources:       sources,
 	}
 	report.SupportedPlugins, report.MissingPlugins = nessus.GetSupportedAndMissingPlugins(findings, cfg.Plugins)
 
+	// A discovery scan run into this project folder provides the assets
+	if inv, err := inventory.Load(parsedArgs.ProjectFolder); err == nil {
+		report.Assets = inv.Hosts
+		lg.Info.Printf("Loaded %d assets from the host inventory", len(inv.Hosts))
+	} else if !errors.Is(err, os.ErrNotExist) {
+		lg.Error.Printf("Failed to load host inventory: %v", err)
+	}
+
 	printSupportedPlugins(report.SupportedPlugins)
 
+	if parsedArgs.RecordFile != "" && parsedArgs.ReplayFile != "" {
+		return fmt.Errorf("-record and -replay cannot be used together")
+	}
+
+	var tape *cassette.Cassette
+	if parsedArgs.ReplayFile != "" {
+		var err error
+		tape, err = cassette.Load(parsedArgs.ReplayFile)
+		if err != nil {
+			return fmt.Errorf("failed to load cassette: %v", err)
+		}
+		lg.Info.Printf("Replaying %d recorded commands from %s", tape.Len(), parsedArgs.ReplayFile)
+	} else if parsedArgs.RecordFile != "" {
+		tape = cassette.New(parsedArgs.RecordFile)
+		lg.Info.Printf("Recording commands to %s", parsedArgs.RecordFile)
+		if err := tape.Save(); err != nil {
+			return fmt.Errorf("failed to save cassette: %v", err)
+		}
+		defer tape.Close()
+	}
+
+	// Which host verifies a plugin first depends on worker timing, so
+	// recordings are made and replayed in order to check the same hosts
+	if tape != nil && parsedArgs.NumWorkers != 1 {
+		lg.Info.Printf("Using 1 worker instead of %d so the cassette replays the same commands", parsedArgs.NumWorkers)
+		parsedArgs.NumWorkers = 1
+	}
+
 	var remoteExec *remote.RemoteExecutor
-	if parsedArgs.RemoteHost != "" {
+	if parsedArgs.RemoteHost != "" && parsedArgs.ReplayFile == "" {
 		var err error
 		remoteExec, err = remote.NewRemoteExecutor(
 			parsedArgs.RemoteHost,
@@ -120,10 +304,10 @@ func RunNMB(parsedArgs *args.Args) {
 			parsedArgs.RemoteKey,
 		)
 		if err != nil {
-			logging.ErrorLogger.Fatalf("Failed to initialize remote executor: %v", err)
+			return fmt.Errorf("failed to initialize remote executor: %v", err)
 		}
 		defer remoteExec.Close()
-		logging.InfoLogger.Printf("Connected to remote host: %s", parsedArgs.RemoteHost)
+		lg.Info.Printf("Connected to remote host: %s", parsedArgs.RemoteHost)
 	}
 
 	scn := scanner.Scanner{
@@ -133,51 +317,204 @@ func RunNMB(parsedArgs *args.Args) {
 		ProjectFolder: parsedArgs.ProjectFolder,
 		Report:        report,
 		RemoteExec:    remoteExec,
+		Cassette:      tape,
+		PerHost:       retest,
+		Log:           lg,
 	}
 
 	workerpool.StartWorkerPool(parsedArgs.NumWorkers, findings, scn.RunScans)
 
-	generateAndSaveReport(report, parsedArgs.ProjectFolder)
+	if parsedArgs.RecordFile != "" {
+		lg.Success.Printf("Recorded %d commands to %s", tape.Len(), parsedArgs.RecordFile)
+	}
+
+	if err := generateAndSaveReport(report, parsedArgs.ProjectFolder, lg); err != nil {
+		return err
+	}
+
+	if retest {
+		return saveRemediationReport(diff.Compare(parsedArgs.RetestFile, previous, parsedArgs.ProjectFolder, report), parsedArgs.ProjectFolder, lg)
+	}
+	return nil
 }
 
-func generateAndSaveReport(report *report.Report, projectFolder string) {
+// retestFindings rebuilds the job list from the verified results of an
+// earlier run, one finding per plugin, host and port. Reports from before
+// results carried a risk get it from the run's Nessus exports, if they are
+// still there.
+func retestFindings(previous *report.Report, source string, lg *logging.Loggers) []nessus.Finding {
+	var findings []nessus.Finding
+	seen := make(map[string]struct{})
+	var risks map[string]string
+
+	for _, result := range previous.ScanResults {
+		if result.Status != "Verified" {
+			continue
+		}
+
+		key := fmt.Sprintf("%s|%s:%s", result.PluginID, result.Host, result.Port)
+		if _, exists := seen[key]; exists {
+			continue
+		}
+		seen[key] = struct{}{}
+
+		if result.Risk == "" && risks == nil {
+			risks = sourceRisks(previous.Sources, lg)
+		}
+		risk := result.Risk
+		if risk == "" {
+			risk = risks[result.PluginID]
+		}
+
+		findings = append(findings, nessus.Finding{
+			PluginID: result.PluginID,
+			Host:     result.Host,
+			Port:     result.Port,
+			Name:     result.Name,
+			Risk:     risk,
+			Source:   source,
+		})
+	}
+
+	return findings
+}
+
+// sourceRisks maps the plugin IDs in a run's Nessus exports to their risk
+func sourceRisks(sources []string, lg *logging.Loggers) map[string]string {
+	risks := make(map[string]string)
+	var available []string
+	for _, source := range sources {
+		if _, err := os.Stat(source); err == nil {
+			available = append(available, source)
+		}
+	}
+	if len(available) == 0 {
+		return risks
+	}
+
+	findings, err := nessus.ParseAll(available)
+	if err != nil {
+		lg.Warning.Printf("Failed to read risks from the original Nessus files: %v", err)
+		return risks
+	}
+	for _, finding := range findings {
+		risks[finding.PluginID] = finding.Risk
+	}
+	return risks
+}
+
+func saveRemediationReport(d *diff.Diff, projectFolder string, lg *logging.Loggers) error {
+	markdownPath := filepath.Join(projectFolder, "NMB_remediation_report.md")
+	if err := os.WriteFile(markdownPath, []byte(d.RemediationMarkdown()), 0644); err != nil {
+		return fmt.Errorf("failed to write remediation report: %v", err)
+	}
+
+	htmlPath := filepath.Join(projectFolder, "NMB_remediation_report.html")
+	if err := os.WriteFile(htmlPath, []byte(d.RemediationHTML()), 0644); err != nil {
+		return fmt.Errorf("failed to write remediation report: %v", err)
+	}
+
+	lg.Info.Printf("Remediation report generated at %s (%d remediated, %d still vulnerable, %d could not be retested, %d not retested)",
+		htmlPath, d.Count(diff.Fixed), d.Count(diff.StillVulnerable), d.Count(diff.NewlyUnreachable), d.Count(diff.NotRechecked))
+	return nil
+}
+
+func generateAndSaveReport(report *report.Report, projectFolder string, lg *logging.Loggers) error {
 	if err := report.Generate(); err != nil {
-		logging.ErrorLogger.Fatalf("Failed to generate report: %v", err)
+		return fmt.Errorf("failed to generate report: %v", err)
+	}
+
+	if err := report.Save(); err != nil {
+		return fmt.Errorf("failed to save report: %v", err)
 	}
 
 	renderedContent, err := render.Generate(report)
 	if err != nil {
-		logging.ErrorLogger.Fatalf("Failed to render report: %v", err)
+		return fmt.Errorf("failed to render report: %v", err)
 	}
 
 	reportFilePath := filepath.Join(projectFolder, "NMB_scan_report.html")
 	if err := os.WriteFile(reportFilePath, []byte(renderedContent), 0644); err != nil {
-		logging.ErrorLogger.Fatalf("Failed to write rendered report: %v", err)
+		return fmt.Errorf("failed to write rendered report: %v", err)
+	}
+
+	lg.Info.Printf("Report generated at %s", reportFilePath)
+	return nil
+}
+
+// applySettings fills in the project folder, worker count and SSH key from
+// the saved settings when they were left out, as they may be by API
+// requests. The CLI already uses the settings as flag defaults.
+func applySettings(parsedArgs *args.Args, lg *logging.Loggers) {
+	saved, err := settings.Load()
+	if err != nil {
+		lg.Warning.Printf("Ignoring saved settings: %v", err)
+	}
+
+	if parsedArgs.ProjectFolder == "" {
+		parsedArgs.ProjectFolder = saved.DefaultProjectFolder
+	}
+	if parsedArgs.NumWorkers <= 0 {
+		parsedArgs.NumWorkers = saved.MaxWorkers
 	}
+	if parsedArgs.RemoteKey == "" && parsedArgs.RemoteHost != "" {
+		parsedArgs.RemoteKey = saved.SSHKeyFile
+	}
+}
 
-	logging.InfoLogger.Printf("Report generated at %s", reportFilePath)
+// nessusCredentials loads the Nessus credentials from the credentials file
+// and environment, with -user, -password and API keys set in the arguments
+// taking precedence
+func nessusCredentials(parsedArgs *args.Args) (*NessusController.Credentials, error) {
+	credentials, err := NessusController.LoadCredentials(parsedArgs.CredentialsFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load Nessus credentials: %v", err)
+	}
+	// -user and -password only fill in what the file and environment leave out
+	if credentials.Username == "" {
+		credentials.Username = parsedArgs.RemoteUser
+	}
+	if credentials.Password == "" {
+		credentials.Password = parsedArgs.RemotePass
+	}
+	if parsedArgs.AccessKey != "" && parsedArgs.SecretKey != "" {
+		credentials.AccessKey = parsedArgs.AccessKey
+		credentials.SecretKey = parsedArgs.SecretKey
+	}
+	return credentials, nil
 }
 
-func validateNessusArgs(args *args.Args) {
-	if args.RemoteHost == "" {
-		logging.ErrorLogger.Fatal("Remote host (-remote) is required for Nessus controller operations")
+func validateNessusArgs(args *args.Args, credentials *NessusController.Credentials) error {
+	if args.RemoteHost == "" && args.NessusURL == "" {
+		return fmt.Errorf("remote host (-remote) or Nessus URL (-nessus-url) is required for Nessus controller operations")
 	}
-	if args.RemoteUser == "" {
-		logging.ErrorLogger.Fatal("Remote user (-user) is required for Nessus controller operations")
+	if err := credentials.Validate(); err != nil {
+		return err
 	}
-	if args.RemotePass == "" {
-		logging.ErrorLogger.Fatal("Remote password (-password) is required for Nessus controller operations")
+	if args.RemoteHost != "" && (credentials.Username == "" || (credentials.Password == "" && args.RemoteKey == "")) {
+		return fmt.Errorf("SSH to the Nessus host needs a user (-user or NESSUS_USERNAME) and a password or -key")
 	}
-	if args.ProjectName == "" {
-		logging.ErrorLogger.Fatal("Project name (-name) is required for Nessus controller operations")
+	switch args.NessusMode {
+	case "policies", "scanners":
+		// Listing does not need a project
+	default:
+		if args.ProjectName == "" {
+			return fmt.Errorf("project name (-name) is required for Nessus controller operations")
+		}
 	}
 
 	switch args.NessusMode {
-	case "deploy", "create":
+	case "deploy", "create", "batch":
 		if args.TargetsFile == "" {
-			logging.ErrorLogger.Fatal("Targets file (-targets) is required for deploy/create operations")
+			return fmt.Errorf("targets file (-targets) is required for deploy/create/batch operations")
+		}
+	}
+	if args.NessusMode == "batch" {
+		if err := batchOptions(args).Validate(); err != nil {
+			return fmt.Errorf("invalid batch options: %v (use -batch-size or -batch-subnet)", err)
 		}
 	}
+	return nil
 }
 
 func getExcludeFiles(args *args.Args) []string {
@@ -208,3 +545,29 @@ func printSupportedPlugins(supportedPlugins []string) {
 
 	fmt.Println(divider)
 }
+
+func printNessusList(title string, list func() ([]NessusController.ListItem, error)) error {
+	items, err := list()
+	if err != nil {
+		return err
+	}
+
+	header := color.New(color.FgHiGreen, color.Bold).SprintfFunc()
+	itemID := color.New(color.FgHiBlue).SprintfFunc()
+	divider := strings.Repeat("=", 50)
+
+	fmt.Println(divider)
+	fmt.Println(header(title))
+	fmt.Println(divider)
+
+	for _, item := range items {
+		if item.Detail != "" {
+			fmt.Printf("%s %s (%s)\n", itemID("[%s]", item.ID), item.Name, item.Detail)
+		} else {
+			fmt.Printf("%s %s\n", itemID("[%s]", item.ID), item.Name)
+		}
+	}
+
+	fmt.Println(divider)
+	return nil
+}
@@ -0,0 +1,178 @@
+package engine
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"NMB/internal/args"
+	"NMB/internal/logging"
+	NessusController "NMB/internal/nessus-controller"
+)
+
+// pipelineStateFile records the finished stages of -mode full in the project
+// folder, so an interrupted run picks up where it stopped
+const pipelineStateFile = "NMB_pipeline.json"
+
+// Stages of -mode full, in order
+const (
+	stageDeploy  = "deploy"
+	stageMonitor = "monitor"
+	stageExport  = "export"
+	stageVerify  = "verify"
+)
+
+var pipelineStages = []string{stageDeploy, stageMonitor, stageExport, stageVerify}
+
+type pipelineState struct {
+	ScanName   string    `json:"scanName"`
+	Completed  []string  `json:"completed"`
+	ExportFile string    `json:"exportFile,omitempty"`
+	UpdatedAt  time.Time `json:"updatedAt"`
+
+	path string
+}
+
+// loadPipelineState reads the project folder's pipeline state, or starts a
+// new one. A project folder belongs to a single scan.
+func loadPipelineState(projectFolder, scanName string) (*pipelineState, error) {
+	state := &pipelineState{
+		ScanName: scanName,
+		path:     filepath.Join(projectFolder, pipelineStateFile),
+	}
+
+	data, err := os.ReadFile(state.path)
+	if os.IsNotExist(err) {
+		return state, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read pipeline state: %v", err)
+	}
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, fmt.Errorf("failed to parse pipeline state %s: %v", state.path, err)
+	}
+	if state.ScanName != scanName {
+		return nil, fmt.Errorf("project folder %s belongs to scan %q, not %q", projectFolder, state.ScanName, scanName)
+	}
+	return state, nil
+}
+
+func (s *pipelineState) done(stage string) bool {
+	for _, completed := range s.Completed {
+		if completed == stage {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *pipelineState) complete(stage string) error {
+	s.Completed = append(s.Completed, stage)
+	s.UpdatedAt = time.Now()
+
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return fmt.Errorf("failed to create project folder: %v", err)
+	}
+	if err := os.WriteFile(s.path, data, 0644); err != nil {
+		return fmt.Errorf("failed to save pipeline state: %v", err)
+	}
+	return nil
+}
+
+// runPipeline deploys the scan, waits for it, exports it into the project
+// folder and verifies the export with NMB, skipping the stages a previous
+// run finished
+func runPipeline(controller *NessusController.Nessus, parsedArgs *args.Args, credentials *NessusController.Credentials, state *pipelineState, lg *logging.Loggers) error {
+	if len(state.Completed) == len(pipelineStages) {
+		lg.Info.Printf("Pipeline for %s already finished, remove %s to run it again", state.ScanName, state.path)
+		return nil
+	}
+	if len(state.Completed) > 0 {
+		lg.Info.Printf("Resuming pipeline for %s after %s", state.ScanName, strings.Join(state.Completed, ", "))
+	}
+
+	for _, stage := range pipelineStages {
+		if state.done(stage) {
+			continue
+		}
+		lg.Info.Printf("Pipeline stage: %s", stage)
+
+		var err error
+		switch stage {
+		case stageDeploy:
+			err = controller.Start()
+		case stageMonitor:
+			err = controller.Wait()
+		case stageExport:
+			state.ExportFile, err = controller.ExportResults()
+			if err == nil && !isVerifiableExport(state.ExportFile) {
+				err = fmt.Errorf("exported %s cannot be verified, include csv or nessus in -export-formats", state.ExportFile)
+			}
+		case stageVerify:
+			err = verifyExport(parsedArgs, credentials, state.ExportFile)
+		}
+		if err != nil {
+			return fmt.Errorf("%s stage failed: %w", stage, err)
+		}
+
+		if err := state.complete(stage); err != nil {
+			return err
+		}
+	}
+
+	lg.Success.Printf("Pipeline finished, results are in %s", parsedArgs.ProjectFolder)
+	return nil
+}
+
+// runBatch splits the targets over several scans and verifies their merged
+// CSV export. The merged file is verified even when some scans failed.
+func runBatch(controller *NessusController.Nessus, parsedArgs *args.Args, credentials *NessusController.Credentials, lg *logging.Loggers) error {
+	merged, err := controller.RunBatch(batchOptions(parsedArgs))
+	if merged == "" {
+		return err
+	}
+	if err != nil {
+		lg.Error.Printf("Verifying partial results: %v", err)
+	}
+
+	if verifyErr := verifyExport(parsedArgs, credentials, merged); verifyErr != nil {
+		return verifyErr
+	}
+	return err
+}
+
+func batchOptions(parsedArgs *args.Args) NessusController.BatchOptions {
+	return NessusController.BatchOptions{
+		ChunkSize:   parsedArgs.BatchSize,
+		Subnet:      parsedArgs.BatchSubnet,
+		Concurrency: parsedArgs.BatchConcurrency,
+	}
+}
+
+// verifyExport runs NMB on the exported file, writing the report into the
+// project folder. Verification commands run over the same SSH connection
+// details as the controller.
+func verifyExport(parsedArgs *args.Args, credentials *NessusController.Credentials, exportFile string) error {
+	if _, err := os.Stat(exportFile); err != nil {
+		return fmt.Errorf("exported file is missing: %v", err)
+	}
+
+	verifyArgs := *parsedArgs
+	verifyArgs.NessusFilePath = exportFile
+	verifyArgs.RemoteUser = credentials.Username
+	verifyArgs.RemotePass = credentials.Password
+
+	return RunNMB(&verifyArgs)
+}
+
+func isVerifiableExport(path string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	return ext == ".csv" || ext == ".nessus"
+}
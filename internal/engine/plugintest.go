@@ -0,0 +1,93 @@
+package engine
+
+import (
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"strings"
+
+	"NMB/internal/config"
+	"NMB/internal/logging"
+	"NMB/internal/plugintest"
+
+	"github.com/fatih/color"
+)
+
+// HandlePluginCommand runs the "nmb plugin" subcommands
+func HandlePluginCommand(cmdArgs []string) {
+	if len(cmdArgs) == 0 || cmdArgs[0] != "test" {
+		fmt.Println("Usage: nmb plugin test [-c config.json] [plugin name ...]")
+		os.Exit(2)
+	}
+
+	flags := flag.NewFlagSet("plugin test", flag.ExitOnError)
+	configPath := flags.String("config", "", "Path to the configuration file (optional)")
+	flags.StringVar(configPath, "c", "", "Path to the configuration file (optional) (short)")
+	flags.Parse(cmdArgs[1:])
+
+	var cfg config.Config
+	if *configPath != "" {
+		if _, err := os.Stat(*configPath); os.IsNotExist(err) {
+			logging.ErrorLogger.Fatalf("Config file %s not found", *configPath)
+		}
+		cfg = config.LoadConfigFromFile(*configPath)
+	} else {
+		cfg = config.LoadEmbeddedConfig()
+	}
+
+	names := flags.Args()
+	if len(names) == 0 {
+		names = plugintest.PluginNames(cfg)
+	}
+
+	if !runPluginTests(cfg, plugintest.FixturesFS(*configPath), names) {
+		os.Exit(1)
+	}
+}
+
+func runPluginTests(cfg config.Config, fixtures fs.FS, names []string) bool {
+	header := color.New(color.FgHiGreen, color.Bold).SprintfFunc()
+	divider := strings.Repeat("=", 50)
+
+	var passed, failed, untested int
+	for _, name := range names {
+		results, err := plugintest.RunPlugin(cfg, fixtures, name)
+		if err != nil {
+			logging.ErrorLogger.Printf("%v", err)
+			failed++
+			continue
+		}
+		if len(results) == 0 {
+			untested++
+			continue
+		}
+
+		fmt.Println(header(name))
+		p, f := printPluginResults(results)
+		passed += p
+		failed += f
+	}
+
+	fmt.Println(divider)
+	fmt.Printf("%d passed, %d failed, %d plugins without fixtures\n", passed, failed, untested)
+	fmt.Println(divider)
+
+	return failed == 0
+}
+
+func printPluginResults(results []plugintest.Result) (passed, failed int) {
+	pass := color.New(color.FgHiGreen).SprintFunc()
+	fail := color.New(color.FgHiRed, color.Bold).SprintFunc()
+
+	for _, result := range results {
+		if result.Passed {
+			passed++
+			fmt.Printf("  %s %s\n", pass("PASS"), result.Fixture.Name)
+			continue
+		}
+		failed++
+		fmt.Printf("  %s %s (status: %s)\n", fail("FAIL"), result.Fixture.Name, result.Status)
+	}
+	return passed, failed
+}
@@ -0,0 +1,227 @@
+// Package inventory is the host inventory built by the discovery scan: which
+// hosts answered, how they were found and how quickly they replied. It is
+// saved in the project folder and used for scan targeting and the report's
+// asset section.
+package inventory
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// File names the inventory is saved under
+const (
+	JSONFileName = "NMB_host_inventory.json"
+	CSVFileName  = "NMB_host_inventory.csv"
+)
+
+// Host is a host that answered the discovery scan
+type Host struct {
+	IP        string  `json:"ip"`
+	Hostname  string  `json:"hostname,omitempty"`
+	Method    string  `json:"method"`
+	LatencyMS float64 `json:"latencyMs,omitempty"`
+	OpenPorts []int   `json:"openPorts,omitempty"`
+}
+
+// Inventory is the result of one discovery scan
+type Inventory struct {
+	Targets      string    `json:"targets"`
+	DiscoveredAt time.Time `json:"discoveredAt"`
+	Hosts        []Host    `json:"hosts"`
+}
+
+// nmapRun is the part of nmap's -oX output the inventory uses
+type nmapRun struct {
+	Hosts []struct {
+		Status struct {
+			State  string `xml:"state,attr"`
+			Reason string `xml:"reason,attr"`
+		} `xml:"status"`
+		Addresses []struct {
+			Addr string `xml:"addr,attr"`
+			Type string `xml:"addrtype,attr"`
+		} `xml:"address"`
+		Hostnames []struct {
+			Name string `xml:"name,attr"`
+		} `xml:"hostnames>hostname"`
+		Ports []struct {
+			PortID int `xml:"portid,attr"`
+			State  struct {
+				State string `xml:"state,attr"`
+			} `xml:"state"`
+		} `xml:"ports>port"`
+		Times struct {
+			SRTT string `xml:"srtt,attr"`
+		} `xml:"times"`
+	} `xml:"host"`
+}
+
+// discoveryMethods names nmap's status reasons
+var discoveryMethods = map[string]string{
+	"echo-reply":         "ICMP echo",
+	"timestamp-reply":    "ICMP timestamp",
+	"addressmask-reply":  "ICMP address mask",
+	"proto-response":     "IP protocol",
+	"arp-response":       "ARP",
+	"nd-response":        "IPv6 neighbor discovery",
+	"syn-ack":            "TCP SYN",
+	"reset":              "TCP",
+	"localhost-response": "localhost",
+	"user-set":           "assumed up",
+}
+
+// ParseNmapXML builds an inventory from nmap -oX output. Anything printed
+// before the XML document, such as a sudo prompt, is skipped.
+func ParseNmapXML(output []byte) (*Inventory, error) {
+	if start := bytes.Index(output, []byte("<?xml")); start > 0 {
+		output = output[start:]
+	}
+
+	var run nmapRun
+	if err := xml.Unmarshal(output, &run); err != nil {
+		return nil, fmt.Errorf("failed to parse nmap XML output: %v", err)
+	}
+
+	inv := &Inventory{DiscoveredAt: time.Now()}
+	for _, h := range run.Hosts {
+		if h.Status.State != "up" {
+			continue
+		}
+
+		host := Host{Method: h.Status.Reason}
+		if method, ok := discoveryMethods[h.Status.Reason]; ok {
+			host.Method = method
+		}
+		for _, address := range h.Addresses {
+			if address.Type == "ipv4" || address.Type == "ipv6" {
+				host.IP = address.Addr
+				break
+			}
+		}
+		if host.IP == "" {
+			continue
+		}
+		if len(h.Hostnames) > 0 {
+			host.Hostname = h.Hostnames[0].Name
+		}
+		// srtt is in microseconds
+		if srtt, err := strconv.ParseFloat(h.Times.SRTT, 64); err == nil {
+			host.LatencyMS = srtt / 1000
+		}
+		for _, port := range h.Ports {
+			if port.State.State == "open" {
+				host.OpenPorts = append(host.OpenPorts, port.PortID)
+			}
+		}
+
+		inv.Hosts = append(inv.Hosts, host)
+	}
+
+	inv.sort()
+	return inv, nil
+}
+
+// sort orders the hosts by address
+func (inv *Inventory) sort() {
+	sort.Slice(inv.Hosts, func(i, j int) bool {
+		a, b := net.ParseIP(inv.Hosts[i].IP), net.ParseIP(inv.Hosts[j].IP)
+		if a == nil || b == nil {
+			return inv.Hosts[i].IP < inv.Hosts[j].IP
+		}
+		return bytes.Compare(a.To16(), b.To16()) < 0
+	})
+}
+
+// Addresses returns the IP addresses of the inventory's hosts
+func (inv *Inventory) Addresses() []string {
+	addresses := make([]string, len(inv.Hosts))
+	for i, host := range inv.Hosts {
+		addresses[i] = host.IP
+	}
+	return addresses
+}
+
+// Save writes the inventory as JSON and CSV into a folder
+func (inv *Inventory) Save(folder string) error {
+	if err := os.MkdirAll(folder, 0755); err != nil {
+		return fmt.Errorf("failed to create inventory folder: %v", err)
+	}
+
+	data, err := json.MarshalIndent(inv, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode inventory: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(folder, JSONFileName), data, 0644); err != nil {
+		return fmt.Errorf("failed to write inventory: %v", err)
+	}
+
+	var buf bytes.Buffer
+	writer := csv.NewWriter(&buf)
+	writer.Write([]string{"IP", "Hostname", "Discovery Method", "Latency (ms)", "Open Ports"})
+	for _, host := range inv.Hosts {
+		writer.Write([]string{host.IP, host.Hostname, host.Method, formatLatency(host.LatencyMS), host.Ports()})
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return fmt.Errorf("failed to encode inventory CSV: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(folder, CSVFileName), buf.Bytes(), 0644); err != nil {
+		return fmt.Errorf("failed to write inventory CSV: %v", err)
+	}
+
+	return nil
+}
+
+// Load reads a saved inventory, either from the JSON file itself or from the
+// folder it was saved in. The error wraps os.ErrNotExist when there is none.
+func Load(path string) (*Inventory, error) {
+	if info, err := os.Stat(path); err == nil && info.IsDir() {
+		path = filepath.Join(path, JSONFileName)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read inventory: %w", err)
+	}
+
+	var inv Inventory
+	if err := json.Unmarshal(data, &inv); err != nil {
+		return nil, fmt.Errorf("failed to parse inventory %s: %w", path, err)
+	}
+	return &inv, nil
+}
+
+// Ports returns the open ports space separated
+func (h Host) Ports() string {
+	ports := make([]string, len(h.OpenPorts))
+	for i, port := range h.OpenPorts {
+		ports[i] = strconv.Itoa(port)
+	}
+	return strings.Join(ports, " ")
+}
+
+// Latency returns the round trip time for display, or "" when unknown
+func (h Host) Latency() string {
+	if h.LatencyMS == 0 {
+		return ""
+	}
+	return formatLatency(h.LatencyMS) + " ms"
+}
+
+func formatLatency(ms float64) string {
+	if ms == 0 {
+		return ""
+	}
+	return strconv.FormatFloat(ms, 'f', 2, 64)
+}
@@ -0,0 +1,51 @@
+package inventory
+
+import (
+	"reflect"
+	"testing"
+)
+
+const nmapOutput = `[sudo] password for nmb: 
+<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE nmaprun>
+<nmaprun scanner="nmap" args="nmap -sn -oX - 10.0.0.0/29">
+<host><status state="up" reason="syn-ack"/>
+<address addr="10.0.0.5" addrtype="ipv4"/>
+<hostnames><hostname name="web.example.com" type="PTR"/></hostnames>
+<ports>
+<port protocol="tcp" portid="443"><state state="open"/></port>
+<port protocol="tcp" portid="22"><state state="closed"/></port>
+<port protocol="tcp" portid="80"><state state="open"/></port>
+</ports>
+<times srtt="1500" rttvar="500" to="100000"/>
+</host>
+<host><status state="down" reason="no-response"/>
+<address addr="10.0.0.3" addrtype="ipv4"/>
+</host>
+<host><status state="up" reason="arp-response"/>
+<address addr="00:11:22:33:44:55" addrtype="mac"/>
+<address addr="10.0.0.2" addrtype="ipv4"/>
+<hostnames/>
+</host>
+</nmaprun>`
+
+func TestParseNmapXML(t *testing.T) {
+	inv, err := ParseNmapXML([]byte(nmapOutput))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []Host{
+		{IP: "10.0.0.2", Method: "ARP"},
+		{IP: "10.0.0.5", Hostname: "web.example.com", Method: "TCP SYN", LatencyMS: 1.5, OpenPorts: []int{443, 80}},
+	}
+	if !reflect.DeepEqual(inv.Hosts, want) {
+		t.Errorf("hosts = %+v, want %+v", inv.Hosts, want)
+	}
+}
+
+func TestParseNmapXMLInvalid(t *testing.T) {
+	if _, err := ParseNmapXML([]byte("nmap: command not found")); err == nil {
+		t.Error("got no error for output without XML")
+	}
+}
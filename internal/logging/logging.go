@@ -26,33 +26,67 @@ const (
 
 type WebSocketWriter struct {
 	msgType string
+	jobID   string
 	writer  io.Writer
 }
 
 func (w *WebSocketWriter) Write(p []byte) (n int, err error) {
 	message := string(p)
 	if len(message) > 0 {
-		websocket.GetInstance().BroadcastMessage(w.msgType, message)
+		websocket.GetInstance().Log(w.msgType, w.jobID, message)
 	}
 	return w.writer.Write(p)
 }
 
+// Loggers are the loggers of one run. Runs started by the API publish their
+// lines with the run's job ID, so clients can tell concurrent runs apart.
+type Loggers struct {
+	Info    *log.Logger
+	Warning *log.Logger
+	Error   *log.Logger
+	Success *log.Logger
+}
+
+func newLoggers(jobID string) *Loggers {
+	// Create writers
+	infoWriter := &WebSocketWriter{msgType: "info", jobID: jobID, writer: os.Stdout}
+	warningWriter := &WebSocketWriter{msgType: "warning", jobID: jobID, writer: os.Stdout}
+	errorWriter := &WebSocketWriter{msgType: "error", jobID: jobID, writer: os.Stderr}
+	successWriter := &WebSocketWriter{msgType: "success", jobID: jobID, writer: os.Stdout}
+
+	// Colored prefixes and no timestamps
+	return &Loggers{
+		Info:    log.New(infoWriter, InfoColor+"[-]"+ResetColor+" ", 0),
+		Warning: log.New(warningWriter, WarningColor+"[!]"+ResetColor+" ", 0),
+		Error:   log.New(errorWriter, ErrorColor+"[x]"+ResetColor+" ", 0),
+		Success: log.New(successWriter, SuccessColor+"[+]"+ResetColor+" ", 0),
+	}
+}
+
 func Init() {
 	once.Do(func() {
-		// Create writers
-		infoWriter := &WebSocketWriter{msgType: "info", writer: os.Stdout}
-		warningWriter := &WebSocketWriter{msgType: "warning", writer: os.Stdout}
-		errorWriter := &WebSocketWriter{msgType: "error", writer: os.Stderr}
-		successWriter := &WebSocketWriter{msgType: "success", writer: os.Stdout}
-
-		// Initialize loggers with colored prefixes and no timestamps
-		InfoLogger = log.New(infoWriter, InfoColor+"[-]"+ResetColor+" ", 0)
-		WarningLogger = log.New(warningWriter, WarningColor+"[!]"+ResetColor+" ", 0)
-		ErrorLogger = log.New(errorWriter, ErrorColor+"[x]"+ResetColor+" ", 0)
-		SuccessLogger = log.New(successWriter, SuccessColor+"[+]"+ResetColor+" ", 0)
+		loggers := newLoggers("")
+		InfoLogger = loggers.Info
+		WarningLogger = loggers.Warning
+		ErrorLogger = loggers.Error
+		SuccessLogger = loggers.Success
 	})
 }
 
+// ForJob returns the loggers of a run started by the API, or the global
+// loggers when there is no job ID
+func ForJob(jobID string) *Loggers {
+	if jobID == "" {
+		return &Loggers{
+			Info:    GetInfoLogger(),
+			Warning: GetWarningLogger(),
+			Error:   GetErrorLogger(),
+			Success: GetSuccessLogger(),
+		}
+	}
+	return newLoggers(jobID)
+}
+
 // GetInfoLogger returns the InfoLogger, initializing it if necessary
 func GetInfoLogger() *log.Logger {
 	if InfoLogger == nil {
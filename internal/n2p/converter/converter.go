@@ -11,6 +11,8 @@ import (
 	"path/filepath"
 	"regexp"
 	"strings"
+
+	"NMB/internal/nessus"
 )
 
 // NessusToPlextracConverter handles conversion of Nessus scan results to Plextrac format
@@ -114,30 +116,20 @@ func (c *NessusToPlextracConverter) ProcessCSVFile(fileName string) error {
 	}
 	defer file.Close()
 
-	reader := csv.NewReader(file)
-	reader.LazyQuotes = true
-	reader.FieldsPerRecord = -1 // Allow variable number of fields
-
-	// Read header
-	header, err := reader.Read()
+	// Columns are mapped by header name, so rows are keyed by the Nessus
+	// column names whichever export format the file came from
+	reader, err := nessus.NewReader(file, nessus.ColumnPluginID, nessus.ColumnHost, nessus.ColumnName)
 	if err != nil {
 		return fmt.Errorf("failed to read CSV header: %w", err)
 	}
 
 	// Process rows
 	for {
-		record, err := reader.Read()
+		row, err := reader.Read()
 		if err != nil {
 			break // End of file or error
 		}
 
-		row := make(map[string]string)
-		for i, value := range record {
-			if i < len(header) {
-				row[header[i]] = value
-			}
-		}
-
 		c.ProcessCSVRow(row)
 	}
 
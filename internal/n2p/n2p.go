@@ -177,7 +177,7 @@ func (e *Engine) ExecuteWorkflow() error {
 	e.Logger.Info("Authenticating to Plextrac...")
 	authenticated, err := e.PlextracHandler.Authenticate()
 	if err != nil || !authenticated {
-		e.Logger.Warn("Authentication failed: %v", err)
+		e.Logger.Warnf("Authentication failed: %v", err)
 		return fmt.Errorf("authentication failed")
 	}
 	e.Logger.Info("Authentication successful")
@@ -185,7 +185,7 @@ func (e *Engine) ExecuteWorkflow() error {
 	// Convert to Plextrac format
 	e.Logger.Info("Converting Nessus file to Plextrac format...")
 	if err := e.Converter.Convert(e.PlextracFormatFile); err != nil {
-		e.Logger.Warn("Conversion failed: %v", err)
+		e.Logger.Warnf("Conversion failed: %v", err)
 		return fmt.Errorf("conversion failed")
 	}
 	e.Logger.Info("Conversion successful")
@@ -200,7 +200,7 @@ func (e *Engine) ExecuteWorkflow() error {
 		existingFlaws := flawLister.GetExistingFlaws()
 		flawsFilePath := "./existing_flaws.txt"
 		if err := e.WriteFlawsToFile(existingFlaws, flawsFilePath); err != nil {
-			e.Logger.Warn("Failed to write flaws to file: %v", err)
+			e.Logger.Warnf("Failed to write flaws to file: %v", err)
 			return fmt.Errorf("failed to write flaws to file")
 		}
 	} else {
@@ -212,7 +212,7 @@ func (e *Engine) ExecuteWorkflow() error {
 	// Upload Nessus file
 	e.Logger.Info("Uploading Nessus file to Plextrac...")
 	if err := e.PlextracHandler.UploadNessusFile(e.PlextracFormatFile); err != nil {
-		e.Logger.Warn("Upload failed: %v", err)
+		e.Logger.Warnf("Upload failed: %v", err)
 		return fmt.Errorf("upload failed")
 	}
 	e.Logger.Info("Upload successful")
@@ -220,7 +220,7 @@ func (e *Engine) ExecuteWorkflow() error {
 	// Upload screenshots
 	e.Logger.Info("Updating flaws with screenshots...")
 	if err := e.ScreenshotUpdater.FlawUpdateEngine(); err != nil {
-		e.Logger.Warn("Screenshot update failed: %v", err)
+		e.Logger.Warnf("Screenshot update failed: %v", err)
 		// Continue execution instead of returning error
 		e.Logger.Warn("Continuing execution despite screenshot update failure")
 	} else {
@@ -230,7 +230,7 @@ func (e *Engine) ExecuteWorkflow() error {
 	// Process descriptions
 	e.Logger.Info("Processing and updating descriptions for flaws...")
 	if err := e.DescProcessor.Process(); err != nil {
-		e.Logger.Warn("Description processing failed: %v", err)
+		e.Logger.Warnf("Description processing failed: %v", err)
 		// Continue execution instead of returning error
 		e.Logger.Warn("Continuing execution despite description processing failure")
 	} else {
@@ -241,7 +241,7 @@ func (e *Engine) ExecuteWorkflow() error {
 	if e.NonCoreUpdater != nil {
 		e.Logger.Info("Processing and updating custom fields for flaws...")
 		if err := e.NonCoreUpdater.Process(); err != nil {
-			e.Logger.Warn("Non-core field processing failed: %v", err)
+			e.Logger.Warnf("Non-core field processing failed: %v", err)
 			// Continue execution instead of returning error
 			e.Logger.Warn("Continuing execution despite non-core field processing failure")
 		} else {
@@ -333,12 +333,12 @@ func (e *Engine) CleanupOnExit() {
 
 	// Move Plextrac format file to _merged folder
 	if err := e.MovePlextracFormatFile(); err != nil {
-		e.Logger.Warn("Failed to move Plextrac format file: %v", err)
+		e.Logger.Warnf("Failed to move Plextrac format file: %v", err)
 	}
 
 	// Clean up existing flaws file
 	if err := e.CleanupFile("existing_flaws.txt"); err != nil {
-		e.Logger.Warn("Failed to clean up existing flaws file: %v", err)
+		e.Logger.Warnf("Failed to clean up existing flaws file: %v", err)
 	}
 
 	e.Logger.Info("Cleanup complete")
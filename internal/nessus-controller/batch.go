@@ -0,0 +1,364 @@
+package nessus
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"NMB/internal/targets"
+)
+
+// BatchOptions splits the project's targets over several scans. Targets are
+// chunked by Subnet prefix length when it is set, or into ChunkSize targets
+// per scan otherwise; at most Concurrency scans run at once.
+type BatchOptions struct {
+	ChunkSize   int
+	Subnet      int
+	Concurrency int
+}
+
+const defaultBatchConcurrency = 2
+
+// Validate checks that the targets can be chunked
+func (o BatchOptions) Validate() error {
+	if o.ChunkSize <= 0 && o.Subnet == 0 {
+		return fmt.Errorf("batch mode needs a chunk size or a subnet prefix length")
+	}
+	if o.ChunkSize < 0 {
+		return fmt.Errorf("invalid batch size %d", o.ChunkSize)
+	}
+	if o.Subnet != 0 && (o.Subnet < 1 || o.Subnet > 32) {
+		return fmt.Errorf("invalid batch subnet prefix length %d", o.Subnet)
+	}
+	if o.Concurrency < 0 {
+		return fmt.Errorf("invalid batch concurrency %d", o.Concurrency)
+	}
+	return nil
+}
+
+// batchResult is the outcome of one scan of a batch
+type batchResult struct {
+	name    string
+	csvFile string
+	err     error
+}
+
+// batchPart is one scan of a batch plan
+type batchPart struct {
+	Name    string `json:"name"`
+	Targets string `json:"targets"`
+}
+
+// RunBatch splits the project's targets into scans named <project>-partN,
+// runs them with the concurrency limit, exports each one and merges their
+// CSV exports into a single CSV in the project's evidence folder, whose
+// path is returned. The parts are saved in the project folder, and parts
+// that already exist are not created again, so an interrupted batch can be
+// run again with the same targets per part. When some parts fail, the
+// finished ones are still merged and the error lists the failed parts.
+func (n *Nessus) RunBatch(opts BatchOptions) (string, error) {
+	var merged string
+	err := n.safeExecute("RunBatch", func() error {
+		var err error
+		merged, err = n.runBatch(opts)
+		return err
+	})
+	return merged, err
+}
+
+func (n *Nessus) runBatch(opts BatchOptions) (string, error) {
+	if err := opts.Validate(); err != nil {
+		return "", err
+	}
+	if err := n.excludeTargets(); err != nil {
+		return "", err
+	}
+
+	parts, err := n.batchPlan(opts)
+	if err != nil {
+		return "", err
+	}
+	n.log.Info.Printf("Splitting targets into %d scans", len(parts))
+
+	// Import the policy file once rather than once per part
+	policy := n.policy
+	if n.policyFile != "" {
+		policy, err = n.importPolicy(n.policyFile)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	concurrency := opts.Concurrency
+	if concurrency == 0 {
+		concurrency = defaultBatchConcurrency
+	}
+
+	results := make([]batchResult, len(parts))
+	slots := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i, part := range parts {
+		list, err := targets.ParseString(part.Targets)
+		if err != nil {
+			return "", fmt.Errorf("invalid targets for batch scan %s: %v", part.Name, err)
+		}
+		n.log.Info.Printf("Batch scan %s: %s", part.Name, list.Summary())
+
+		wg.Add(1)
+		go func(i int, scan *Nessus) {
+			defer wg.Done()
+			slots <- struct{}{}
+			defer func() { <-slots }()
+
+			results[i] = scan.runBatchPart()
+		}(i, n.batchPart(part, policy))
+	}
+	wg.Wait()
+
+	var csvFiles, failed []string
+	for _, result := range results {
+		if result.err != nil {
+			n.log.Error.Printf("Batch scan %s failed: %v", result.name, result.err)
+			failed = append(failed, result.name)
+			continue
+		}
+		csvFiles = append(csvFiles, result.csvFile)
+	}
+	if len(csvFiles) == 0 {
+		return "", fmt.Errorf("all %d batch scans failed", len(parts))
+	}
+
+	evidenceFolder := filepath.Join(n.outputFolder, "evidence", n.projectName)
+	if err := os.MkdirAll(evidenceFolder, 0755); err != nil {
+		return "", fmt.Errorf("failed to create evidence folder: %v", err)
+	}
+	merged := filepath.Join(evidenceFolder, n.projectName+".csv")
+	if err := mergeCSV(csvFiles, merged); err != nil {
+		return "", err
+	}
+	n.log.Success.Printf("Merged %d batch exports into %s", len(csvFiles), merged)
+
+	if len(failed) > 0 {
+		return merged, fmt.Errorf("%d of %d batch scans failed: %s", len(failed), len(parts), strings.Join(failed, ", "))
+	}
+	return merged, nil
+}
+
+// batchPlanFile is the file in the project folder that records the targets
+// of each part, so a batch run again resumes every part with its targets
+func (n *Nessus) batchPlanFile() string {
+	name := strings.NewReplacer("/", "_", "\\", "_").Replace(n.projectName)
+	return filepath.Join(n.outputFolder, fmt.Sprintf("NMB_batch_%s.json", name))
+}
+
+// batchPlan chunks the discovered hosts, or the target list when discovery
+// was not run. When the project already has a plan its parts are kept, and
+// targets none of them cover are chunked into new parts after them.
+func (n *Nessus) batchPlan(opts BatchOptions) ([]batchPart, error) {
+	current := n.aliveHosts
+	if current == "" {
+		current = n.targetsList
+	}
+	if current == "" {
+		return nil, fmt.Errorf("no targets specified")
+	}
+
+	list, err := targets.ParseString(current)
+	if err != nil {
+		return nil, err
+	}
+
+	parts, err := loadBatchPlan(n.batchPlanFile())
+	if err != nil {
+		return nil, err
+	}
+	if len(parts) > 0 {
+		var planned []string
+		for _, part := range parts {
+			planned = append(planned, part.Targets)
+		}
+		covered, err := targets.ParseString(strings.Join(planned, "\n"))
+		if err != nil {
+			return nil, fmt.Errorf("invalid batch plan %s: %v", n.batchPlanFile(), err)
+		}
+		list = list.Subtract(covered)
+		n.log.Info.Printf("Resuming the %d scans of the existing batch plan", len(parts))
+		if list.Empty() {
+			return parts, nil
+		}
+		n.log.Info.Printf("Adding scans for targets not in the batch plan: %s", list.Summary())
+	}
+
+	var chunks []*targets.List
+	if opts.Subnet != 0 {
+		if chunks, err = list.SplitBySubnet(opts.Subnet); err != nil {
+			return nil, err
+		}
+	} else {
+		chunks = list.Split(uint64(opts.ChunkSize))
+	}
+	for _, chunk := range chunks {
+		name := fmt.Sprintf("%s-part%d", n.projectName, len(parts)+1)
+		parts = append(parts, batchPart{Name: name, Targets: chunk.String()})
+	}
+
+	if err := saveBatchPlan(n.batchPlanFile(), parts); err != nil {
+		return nil, err
+	}
+	return parts, nil
+}
+
+// loadBatchPlan reads a batch plan, returning no parts when there is none
+func loadBatchPlan(path string) ([]batchPart, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read batch plan: %v", err)
+	}
+
+	var parts []batchPart
+	if err := json.Unmarshal(data, &parts); err != nil {
+		return nil, fmt.Errorf("failed to parse batch plan %s: %v", path, err)
+	}
+	return parts, nil
+}
+
+func saveBatchPlan(path string, parts []batchPart) error {
+	data, err := json.MarshalIndent(parts, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode batch plan: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create project folder: %v", err)
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write batch plan: %v", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("failed to write batch plan: %v", err)
+	}
+	return nil
+}
+
+// batchPart returns a controller for one scan of a batch. It shares the
+// SSH connection and the authenticated API client of n, and uses policy
+// rather than importing the policy file again.
+func (n *Nessus) batchPart(part batchPart, policy string) *Nessus {
+	exportOptions := n.exportOptions
+	if len(exportOptions.Formats) > 0 && !containsString(exportOptions.Formats, "csv") {
+		// The merged results need every part as CSV
+		exportOptions.Formats = append([]string{"csv"}, exportOptions.Formats...)
+	}
+
+	return &Nessus{
+		remote:        n.remote,
+		api:           n.api,
+		url:           n.url,
+		username:      n.username,
+		projectName:   part.Name,
+		targetsList:   part.Targets,
+		droneIP:       n.droneIP,
+		outputFolder:  n.outputFolder,
+		policy:        policy,
+		scanner:       n.scanner,
+		folder:        n.folder,
+		schedule:      n.schedule,
+		window:        n.window,
+		exportOptions: exportOptions,
+		log:           n.log,
+	}
+}
+
+// runBatchPart creates or resumes one scan of a batch, waits for it and
+// exports it
+func (n *Nessus) runBatchPart() batchResult {
+	result := batchResult{name: n.projectName}
+
+	if result.err = n.Start(); result.err != nil {
+		return result
+	}
+	if result.err = n.Wait(); result.err != nil {
+		return result
+	}
+
+	files, err := n.exportScanFiles()
+	if err != nil {
+		result.err = err
+		return result
+	}
+	result.csvFile = files["csv"]
+	return result
+}
+
+// mergeCSV concatenates CSV exports into one file, keeping the header of
+// the first. All files must have the same columns.
+func mergeCSV(files []string, output string) error {
+	out, err := os.Create(output)
+	if err != nil {
+		return fmt.Errorf("failed to create merged CSV: %v", err)
+	}
+	defer out.Close()
+
+	writer := csv.NewWriter(out)
+	var header []string
+	for _, file := range files {
+		if err := appendCSV(writer, file, &header); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return fmt.Errorf("failed to write merged CSV: %v", err)
+	}
+	return nil
+}
+
+func appendCSV(writer *csv.Writer, file string, header *[]string) error {
+	in, err := os.Open(file)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %v", file, err)
+	}
+	defer in.Close()
+
+	reader := csv.NewReader(in)
+	reader.LazyQuotes = true
+
+	columns, err := reader.Read()
+	if err == io.EOF {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %v", file, err)
+	}
+	if *header == nil {
+		*header = columns
+		if err := writer.Write(columns); err != nil {
+			return err
+		}
+	} else if strings.Join(columns, ",") != strings.Join(*header, ",") {
+		return fmt.Errorf("%s has different columns than the other exports", file)
+	}
+
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %v", file, err)
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+}
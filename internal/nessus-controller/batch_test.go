@@ -0,0 +1,57 @@
+package nessus
+
+import (
+	"reflect"
+	"testing"
+
+	"NMB/internal/logging"
+)
+
+func TestBatchPlanKeepsParts(t *testing.T) {
+	n := &Nessus{projectName: "acme", outputFolder: t.TempDir(), targetsList: "10.0.0.1-10.0.0.4", log: logging.ForJob("")}
+	opts := BatchOptions{ChunkSize: 2}
+
+	first, err := n.batchPlan(opts)
+	if err != nil {
+		t.Fatalf("batchPlan: %v", err)
+	}
+	want := []batchPart{
+		{Name: "acme-part1", Targets: "10.0.0.1,10.0.0.2"},
+		{Name: "acme-part2", Targets: "10.0.0.3,10.0.0.4"},
+	}
+	if !reflect.DeepEqual(first, want) {
+		t.Fatalf("first plan = %q, want %q", first, want)
+	}
+
+	// Discovery finds a different host set on the next run: the existing
+	// parts keep their targets and the new host gets a part of its own
+	n.targetsList = "10.0.0.2\n10.0.0.9"
+	second, err := n.batchPlan(opts)
+	if err != nil {
+		t.Fatalf("batchPlan again: %v", err)
+	}
+	want = append(want, batchPart{Name: "acme-part3", Targets: "10.0.0.9"})
+	if !reflect.DeepEqual(second, want) {
+		t.Fatalf("second plan = %q, want %q", second, want)
+	}
+
+	saved, err := loadBatchPlan(n.batchPlanFile())
+	if err != nil {
+		t.Fatalf("loadBatchPlan: %v", err)
+	}
+	if !reflect.DeepEqual(saved, want) {
+		t.Errorf("saved plan = %q, want %q", saved, want)
+	}
+}
+
+func TestBatchPartKeepsCallerPolicy(t *testing.T) {
+	n := &Nessus{projectName: "acme", policyFile: "policy.nessus", policy: "Basic"}
+
+	part := n.batchPart(batchPart{Name: "acme-part1", Targets: "10.0.0.1"}, "42")
+	if part.policy != "42" || part.policyFile != "" {
+		t.Errorf("part policy = %q, policy file %q", part.policy, part.policyFile)
+	}
+	if n.policy != "Basic" || n.policyFile != "policy.nessus" {
+		t.Errorf("caller policy changed to %q, policy file %q", n.policy, n.policyFile)
+	}
+}
@@ -0,0 +1,85 @@
+package nessus
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"runtime"
+
+	"NMB/internal/logging"
+)
+
+// Environment variables read by LoadCredentials
+const (
+	EnvUsername  = "NESSUS_USERNAME"
+	EnvPassword  = "NESSUS_PASSWORD"
+	EnvAccessKey = "NESSUS_ACCESS_KEY"
+	EnvSecretKey = "NESSUS_SECRET_KEY"
+)
+
+// Credentials authenticate against Nessus. API keys are used when both are
+// set; the username and password log in to a web session otherwise.
+type Credentials struct {
+	Username  string `json:"username,omitempty"`
+	Password  string `json:"password,omitempty"`
+	AccessKey string `json:"accessKey,omitempty"`
+	SecretKey string `json:"secretKey,omitempty"`
+}
+
+// LoadCredentials reads credentials from a JSON file, when a path is given,
+// and then from the NESSUS_* environment variables, which take precedence
+func LoadCredentials(path string) (*Credentials, error) {
+	creds := &Credentials{}
+
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read credentials file: %v", err)
+		}
+		if err := json.Unmarshal(data, creds); err != nil {
+			return nil, fmt.Errorf("failed to parse credentials file %s: %v", path, err)
+		}
+		if info, err := os.Stat(path); err == nil && runtime.GOOS != "windows" && info.Mode().Perm()&0077 != 0 {
+			logging.WarningLogger.Printf("Credentials file %s is readable by other users, consider chmod 600", path)
+		}
+	}
+
+	for env, field := range map[string]*string{
+		EnvUsername:  &creds.Username,
+		EnvPassword:  &creds.Password,
+		EnvAccessKey: &creds.AccessKey,
+		EnvSecretKey: &creds.SecretKey,
+	} {
+		if value := os.Getenv(env); value != "" {
+			*field = value
+		}
+	}
+
+	return creds, nil
+}
+
+// HasAPIKeys reports whether both API keys are set
+func (c *Credentials) HasAPIKeys() bool {
+	return c.AccessKey != "" && c.SecretKey != ""
+}
+
+// HasLogin reports whether a username and password are set
+func (c *Credentials) HasLogin() bool {
+	return c.Username != "" && c.Password != ""
+}
+
+// Validate checks that the credentials can authenticate one way or the other
+func (c *Credentials) Validate() error {
+	if (c.AccessKey == "") != (c.SecretKey == "") {
+		return fmt.Errorf("both an access key and a secret key are required for API key authentication")
+	}
+	if !c.HasAPIKeys() && !c.HasLogin() {
+		return fmt.Errorf("no Nessus credentials: set %s/%s, %s/%s or use a credentials file",
+			EnvAccessKey, EnvSecretKey, EnvUsername, EnvPassword)
+	}
+	return nil
+}
+
+func (c *Credentials) apiKeysHeader() string {
+	return fmt.Sprintf("accessKey=%s; secretKey=%s", c.AccessKey, c.SecretKey)
+}
@@ -0,0 +1,342 @@
+package nessus
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"NMB/internal/targets"
+)
+
+type ExportFormat struct {
+	Format            string                 `json:"format"`
+	TemplateID        string                 `json:"template_id,omitempty"`
+	Password          string                 `json:"password,omitempty"`
+	ReportContents    map[string]interface{} `json:"reportContents,omitempty"`
+	ExtraFilters      map[string]interface{} `json:"extraFilters,omitempty"`
+	FormattingOptions map[string]interface{} `json:"formattingOptions,omitempty"`
+}
+
+// ExportOptions selects what an export downloads. Empty fields fall back to
+// the defaults: csv, nessus and html with every CSV column, no filters, and
+// the "Detailed Vulnerabilities By Plugin" template. Nessus only applies the
+// plugin and host filters to csv, html and pdf exports.
+type ExportOptions struct {
+	Formats    []string `json:"formats,omitempty"`
+	Columns    []string `json:"columns,omitempty"`
+	Severities []string `json:"severities,omitempty"`
+	PluginIDs  []string `json:"pluginIds,omitempty"`
+	Hosts      []string `json:"hosts,omitempty"`
+	Template   string   `json:"template,omitempty"`
+	DBPassword string   `json:"dbPassword,omitempty"`
+}
+
+const defaultReportTemplate = "Detailed Vulnerabilities By Plugin"
+
+var defaultExportFormats = []string{"csv", "nessus", "html"}
+
+// defaultFilteredFormats are the default formats when plugins or hosts are
+// filtered, leaving out nessus, which would not be filtered
+var defaultFilteredFormats = []string{"csv", "html"}
+
+// csvColumns are the columns a Nessus CSV export can include
+var csvColumns = []string{
+	"id", "cve", "cvss", "risk", "hostname", "protocol", "port", "plugin_name",
+	"synopsis", "description", "solution", "see_also", "plugin_output",
+	"stig_severity", "cvss3_base_score", "cvss_temporal_score",
+	"cvss3_temporal_score", "risk_factor", "references", "plugin_information",
+	"exploitable_with",
+}
+
+var severityLevels = map[string]string{
+	"critical": "4", "high": "3", "medium": "2", "low": "1", "info": "0", "none": "0",
+}
+
+// Validate checks the formats, columns and severities before anything is
+// requested from Nessus
+func (o ExportOptions) Validate() error {
+	for _, format := range o.Formats {
+		switch format {
+		case "csv", "html", "pdf":
+		case "nessus", "db":
+			if o.filtered() {
+				return fmt.Errorf("the %s export format cannot be filtered by plugin or host (csv, html, pdf)", format)
+			}
+			if format == "db" && o.DBPassword == "" {
+				return fmt.Errorf("the db export format requires a password")
+			}
+		default:
+			return fmt.Errorf("unsupported export format %q (csv, nessus, html, pdf, db)", format)
+		}
+	}
+
+	for _, column := range o.Columns {
+		if !containsString(csvColumns, column) {
+			return fmt.Errorf("unknown CSV column %q (%s)", column, strings.Join(csvColumns, ", "))
+		}
+	}
+
+	for _, severity := range o.Severities {
+		if _, ok := severityLevels[strings.ToLower(severity)]; !ok {
+			return fmt.Errorf("unknown severity %q", severity)
+		}
+	}
+
+	for _, id := range o.PluginIDs {
+		if _, err := strconv.Atoi(id); err != nil {
+			return fmt.Errorf("invalid plugin ID %q", id)
+		}
+	}
+
+	return nil
+}
+
+// filtered reports whether the export is limited to some plugins or hosts
+func (o ExportOptions) filtered() bool {
+	return len(o.PluginIDs) > 0 || len(o.Hosts) > 0
+}
+
+// formats returns the selected formats, or the defaults
+func (o ExportOptions) formats() []string {
+	switch {
+	case len(o.Formats) > 0:
+		return o.Formats
+	case o.filtered():
+		return defaultFilteredFormats
+	default:
+		return defaultExportFormats
+	}
+}
+
+// ExportScanByID exports a specific scan by ID with the controller's export
+// options
+func (n *Nessus) ExportScanByID(scanID string) error {
+	return n.ExportScanWithOptions(scanID, n.exportOptions)
+}
+
+// ExportScanWithOptions exports a specific scan by ID, waiting for it to
+// finish first if it is still running. It leaves the controller's project
+// alone, as the API shares controllers between requests.
+func (n *Nessus) ExportScanWithOptions(scanID string, opts ExportOptions) error {
+	id, err := parseScanID(scanID)
+	if err != nil {
+		return err
+	}
+	details, err := n.GetScanDetails(scanID)
+	if err != nil {
+		return err
+	}
+
+	scanName := details.Info.Name
+	if scanName == "" {
+		scanName = fmt.Sprintf("scan_%s", scanID)
+	}
+
+	if status := details.Info.Status; status == "running" || status == "pending" {
+		n.log.Info.Printf("Scan still running, will monitor until completion")
+		if err := n.watchScan(id, false); err != nil {
+			return fmt.Errorf("monitoring scan failed: %v", err)
+		}
+	}
+
+	_, err = n.export(id, scanName, opts)
+	return err
+}
+
+// exportScan exports the project's scan and returns the path of the .nessus
+// file, or of the first exported file when .nessus was not selected
+func (n *Nessus) exportScan() (string, error) {
+	files, err := n.exportScanFiles()
+	if err != nil {
+		return "", err
+	}
+
+	if path, ok := files["nessus"]; ok {
+		return path, nil
+	}
+	return files[n.exportOptions.formats()[0]], nil
+}
+
+// exportScanFiles exports the project's scan, waiting for it to finish
+// first, and returns the written files by format
+func (n *Nessus) exportScanFiles() (map[string]string, error) {
+	n.log.Info.Printf("Exporting scan results...")
+
+	scan := n.getScanInfo()
+	if scan == nil {
+		return nil, fmt.Errorf("scan not found")
+	}
+
+	if scan.Status == "running" || scan.Status == "pending" {
+		n.log.Error.Printf("Scan still running, waiting for it to finish...")
+		if err := n.monitorScan(); err != nil {
+			return nil, fmt.Errorf("monitoring scan failed: %v", err)
+		}
+	}
+
+	return n.export(scan.ID, scan.Name, n.exportOptions)
+}
+
+// export downloads each selected format of a finished scan into the
+// evidence folder and returns the written files by format
+func (n *Nessus) export(scanID int, scanName string, opts ExportOptions) (map[string]string, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+
+	// Create an evidence folder based on the scan name
+	evidenceFolder := filepath.Join(n.outputFolder, "evidence", scanName)
+	if err := os.MkdirAll(evidenceFolder, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create evidence folder: %v", err)
+	}
+	n.log.Info.Printf("Created evidence folder: %s", evidenceFolder)
+
+	extraFilters, err := n.exportExtraFilters(scanID, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	files := make(map[string]string)
+	for _, format := range opts.formats() {
+		n.log.Info.Printf("Exporting %s file...", format)
+
+		request, err := n.exportRequest(format, opts, extraFilters)
+		if err != nil {
+			return nil, err
+		}
+
+		token, err := n.api.Export(scanID, request)
+		if err != nil {
+			return nil, fmt.Errorf("failed to export %s format: %w", format, err)
+		}
+
+		// Create the output file path within the evidence folder
+		outputFile := filepath.Join(evidenceFolder, fmt.Sprintf("%s.%s", scanName, format))
+		if err := n.waitForDownload(token.Token, outputFile); err != nil {
+			return nil, fmt.Errorf("failed to download %s file: %v", format, err)
+		}
+
+		files[format] = outputFile
+		n.log.Success.Printf("Exported %s file to %q", format, outputFile)
+	}
+
+	return files, nil
+}
+
+// exportRequest builds the export body for one format. Severity filters are
+// sent as Nessus report filters, OR'd together.
+func (n *Nessus) exportRequest(format string, opts ExportOptions, extraFilters map[string]interface{}) (map[string]interface{}, error) {
+	config := ExportFormat{Format: format}
+
+	switch format {
+	case "csv":
+		columns := opts.Columns
+		if len(columns) == 0 {
+			columns = csvColumns
+		}
+		selected := make(map[string]bool, len(columns))
+		for _, column := range columns {
+			selected[column] = true
+		}
+		config.ReportContents = map[string]interface{}{"csvColumns": selected}
+		config.ExtraFilters = extraFilters
+	case "html", "pdf":
+		templateID, err := n.getTemplateID(opts.Template)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get report template ID: %v", err)
+		}
+		config.TemplateID = templateID
+		config.ExtraFilters = extraFilters
+	case "db":
+		config.Password = opts.DBPassword
+	}
+
+	// Round trip through JSON so the filter keys can be added alongside
+	data, err := json.Marshal(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to serialize export config: %v", err)
+	}
+	var request map[string]interface{}
+	if err := json.Unmarshal(data, &request); err != nil {
+		return nil, err
+	}
+
+	for i, severity := range opts.Severities {
+		request[fmt.Sprintf("filter.%d.filter", i)] = "severity"
+		request[fmt.Sprintf("filter.%d.quality", i)] = "eq"
+		request[fmt.Sprintf("filter.%d.value", i)] = severityLevels[strings.ToLower(severity)]
+	}
+	if len(opts.Severities) > 0 {
+		request["filter.search_type"] = "or"
+	}
+
+	return request, nil
+}
+
+// exportExtraFilters resolves the plugin and host filters to the IDs the
+// export API expects. Hosts may be addresses, CIDRs, ranges or hostnames.
+func (n *Nessus) exportExtraFilters(scanID int, opts ExportOptions) (map[string]interface{}, error) {
+	pluginIDs := []int{}
+	for _, id := range opts.PluginIDs {
+		value, _ := strconv.Atoi(id)
+		pluginIDs = append(pluginIDs, value)
+	}
+
+	hostIDs := []int{}
+	if len(opts.Hosts) > 0 {
+		wanted, err := targets.ParseString(strings.Join(opts.Hosts, ","))
+		if err != nil {
+			return nil, fmt.Errorf("invalid export host filter: %w", err)
+		}
+
+		details, err := n.api.ScanDetails(scanID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get scan details: %w", err)
+		}
+		for _, host := range details.Hosts {
+			if wanted.Contains(host.Hostname) {
+				hostIDs = append(hostIDs, host.ID)
+			}
+		}
+		if len(hostIDs) == 0 {
+			return nil, fmt.Errorf("no hosts in the scan match the export host filter")
+		}
+	}
+
+	return map[string]interface{}{
+		"host_ids":   hostIDs,
+		"plugin_ids": pluginIDs,
+	}, nil
+}
+
+// getTemplateID looks up a report template by name, used by html and pdf
+// exports
+func (n *Nessus) getTemplateID(name string) (string, error) {
+	if name == "" {
+		name = defaultReportTemplate
+	}
+
+	templates, err := n.api.ListReportTemplates()
+	if err != nil {
+		return "", err
+	}
+
+	for _, template := range templates {
+		if strings.EqualFold(template.Name, name) {
+			return strconv.Itoa(template.ID), nil
+		}
+	}
+	return "", fmt.Errorf("template '%s' not found", name)
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,58 @@
+package nessus
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestExportOptionsValidate(t *testing.T) {
+	tests := []struct {
+		name string
+		opts ExportOptions
+		want string
+	}{
+		{"defaults", ExportOptions{}, ""},
+		{"every format", ExportOptions{Formats: []string{"csv", "nessus", "html", "pdf", "db"}, DBPassword: "pw"}, ""},
+		{"filtered report formats", ExportOptions{Formats: []string{"csv", "pdf"}, Hosts: []string{"10.0.0.0/24"}, PluginIDs: []string{"19506"}}, ""},
+		{"unknown format", ExportOptions{Formats: []string{"xlsx"}}, "unsupported export format"},
+		{"db without password", ExportOptions{Formats: []string{"db"}}, "requires a password"},
+		{"nessus filtered by host", ExportOptions{Formats: []string{"csv", "nessus"}, Hosts: []string{"10.0.0.1"}}, "nessus export format cannot be filtered"},
+		{"db filtered by plugin", ExportOptions{Formats: []string{"db"}, DBPassword: "pw", PluginIDs: []string{"19506"}}, "db export format cannot be filtered"},
+		{"unknown column", ExportOptions{Columns: []string{"owner"}}, "unknown CSV column"},
+		{"unknown severity", ExportOptions{Severities: []string{"urgent"}}, "unknown severity"},
+		{"invalid plugin ID", ExportOptions{Formats: []string{"csv"}, PluginIDs: []string{"ssl"}}, "invalid plugin ID"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.opts.Validate()
+			if tt.want == "" {
+				if err != nil {
+					t.Errorf("Validate: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Validate = %v, want an error containing %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestExportFormats(t *testing.T) {
+	tests := []struct {
+		opts ExportOptions
+		want []string
+	}{
+		{ExportOptions{}, []string{"csv", "nessus", "html"}},
+		{ExportOptions{Hosts: []string{"10.0.0.1"}}, []string{"csv", "html"}},
+		{ExportOptions{PluginIDs: []string{"19506"}}, []string{"csv", "html"}},
+		{ExportOptions{Formats: []string{"pdf"}, Hosts: []string{"10.0.0.1"}}, []string{"pdf"}},
+	}
+	for _, tt := range tests {
+		if got := tt.opts.formats(); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("formats of %+v = %v, want %v", tt.opts, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package nessus
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"NMB/internal/nessusapi"
+)
+
+// GetScanHosts returns the hosts of a scan with their finding counts
+func (n *Nessus) GetScanHosts(scanID string) ([]nessusapi.Host, error) {
+	details, err := n.GetScanDetails(scanID)
+	if err != nil {
+		return nil, err
+	}
+	return details.Hosts, nil
+}
+
+// GetHostDetails returns a host's information and findings. historyID
+// selects an earlier run of the scan and may be empty for the latest.
+func (n *Nessus) GetHostDetails(scanID, hostID, historyID string) (*nessusapi.HostDetails, error) {
+	ids, err := parseIDs(map[string]string{"scan": scanID, "host": hostID, "history": historyID})
+	if err != nil {
+		return nil, err
+	}
+
+	details, err := n.api.HostDetails(ids["scan"], ids["host"], ids["history"])
+	if err != nil {
+		return nil, fmt.Errorf("failed to get host details: %w", err)
+	}
+	return details, nil
+}
+
+// GetPluginOutput returns the output of one plugin on one host. historyID
+// selects an earlier run of the scan and may be empty for the latest.
+func (n *Nessus) GetPluginOutput(scanID, hostID, pluginID, historyID string) (*nessusapi.PluginDetails, error) {
+	ids, err := parseIDs(map[string]string{"scan": scanID, "host": hostID, "plugin": pluginID, "history": historyID})
+	if err != nil {
+		return nil, err
+	}
+
+	details, err := n.api.PluginOutput(ids["scan"], ids["host"], ids["plugin"], ids["history"])
+	if err != nil {
+		return nil, fmt.Errorf("failed to get plugin output: %w", err)
+	}
+	return details, nil
+}
+
+// GetScanHistory returns the previous runs of a scan
+func (n *Nessus) GetScanHistory(scanID string) ([]nessusapi.History, error) {
+	id, err := parseScanID(scanID)
+	if err != nil {
+		return nil, err
+	}
+
+	history, err := n.api.ScanHistory(id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get scan history: %w", err)
+	}
+	return history, nil
+}
+
+// parseIDs converts named numeric IDs; an empty history ID is 0, the latest
+// run
+func parseIDs(values map[string]string) (map[string]int, error) {
+	ids := make(map[string]int, len(values))
+	for name, value := range values {
+		value = strings.TrimSpace(value)
+		if value == "" && name == "history" {
+			ids[name] = 0
+			continue
+		}
+		id, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, invalidArgument(fmt.Sprintf("invalid %s ID %q", name, value))
+		}
+		ids[name] = id
+	}
+	return ids, nil
+}
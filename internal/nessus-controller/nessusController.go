@@ -2,40 +2,62 @@ package nessus
 
 import (
 	"NMB/internal/crash"
+	"NMB/internal/inventory"
 	"NMB/internal/logging"
+	"NMB/internal/nessusapi"
 	"NMB/internal/remote"
+	"NMB/internal/targets"
 	"bytes"
+	"context"
 	"crypto/tls"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
-	"log"
+	"net"
 	"net/http"
+	"net/url"
 	"os"
 	"path/filepath"
 	"regexp"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
 )
 
 type Nessus struct {
-	remote       *remote.RemoteExecutor
-	url          string
-	username     string
-	password     string
-	projectName  string
-	targetsList  string
-	excludeFile  []string
-	droneIP      string
-	aliveHosts   string
-	tokenKeys    map[string]string
-	tokenAuth    map[string]string
-	apiKeys      map[string]string
-	apiAuth      map[string]string
-	outputFolder string
-	stopRefresh  chan struct{}
-	mutex        sync.RWMutex
+	remote        *remote.RemoteExecutor
+	api           *nessusapi.Client
+	url           string
+	username      string
+	password      string
+	accessKey     string
+	secretKey     string
+	projectName   string
+	targetsList   string
+	excludeFile   []string
+	droneIP       string
+	topPorts      int
+	aliveHosts    string
+	tokenKeys     map[string]string
+	tokenAuth     map[string]string
+	apiKeys       map[string]string
+	apiAuth       map[string]string
+	outputFolder  string
+	policyFile    string
+	policy        string
+	scanner       string
+	folder        string
+	schedule      *Schedule
+	window        *Window
+	exportOptions ExportOptions
+	log           *logging.Loggers
+	mutex         sync.RWMutex
+
+	// refreshMu lets a single request renew an expired session while the
+	// others wait for it
+	refreshMu sync.Mutex
 }
 
 type Auth struct {
@@ -129,29 +151,23 @@ func (n *Nessus) getTokens() error {
 	return nil
 }
 
-func (n *Nessus) startTokenRefresh() {
-	n.stopRefresh = make(chan struct{})
-	go func() {
-		ticker := time.NewTicker(5 * time.Minute)
-		defer ticker.Stop()
-
-		for {
-			select {
-			case <-ticker.C:
-				if err := n.getTokens(); err != nil {
-					logging.ErrorLogger.Printf("Failed to refresh tokens: %v", err)
-					// Try to re-authenticate completely if token refresh fails
-					if err := n.authenticate(); err != nil {
-						logging.ErrorLogger.Printf("Failed to re-authenticate: %v", err)
-					}
-				} else {
-					logging.InfoLogger.Printf("Successfully refreshed authentication tokens")
-				}
-			case <-n.stopRefresh:
-				return
-			}
+// reauthorize renews the session after Nessus rejected a request with a
+// 401. When another request already renewed it since the rejected one was
+// sent, the new session is used as is.
+func (n *Nessus) reauthorize(rejected *http.Request) error {
+	n.refreshMu.Lock()
+	defer n.refreshMu.Unlock()
+
+	current, _ := http.NewRequest(http.MethodGet, n.url, nil)
+	n.authorize(current)
+	for _, header := range []string{"X-Cookie", "X-ApiKeys"} {
+		if current.Header.Get(header) != rejected.Header.Get(header) {
+			return nil
 		}
-	}()
+	}
+
+	n.log.Info.Printf("Nessus session expired, logging in again")
+	return n.authenticate()
 }
 
 var (
@@ -159,12 +175,6 @@ var (
 	ErrScanFailed   = fmt.Errorf("scan failed to complete")
 )
 
-func (n *Nessus) stopTokenRefresh() {
-	if n.stopRefresh != nil {
-		close(n.stopRefresh)
-	}
-}
-
 func (n *Nessus) getAPIKeys() error {
 	client := createInsecureClient()
 
@@ -197,141 +207,75 @@ func (n *Nessus) getAPIKeys() error {
 	return nil
 }
 
+// authenticate uses the configured API keys when there are any, and falls
+// back to logging in and scraping the web UI's API token otherwise
 func (n *Nessus) authenticate() error {
-	logging.InfoLogger.Printf("Retrieving API tokens")
+	if n.accessKey != "" && n.secretKey != "" {
+		err := n.useAPIKeys()
+		if err == nil {
+			return nil
+		}
+		if n.username == "" || n.password == "" {
+			return err
+		}
+		n.log.Warning.Printf("%v, falling back to a session login", err)
+	}
+
+	n.log.Info.Printf("Retrieving API tokens")
 
 	// Get tokens (cookie token and API token)
 	if err := n.getTokens(); err != nil {
-		logging.ErrorLogger.Printf("Failed to retrieve API tokens - check your login credentials")
+		n.log.Error.Printf("Failed to retrieve API tokens - check your login credentials")
 		return err
 	}
 
 	// Get API keys
 	if err := n.getAPIKeys(); err != nil {
-		logging.ErrorLogger.Printf("Failed to retrieve API keys - check your login credentials")
+		n.log.Error.Printf("Failed to retrieve API keys - check your login credentials")
 		return err
 	}
 
-	// Start token refresh routine
-	n.startTokenRefresh()
-
-	logging.SuccessLogger.Printf("API tokens retrieved successfully")
+	n.log.Success.Printf("API tokens retrieved successfully")
 	return nil
 }
 
-// Add this exported method to your nessus package
+// useAPIKeys authenticates every request with X-ApiKeys and checks the keys
+// against the session endpoint
+func (n *Nessus) useAPIKeys() error {
+	credentials := Credentials{AccessKey: n.accessKey, SecretKey: n.secretKey}
+
+	n.mutex.Lock()
+	n.tokenAuth = nil
+	n.apiAuth = map[string]string{"X-ApiKeys": credentials.apiKeysHeader()}
+	n.mutex.Unlock()
+
+	// Check with a client that does not renew the session, as this may be
+	// the renewal
+	session, err := nessusapi.NewClient(n.url, createInsecureClient(), n.authorize).Session()
+	if err != nil {
+		n.mutex.Lock()
+		n.apiAuth = nil
+		n.mutex.Unlock()
+		return fmt.Errorf("API key authentication failed: %w", err)
+	}
 
+	n.log.Success.Printf("Authenticated with API keys as %s", session.Username)
+	return nil
+}
+
+// GetScanFindings returns a scan's finding counts by severity name
 func (n *Nessus) GetScanFindings(scanID string) (map[string]int, error) {
 	var findings map[string]int
 
 	err := n.safeExecute("GetScanFindings", func() error {
-		var err error
-		findings = make(map[string]int)
-
-		// First get the scan details
-		resp, err := n.makeRequest(http.MethodGet, "/scans/"+scanID, nil)
+		details, err := n.GetScanDetails(scanID)
 		if err != nil {
-			return fmt.Errorf("failed to get scan details: %v", err)
-		}
-		defer resp.Body.Close()
-
-		var scanDetails map[string]interface{}
-		if err := json.NewDecoder(resp.Body).Decode(&scanDetails); err != nil {
-			return fmt.Errorf("failed to decode scan details: %v", err)
-		}
-
-		// Extract the vulnerabilities counts if available
-		info, ok := scanDetails["info"].(map[string]interface{})
-		if !ok {
-			return nil // Return empty findings if no info section
-		}
-
-		// Try to get severity counts from different possible locations in the response
-
-		// First try the vulnerabilities counts
-		if vulns, ok := info["vulnerabilities"].([]interface{}); ok {
-			for _, v := range vulns {
-				if vuln, ok := v.(map[string]interface{}); ok {
-					severity, _ := vuln["severity"].(float64)
-					count, _ := vuln["count"].(float64)
-
-					switch int(severity) {
-					case 4:
-						findings["critical"] += int(count)
-					case 3:
-						findings["high"] += int(count)
-					case 2:
-						findings["medium"] += int(count)
-					case 1:
-						findings["low"] += int(count)
-					case 0:
-						findings["info"] += int(count)
-					}
-				}
-			}
-
-			return nil
-		}
-
-		// If vulnerabilities counts not found, try the severities counts
-		sevs, ok := info["severities"].([]interface{})
-		if ok {
-			for _, s := range sevs {
-				if sev, ok := s.(map[string]interface{}); ok {
-					severity, _ := sev["id"].(float64)
-					count, _ := sev["count"].(float64)
-
-					switch int(severity) {
-					case 4:
-						findings["critical"] = int(count)
-					case 3:
-						findings["high"] = int(count)
-					case 2:
-						findings["medium"] = int(count)
-					case 1:
-						findings["low"] = int(count)
-					case 0:
-						findings["info"] = int(count)
-					}
-				}
-			}
-
-			return nil
-		}
-
-		// Last resort: try to get the total vulnerability counts
-		if hostCount, ok := info["hostcount"].(float64); ok && hostCount > 0 {
-			if counts, ok := info["counts"].(map[string]interface{}); ok {
-				if vulnsBySev, ok := counts["vulnerabilities"].(map[string]interface{}); ok {
-					// Extract by severity
-					criticalCount, _ := vulnsBySev["critical"].(float64)
-					highCount, _ := vulnsBySev["high"].(float64)
-					mediumCount, _ := vulnsBySev["medium"].(float64)
-					lowCount, _ := vulnsBySev["low"].(float64)
-					infoCount, _ := vulnsBySev["info"].(float64)
-
-					findings["critical"] = int(criticalCount)
-					findings["high"] = int(highCount)
-					findings["medium"] = int(mediumCount)
-					findings["low"] = int(lowCount)
-					findings["info"] = int(infoCount)
-
-					return nil
-				}
-			}
+			return err
 		}
-
-		// If we couldn't get real findings, set defaults to prevent UI issues
-		findings["critical"] = 0
-		findings["high"] = 0
-		findings["medium"] = 0
-		findings["low"] = 0
-		findings["info"] = 0
-
+		findings = details.SeverityCounts()
 		return nil
 	})
 
-	// If err is nil, return the findings, otherwise return an error
 	return findings, err
 }
 
@@ -340,199 +284,85 @@ func (n *Nessus) MakeRequest(method, endpoint string, body []byte) (*http.Respon
 }
 
 // GetScans returns a list of all scans from the Nessus API
-func (n *Nessus) GetScans() ([]map[string]interface{}, error) {
-	resp, err := n.makeRequest(http.MethodGet, "/scans", nil)
+func (n *Nessus) GetScans() ([]nessusapi.Scan, error) {
+	list, err := n.api.ListScans()
 	if err != nil {
-		return nil, fmt.Errorf("failed to get scans: %v", err)
+		return nil, fmt.Errorf("failed to get scans: %w", err)
 	}
-	defer resp.Body.Close()
-
-	var result map[string]interface{}
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return nil, fmt.Errorf("failed to decode response: %v", err)
-	}
-
-	scans, ok := result["scans"].([]interface{})
-	if !ok {
-		return []map[string]interface{}{}, nil
-	}
-
-	var scansList []map[string]interface{}
-	for _, scan := range scans {
-		if scanMap, ok := scan.(map[string]interface{}); ok {
-			scansList = append(scansList, scanMap)
-		}
-	}
-
-	return scansList, nil
+	return list.Scans, nil
 }
 
 // GetScanDetails returns detailed information about a specific scan
-func (n *Nessus) GetScanDetails(scanID string) (map[string]interface{}, error) {
-	resp, err := n.makeRequest(http.MethodGet, "/scans/"+scanID, nil)
+func (n *Nessus) GetScanDetails(scanID string) (*nessusapi.ScanDetails, error) {
+	id, err := parseScanID(scanID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get scan details: %v", err)
+		return nil, err
 	}
-	defer resp.Body.Close()
 
-	var result map[string]interface{}
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return nil, fmt.Errorf("failed to decode response: %v", err)
+	details, err := n.api.ScanDetails(id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get scan details: %w", err)
 	}
-
-	return result, nil
+	return details, nil
 }
 
 // ExecuteScanAction performs an action (start, stop, pause, resume) on a scan
 func (n *Nessus) ExecuteScanAction(scanID, action string) error {
-	endpoint := fmt.Sprintf("/scans/%s/%s", scanID, action)
-	resp, err := n.makeRequest(http.MethodPost, endpoint, nil)
+	id, err := parseScanID(scanID)
 	if err != nil {
-		return fmt.Errorf("failed to execute scan action: %v", err)
+		return err
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode >= 400 {
-		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("failed to %s scan: %s - %s", action, resp.Status, string(body))
+	if err := n.api.ScanAction(id, action); err != nil {
+		return fmt.Errorf("failed to %s scan: %w", action, err)
 	}
-
 	return nil
 }
 
 // DeleteScan deletes a scan
 func (n *Nessus) DeleteScan(scanID string) error {
-	resp, err := n.makeRequest(http.MethodDelete, "/scans/"+scanID, nil)
+	id, err := parseScanID(scanID)
 	if err != nil {
-		return fmt.Errorf("failed to delete scan: %v", err)
+		return err
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode >= 400 {
-		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("failed to delete scan: %s - %s", resp.Status, string(body))
+	if err := n.api.DeleteScan(id); err != nil {
+		return fmt.Errorf("failed to delete scan: %w", err)
 	}
-
 	return nil
 }
 
-// ExportScanByID exports a specific scan by ID
-func (n *Nessus) ExportScanByID(scanID string) error {
-	// Get scan details to check status
-	resp, err := n.makeRequest(http.MethodGet, "/scans/"+scanID, nil)
-	if err != nil {
-		return fmt.Errorf("failed to get scan details: %v", err)
-	}
+// invalidArgument is an error about a malformed ID, which matches
+// nessusapi.ErrInvalidArgument
+type invalidArgument string
 
-	var scanDetails map[string]interface{}
-	if err := json.NewDecoder(resp.Body).Decode(&scanDetails); err != nil {
-		resp.Body.Close()
-		return fmt.Errorf("failed to decode scan details: %v", err)
-	}
-	resp.Body.Close()
-
-	// Extract scan name and status
-	info, ok := scanDetails["info"].(map[string]interface{})
-	if !ok {
-		return fmt.Errorf("invalid scan details format")
-	}
-
-	scanName, _ := info["name"].(string)
-	if scanName == "" {
-		scanName = fmt.Sprintf("scan_%s", scanID)
-	}
-
-	status, _ := info["status"].(string)
-	if status == "running" || status == "pending" {
-		logging.InfoLogger.Printf("Scan still running, will monitor until completion")
-		// Wait for scan to complete
-		n.projectName = scanName // Set project name so monitorScan can find it
-		if err := n.monitorScan(); err != nil {
-			return fmt.Errorf("monitoring scan failed: %v", err)
-		}
-	}
+func (e invalidArgument) Error() string {
+	return string(e)
+}
 
-	// Create an evidence folder based on the scan name
-	evidenceFolder := filepath.Join(n.outputFolder, "evidence", scanName)
-	if err := os.MkdirAll(evidenceFolder, 0755); err != nil {
-		return fmt.Errorf("failed to create evidence folder: %v", err)
-	}
-	logging.InfoLogger.Printf("Created evidence folder: %s", evidenceFolder)
+func (e invalidArgument) Is(target error) bool {
+	return target == nessusapi.ErrInvalidArgument
+}
 
-	templateID, err := n.getHTMLTemplateID()
+func parseScanID(scanID string) (int, error) {
+	id, err := strconv.Atoi(strings.TrimSpace(scanID))
 	if err != nil {
-		return fmt.Errorf("failed to get HTML template ID: %v", err)
-	}
-
-	formats := map[string]ExportFormat{
-		"csv": {
-			Format: "csv",
-			ReportContents: map[string]interface{}{
-				"csvColumns": map[string]bool{
-					"id": true, "cve": true, "cvss": true,
-					"risk": true, "hostname": true, "protocol": true,
-					"port": true, "plugin_name": true, "synopsis": true,
-					"description": true, "solution": true, "see_also": true,
-					"plugin_output": true, "stig_severity": true,
-					"cvss3_base_score": true, "cvss_temporal_score": true,
-					"cvss3_temporal_score": true, "risk_factor": true,
-					"references": true, "plugin_information": true,
-					"exploitable_with": true,
-				},
-			},
-			ExtraFilters: map[string]interface{}{
-				"host_ids":   []int{},
-				"plugin_ids": []int{},
-			},
-		},
-		"nessus": {
-			Format: "nessus",
-		},
-		"html": {
-			Format:     "html",
-			TemplateID: templateID,
-			ExtraFilters: map[string]interface{}{
-				"host_ids":   []int{},
-				"plugin_ids": []int{},
-			},
-		},
+		return 0, invalidArgument(fmt.Sprintf("invalid scan ID %q", scanID))
 	}
+	return id, nil
+}
 
-	for format, config := range formats {
-		logging.InfoLogger.Printf("Exporting %s file...", format)
-
-		exportJSON, err := json.Marshal(config)
-		if err != nil {
-			return fmt.Errorf("failed to serialize export config: %v", err)
-		}
-
-		exportResp, err := n.makeRequest(http.MethodPost, fmt.Sprintf("/scans/%s/export", scanID), exportJSON)
-		if err != nil {
-			return fmt.Errorf("export request failed: %v", err)
-		}
-
-		defer exportResp.Body.Close()
-		var result struct {
-			Token string `json:"token"`
-		}
-		if err := json.NewDecoder(exportResp.Body).Decode(&result); err != nil {
-			return fmt.Errorf("failed to decode export token: %v", err)
-		}
-
-		if result.Token == "" {
-			return fmt.Errorf("no export token received for %s format", format)
-		}
-
-		// Create the output file path within the evidence folder
-		outputFile := filepath.Join(evidenceFolder, fmt.Sprintf("%s.%s", scanName, format))
-		if err := n.waitForDownload(result.Token, outputFile); err != nil {
-			return fmt.Errorf("failed to download %s file: %v", format, err)
-		}
+// authorize adds the current session and API key headers to a request
+func (n *Nessus) authorize(req *http.Request) {
+	n.mutex.RLock()
+	defer n.mutex.RUnlock()
 
-		logging.SuccessLogger.Printf("Exported %s file to %q", format, outputFile)
+	for k, v := range n.tokenAuth {
+		req.Header.Set(k, v)
+	}
+	for k, v := range n.apiAuth {
+		req.Header.Set(k, v)
 	}
-
-	return nil
 }
 
 // Helper function to make authenticated requests with thread-safe token access
@@ -545,81 +375,164 @@ func (n *Nessus) makeRequest(method, endpoint string, body []byte) (*http.Respon
 	}
 
 	req.Header.Set("Content-Type", "application/json")
-
-	// Thread-safe reading of auth headers
-	n.mutex.RLock()
-	for k, v := range n.tokenAuth {
-		req.Header.Set(k, v)
-	}
-	for k, v := range n.apiAuth {
-		req.Header.Set(k, v)
-	}
-	n.mutex.RUnlock()
+	n.authorize(req)
 
 	return client.Do(req)
 }
 
-// Clean up resources when done
+// Close closes the SSH connection, if there is one
 func (n *Nessus) Close() {
-	n.stopTokenRefresh()
+	if n.remote != nil {
+		n.remote.Close()
+	}
+}
+
+// Options configures a controller. Policy, Scanner and Folder select what a
+// new scan uses, by name or ID; PolicyFile is a .nessus policy to import and
+// use instead of an existing policy.
+type Options struct {
+	// URL is the Nessus base URL, https://<Host>:8834 by default. Host is
+	// also the SSH host; without one no SSH connection is made and the
+	// drone IP lookup and the discovery scan are skipped.
+	URL string
+
+	Host         string
+	Username     string
+	Password     string
+	ProjectName  string
+	TargetsFile  string
+	ExcludeFiles []string
+	Discovery    bool
+
+	// TopPorts also scans the top N TCP ports during discovery
+	TopPorts int
+
+	// AccessKey and SecretKey authenticate API requests instead of a web
+	// session; KeyFile is an SSH private key used alongside or instead of
+	// the password
+	AccessKey string
+	SecretKey string
+	KeyFile   string
+
+	PolicyFile string
+	Policy     string
+	Scanner    string
+	Folder     string
+
+	// Schedule has Nessus launch new scans at a set time instead of on
+	// demand; Window pauses monitored scans outside the allowed hours
+	Schedule *Schedule
+	Window   *Window
+
+	// Export selects the exported formats and filters; OutputDir is where
+	// the evidence folder is created, next to the binary by default
+	Export    ExportOptions
+	OutputDir string
+
+	// Log is where the controller logs, the global loggers by default
+	Log *logging.Loggers
 }
 
 func New(host, username, password, projectName, targetsFile string, excludeFile []string, discovery bool) (*Nessus, error) {
+	return NewWithOptions(Options{
+		Host:         host,
+		Username:     username,
+		Password:     password,
+		ProjectName:  projectName,
+		TargetsFile:  targetsFile,
+		ExcludeFiles: excludeFile,
+		Discovery:    discovery,
+	})
+}
+
+func NewWithOptions(opts Options) (*Nessus, error) {
 	reporter := crash.NewReporter("crash_reports")
 
 	// Extra information for crash reports
 	extra := map[string]string{
-		"host":        host,
-		"username":    username,
-		"projectName": projectName,
-		"targetsFile": targetsFile,
+		"host":        opts.Host,
+		"username":    opts.Username,
+		"projectName": opts.ProjectName,
+		"targetsFile": opts.TargetsFile,
 	}
 
 	// Recover from panics during initialization
 	defer reporter.RecoverWithCrashReport("NessusInitialization", extra)
 
-	remote, err := remote.NewRemoteExecutor(host, username, password, "")
+	baseURL, err := nessusURL(opts.URL, opts.Host)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create remote executor: %v", err)
+		return nil, err
 	}
 
 	n := &Nessus{
-		remote:       remote,
-		url:          fmt.Sprintf("https://%s:8834", host),
-		username:     username,
-		password:     password,
-		projectName:  projectName,
-		excludeFile:  excludeFile,
-		outputFolder: filepath.Dir(os.Args[0]),
+		url:           baseURL,
+		username:      opts.Username,
+		password:      opts.Password,
+		accessKey:     opts.AccessKey,
+		secretKey:     opts.SecretKey,
+		projectName:   opts.ProjectName,
+		excludeFile:   opts.ExcludeFiles,
+		topPorts:      opts.TopPorts,
+		outputFolder:  filepath.Dir(os.Args[0]),
+		policyFile:    opts.PolicyFile,
+		policy:        opts.Policy,
+		scanner:       opts.Scanner,
+		folder:        opts.Folder,
+		schedule:      opts.Schedule,
+		window:        opts.Window,
+		exportOptions: opts.Export,
+		log:           opts.Log,
+	}
+	if n.log == nil {
+		n.log = logging.ForJob("")
+	}
+	n.api = nessusapi.NewClient(n.url, createInsecureClient(), n.authorize)
+	n.api.Reauthorize = n.reauthorize
+	if opts.Host != "" {
+		n.remote, err = remote.NewRemoteExecutor(opts.Host, opts.Username, opts.Password, opts.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create remote executor: %v", err)
+		}
+	}
+	if opts.OutputDir != "" {
+		n.outputFolder = opts.OutputDir
+	}
+	if err := opts.Export.Validate(); err != nil {
+		return nil, err
 	}
 
 	// Process targets file
-	if targetsFile != "" {
-		content, err := os.ReadFile(targetsFile)
+	if targetsFile := opts.TargetsFile; targetsFile != "" {
+		list, err := targets.ParseFile(targetsFile)
 		if err != nil {
-			return nil, fmt.Errorf("failed to read targets file: %v", err)
+			return nil, err
 		}
-		// Split on newlines and remove empty lines
-		targets := []string{}
-		for _, line := range strings.Split(strings.TrimSpace(string(content)), "\n") {
-			if trimmed := strings.TrimSpace(line); trimmed != "" {
-				targets = append(targets, trimmed)
-			}
+		if list.Empty() {
+			return nil, fmt.Errorf("targets file %s has no targets", targetsFile)
 		}
-		n.targetsList = strings.Join(targets, ",")
-		logging.InfoLogger.Printf("Loaded targets from file: %s", n.targetsList)
-	}
-
-	n.droneIP, err = n.getDroneIP()
-	if err != nil {
-		return nil, err
+		n.targetsList = list.String()
+		n.log.Info.Printf("Loaded targets from file: %s", list.Summary())
 	}
 
-	if discovery {
-		n.aliveHosts, err = n.discoveryScan()
+	if n.remote != nil {
+		n.droneIP, err = n.getDroneIP()
 		if err != nil {
 			return nil, err
 		}
+	} else {
+		n.log.Info.Printf("No SSH host configured, connecting to %s over HTTPS only", n.url)
+	}
+
+	if opts.Discovery {
+		if n.remote == nil {
+			n.log.Warning.Printf("Skipping discovery scan, it runs nmap over SSH on the Nessus host (-remote)")
+		} else {
+			inv, err := n.discoveryScan()
+			if err != nil {
+				return nil, err
+			}
+			n.aliveHosts = strings.Join(inv.Addresses(), ",")
+		}
 	}
 
 	// Get authentication
@@ -630,242 +543,188 @@ func New(host, username, password, projectName, targetsFile string, excludeFile
 	return n, nil
 }
 
-// Monitor scan progress
+// monitorScan watches the project's scan until it finishes, logging its
+// progress and keeping it inside the scan window
 func (n *Nessus) monitorScan() error {
-	logging.InfoLogger.Printf("Monitoring scan progress...")
+	n.log.Info.Printf("Monitoring scan progress...")
 
-	for {
-		scan := n.getScanInfo()
-		if scan == nil {
-			return fmt.Errorf("scan not found")
-		}
-
-		status := fmt.Sprintf("%v", scan["status"])
+	scan := n.getScanInfo()
+	if scan == nil {
+		return fmt.Errorf("scan not found")
+	}
+	return n.watchScan(scan.ID, true)
+}
 
-		switch status {
-		case "completed":
-			logging.InfoLogger.Printf("Scan completed successfully")
-			return nil
-		case "canceled":
-			logging.InfoLogger.Printf("Scan was canceled by user or system")
-			return ErrScanCanceled
-		case "failed":
-			logging.ErrorLogger.Printf("Scan failed to complete")
-			return ErrScanFailed
-		case "running", "paused":
-			progress, ok := scan["progress"].(float64)
-			if ok {
-				logging.InfoLogger.Printf("Scan progress: %.0f%%", progress)
+// watchScan waits for a scan to finish, logging its progress. The scan
+// window is only enforced for the project's own scan.
+func (n *Nessus) watchScan(scanID int, enforceWindow bool) error {
+	watcher := n.NewWatcher(scanID)
+	watcher.Subscribe(logSubscriber{log: n.log})
+	if enforceWindow {
+		watcher.Subscribe(SubscriberFunc(func(e Event) {
+			if e.Type == EventProgress {
+				n.enforceWindow(e.Status)
 			}
-			time.Sleep(30 * time.Second)
-		default:
-			logging.InfoLogger.Printf("Scan status: %s", status)
-			time.Sleep(30 * time.Second)
-		}
+		}))
+	}
+
+	details, err := watcher.Watch(context.Background())
+	if err != nil {
+		return err
+	}
+
+	switch details.Info.Status {
+	case "completed":
+		n.log.Info.Printf("Scan completed successfully")
+		return nil
+	case "failed":
+		n.log.Error.Printf("Scan failed to complete")
+		return ErrScanFailed
+	default:
+		n.log.Info.Printf("Scan was canceled by user or system")
+		return ErrScanCanceled
 	}
 }
 
 func (n *Nessus) createScan(launch bool) error {
-	logging.InfoLogger.Printf("Creating new scan")
+	n.log.Info.Printf("Creating new scan")
 
 	// Check if scan already exists
 	if scan := n.getScanInfo(); scan != nil {
-		logging.InfoLogger.Printf("Scan already exists, aborting scan creation")
+		n.log.Info.Printf("Scan already exists, aborting scan creation")
 		return fmt.Errorf("scan name already exists")
 	}
 
-	// Get policy ID
-	policies, err := n.getPolicies()
+	// Get policy ID, importing the policy file first if one was given
+	policy, err := n.resolvePolicy()
 	if err != nil {
-		logging.ErrorLogger.Printf("Failed to get policies: %v", err)
+		n.log.Error.Printf("Failed to select policy: %v", err)
 		return err
 	}
 
-	var policyID string
-	var templateUUID string
-	for _, policy := range policies {
-		if policy["name"] == "Default Good Model Nessus Vulnerability Policy" {
-			switch v := policy["id"].(type) {
-			case string:
-				policyID = v
-			case float64:
-				policyID = fmt.Sprintf("%v", v)
-			default:
-				return fmt.Errorf("unexpected type for policy ID: %T", v)
-			}
-			// Get the template UUID from the policy
-			templateUUID = policy["template_uuid"].(string)
-			break
-		}
+	scannerID, err := n.resolveScanner()
+	if err != nil {
+		n.log.Error.Printf("Failed to select scanner: %v", err)
+		return err
 	}
 
-	if policyID == "" {
-		logging.ErrorLogger.Printf("Policy not found")
-		return fmt.Errorf("policy not found")
+	folderID, err := n.resolveFolder()
+	if err != nil {
+		n.log.Error.Printf("Failed to select folder: %v", err)
+		return err
 	}
 
 	// Use targetsList if no alive hosts (discovery scan wasn't run)
-	targets := n.aliveHosts
-	if targets == "" {
-		targets = n.targetsList
+	scanTargets := n.aliveHosts
+	if scanTargets == "" {
+		scanTargets = n.targetsList
 	}
 
-	if targets == "" {
+	if scanTargets == "" {
 		return fmt.Errorf("no targets specified")
 	}
 
-	logging.InfoLogger.Printf("Using targets: %s", targets)
-
-	// Create scan data following Nessus documentation
-	scanData := map[string]interface{}{
-		"uuid": templateUUID,
-		"settings": map[string]interface{}{
-			"name":           n.projectName,
-			"policy_id":      policyID,
-			"enabled":        true,
-			"launch":         "ON_DEMAND",
-			"scanner_id":     "1",
-			"folder_id":      3,
-			"text_targets":   targets,
-			"description":    "No host Discovery\nAll TCP port\nAll Service Discovery\nDefault passwords being tested\nGeneric Web Test\nNo compliance or local Check\nNo DOS plugins\n",
-			"agent_group_id": []string{},
-		},
+	list, err := targets.ParseString(scanTargets)
+	if err != nil {
+		return err
 	}
+	n.log.Info.Printf("Scan targets: %s", list.Summary())
+	n.log.Info.Printf("Using targets: %s", scanTargets)
 
-	// If launch is true, we'll start the scan immediately
-	if launch {
-		scanData["settings"].(map[string]interface{})["launch_now"] = true
+	// Create scan settings following Nessus documentation
+	settings := map[string]interface{}{
+		"name":           n.projectName,
+		"policy_id":      policy.ID,
+		"enabled":        true,
+		"launch":         "ON_DEMAND",
+		"scanner_id":     scannerID,
+		"folder_id":      folderID,
+		"text_targets":   scanTargets,
+		"description":    "No host Discovery\nAll TCP port\nAll Service Discovery\nDefault passwords being tested\nGeneric Web Test\nNo compliance or local Check\nNo DOS plugins\n",
+		"agent_group_id": []string{},
 	}
 
-	// Debug print scan data
-	scanDataJSON, err := json.Marshal(scanData)
-	if err != nil {
-		logging.ErrorLogger.Printf("Failed to marshal scan data: %v", err)
-		return err
+	// A schedule replaces launching on demand; otherwise if launch is true,
+	// we'll start the scan immediately
+	if n.schedule != nil {
+		n.schedule.apply(settings)
+		n.log.Info.Printf("Scan scheduled for %s (%s)", n.schedule.Start.Format(scheduleLayout), n.schedule.RRules)
+	} else if launch {
+		settings["launch_now"] = true
 	}
-	logging.InfoLogger.Printf("Scan data JSON: %s", string(scanDataJSON))
 
-	// Make the request
-	resp, err := n.makeRequest(http.MethodPost, "/scans", scanDataJSON)
-	if err != nil {
-		logging.ErrorLogger.Printf("Failed to send HTTP request: %v", err)
-		return err
+	// Debug print scan settings
+	if settingsJSON, err := json.Marshal(settings); err == nil {
+		n.log.Info.Printf("Scan settings JSON: %s", string(settingsJSON))
 	}
-	defer resp.Body.Close()
 
-	// Log response status code and body
-	logging.InfoLogger.Printf("Received response: %s", resp.Status)
-
-	// If response is not 200, read and log the error body
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		logging.ErrorLogger.Printf("Failed to create scan. Status code: %d, Response body: %s", resp.StatusCode, string(body))
-		return fmt.Errorf("failed to create scan: %s - %s", resp.Status, string(body))
+	scan, err := n.api.CreateScan(policy.TemplateUUID, settings)
+	if err != nil {
+		n.log.Error.Printf("Failed to create scan: %v", err)
+		return fmt.Errorf("failed to create scan: %w", err)
 	}
 
-	logging.InfoLogger.Printf("Scan created successfully")
+	n.log.Info.Printf("Scan created successfully (ID %d)", scan.ID)
 	return nil
 }
 
+// excludeTargets removes the exclude files' targets from the discovered hosts,
+// or from the target list when discovery was not run. Exclusions are CIDR and
+// range aware, so excluding 10.0.0.0/28 removes 10.0.0.5 from 10.0.0.0/24.
 func (n *Nessus) excludeTargets() error {
 	if len(n.excludeFile) == 0 {
 		return nil
 	}
 
-	var excludeHosts []string
-	for _, file := range n.excludeFile {
-		content, err := os.ReadFile(file)
-		if err != nil {
-			return fmt.Errorf("failed to read exclude file %s: %v", file, err)
-		}
-		hosts := strings.Split(strings.TrimSpace(string(content)), "\n")
-		excludeHosts = append(excludeHosts, hosts...)
+	current := n.aliveHosts
+	if current == "" {
+		current = n.targetsList
 	}
 
-	excludeMap := make(map[string]bool)
-	for _, host := range excludeHosts {
-		excludeMap[strings.TrimSpace(host)] = true
+	list, err := targets.ParseString(current)
+	if err != nil {
+		return err
 	}
+	before := list.Addresses() + uint64(len(list.Hostnames()))
 
-	// Filter out excluded hosts from aliveHosts
-	var filteredHosts []string
-	for _, host := range strings.Split(n.aliveHosts, ",") {
-		if !excludeMap[strings.TrimSpace(host)] {
-			filteredHosts = append(filteredHosts, host)
+	for _, file := range n.excludeFile {
+		exclude, err := targets.ParseFile(file)
+		if err != nil {
+			return fmt.Errorf("failed to read exclude file %s: %w", file, err)
 		}
+		list = list.Subtract(exclude)
 	}
 
-	if len(filteredHosts) == 0 {
+	if list.Empty() {
 		return fmt.Errorf("no targets remaining after exclusion")
 	}
 
-	n.aliveHosts = strings.Join(filteredHosts, ",")
-	return nil
-}
+	after := list.Addresses() + uint64(len(list.Hostnames()))
+	n.log.Info.Printf("Excluded %d targets, %s remaining", before-after, list.Summary())
 
-func (n *Nessus) getScanInfo() map[string]interface{} {
-	resp, err := n.makeRequest(http.MethodGet, "/scans", nil)
-	if err != nil {
-		logging.ErrorLogger.Printf("Failed to get scans: %v", err)
-		return nil
-	}
-	defer resp.Body.Close()
-
-	var result map[string]interface{}
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		logging.ErrorLogger.Printf("Failed to decode response: %v", err)
-		return nil
+	if n.aliveHosts != "" {
+		n.aliveHosts = list.String()
+	} else {
+		n.targetsList = list.String()
 	}
-
-	scans, ok := result["scans"].([]interface{})
-	if !ok {
-		return nil
-	}
-
-	for _, scan := range scans {
-		scanMap, ok := scan.(map[string]interface{})
-		if !ok {
-			continue
-		}
-		if scanMap["name"] == n.projectName {
-			return scanMap
-		}
-	}
-
 	return nil
 }
 
-func (n *Nessus) getPolicies() ([]map[string]interface{}, error) {
-	resp, err := n.makeRequest(http.MethodGet, "/policies", nil)
+// getScanInfo returns the project's scan, or nil when it does not exist
+func (n *Nessus) getScanInfo() *nessusapi.Scan {
+	list, err := n.api.ListScans()
 	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-
-	var result map[string]interface{}
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return nil, err
-	}
-
-	// Extract policies from the response
-	policies, ok := result["policies"].([]interface{})
-	if !ok {
-		prettyJSON, err := json.MarshalIndent(result, "", "    ")
-		if err != nil {
-			return nil, fmt.Errorf("failed to marshal response: %v", err)
-		}
-		log.Printf("Invalid policies response:\n%s", string(prettyJSON))
-		return nil, fmt.Errorf("invalid policies response")
+		n.log.Error.Printf("Failed to get scans: %v", err)
+		return nil
 	}
 
-	var policyList []map[string]interface{}
-	for _, policy := range policies {
-		if policyMap, ok := policy.(map[string]interface{}); ok {
-			policyList = append(policyList, policyMap)
+	for i := range list.Scans {
+		if list.Scans[i].Name == n.projectName {
+			return &list.Scans[i]
 		}
 	}
 
-	return policyList, nil
+	return nil
 }
 
 func (n *Nessus) scanAction(action string) error {
@@ -874,31 +733,14 @@ func (n *Nessus) scanAction(action string) error {
 		return fmt.Errorf("scan not found")
 	}
 
-	scanID := fmt.Sprintf("%v", scan["id"])
-	endpoint := fmt.Sprintf("/scans/%s/%s", scanID, action)
-
-	resp, err := n.makeRequest(http.MethodPost, endpoint, nil)
-	if err != nil {
-		return err
+	if err := n.api.ScanAction(scan.ID, action); err != nil {
+		return fmt.Errorf("failed to %s scan: %w", action, err)
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("failed to %s scan: %s", action, resp.Status)
-	}
-
-	logging.InfoLogger.Printf("Scan %s successful", action)
+	n.log.Info.Printf("Scan %s successful", action)
 	return nil
 }
 
-type ExportFormat struct {
-	Format            string                 `json:"format"`
-	TemplateID        string                 `json:"template_id,omitempty"`
-	ReportContents    map[string]interface{} `json:"reportContents,omitempty"`
-	ExtraFilters      map[string]interface{} `json:"extraFilters,omitempty"`
-	FormattingOptions map[string]interface{} `json:"formattingOptions,omitempty"`
-}
-
 func (n *Nessus) waitForDownload(token, outputFile string) error {
 	startTime := time.Now()
 	maxDuration := 10 * time.Minute // Maximum wait time
@@ -910,170 +752,55 @@ func (n *Nessus) waitForDownload(token, outputFile string) error {
 			return fmt.Errorf("timed out after %v while waiting for file to be ready", maxDuration)
 		}
 
-		resp, err := n.makeRequest(http.MethodGet, fmt.Sprintf("/tokens/%s/download", token), nil)
-		if err != nil {
-			return fmt.Errorf("error checking download status: %v", err)
-		}
-		defer resp.Body.Close()
-
-		body, err := io.ReadAll(resp.Body)
-		if err != nil {
-			return fmt.Errorf("failed to read response body: %v", err)
-		}
-
-		// Check for readiness in both status code and response body
-		if resp.StatusCode == http.StatusOK && !strings.Contains(string(body), "not ready") {
-			// File is genuinely ready, download it
-			out, err := os.Create(outputFile)
-			if err != nil {
-				return fmt.Errorf("failed to create output file: %v", err)
-			}
-			_, err = io.Copy(out, bytes.NewReader(body)) // Use the already-read body
-			out.Close()
-			if err != nil {
+		data, err := n.api.Download(token)
+		var apiErr *nessusapi.APIError
+		switch {
+		case err == nil:
+			if err := os.WriteFile(outputFile, data, 0644); err != nil {
 				return fmt.Errorf("failed to write downloaded file: %v", err)
 			}
-
-			logging.InfoLogger.Printf("File downloaded successfully: %s", outputFile)
+			n.log.Info.Printf("File downloaded successfully: %s", outputFile)
 			return nil
+		case errors.Is(err, nessusapi.ErrNotReady),
+			errors.As(err, &apiErr) && apiErr.StatusCode >= http.StatusInternalServerError:
+			// Still being generated, or Nessus is busy; 4xx errors will not
+			// go away by waiting
+			time.Sleep(5 * time.Second)
+		default:
+			return fmt.Errorf("error checking download status: %v", err)
 		}
-
-		// Handle "not ready" response or other cases
-		time.Sleep(5 * time.Second)
 	}
 }
 
-func (n *Nessus) exportScan() (string, error) {
-	logging.InfoLogger.Printf("Exporting scan results...")
-
-	scanInfo := n.getScanInfo()
-	if scanInfo == nil {
-		return "", fmt.Errorf("scan not found")
-	}
-	scanID := fmt.Sprintf("%v", scanInfo["id"])
-	scanName := fmt.Sprintf("%v", scanInfo["name"])
-	status := fmt.Sprintf("%v", scanInfo["status"])
+// defaultNessusPort is the port Nessus serves its web UI and API on
+const defaultNessusPort = "8834"
 
-	if status == "running" || status == "pending" {
-		logging.ErrorLogger.Printf("Scan still running, waiting for it to finish...")
-		if err := n.monitorScan(); err != nil {
-			return "", fmt.Errorf("monitoring scan failed: %v", err)
+// nessusURL returns the Nessus base URL. A URL without a scheme is taken as
+// host[:port] on the default port; without a URL the host is used.
+func nessusURL(rawURL, host string) (string, error) {
+	if rawURL == "" {
+		if host == "" {
+			return "", fmt.Errorf("a Nessus URL or host is required")
 		}
+		return "https://" + net.JoinHostPort(host, defaultNessusPort), nil
 	}
 
-	// Create an evidence folder based on the scan name
-	evidenceFolder := filepath.Join(n.outputFolder, "evidence", scanName)
-	if err := os.MkdirAll(evidenceFolder, 0755); err != nil {
-		return "", fmt.Errorf("failed to create evidence folder: %v", err)
-	}
-	logging.InfoLogger.Printf("Created evidence folder: %s", evidenceFolder)
-
-	templateID, err := n.getHTMLTemplateID()
-	if err != nil {
-		return "", fmt.Errorf("failed to get HTML template ID: %v", err)
-	}
-
-	formats := map[string]ExportFormat{
-		"csv": {
-			Format: "csv",
-			ReportContents: map[string]interface{}{
-				"csvColumns": map[string]bool{
-					"id": true, "cve": true, "cvss": true,
-					"risk": true, "hostname": true, "protocol": true,
-					"port": true, "plugin_name": true, "synopsis": true,
-					"description": true, "solution": true, "see_also": true,
-					"plugin_output": true, "stig_severity": true,
-					"cvss3_base_score": true, "cvss_temporal_score": true,
-					"cvss3_temporal_score": true, "risk_factor": true,
-					"references": true, "plugin_information": true,
-					"exploitable_with": true,
-				},
-			},
-			ExtraFilters: map[string]interface{}{
-				"host_ids":   []int{},
-				"plugin_ids": []int{},
-			},
-		},
-		"nessus": {
-			Format: "nessus",
-		},
-		"html": {
-			Format:     "html",
-			TemplateID: templateID,
-			ExtraFilters: map[string]interface{}{
-				"host_ids":   []int{},
-				"plugin_ids": []int{},
-			},
-		},
-	}
-
-	for format, config := range formats {
-		logging.InfoLogger.Printf("Exporting %s file...", format)
-
-		exportJSON, err := json.Marshal(config)
-		if err != nil {
-			return "", fmt.Errorf("failed to serialize export config: %v", err)
-		}
-
-		resp, err := n.makeRequest(http.MethodPost, fmt.Sprintf("/scans/%s/export", scanID), exportJSON)
-		if err != nil {
-			return "", fmt.Errorf("export request failed: %v", err)
-		}
-
-		defer resp.Body.Close()
-		var result struct {
-			Token string `json:"token"`
-		}
-		if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-			return "", fmt.Errorf("failed to decode export token: %v", err)
-		}
-
-		if result.Token == "" {
-			return "", fmt.Errorf("no export token received for %s format", format)
-		}
-
-		// Create the output file path within the evidence folder
-		outputFile := filepath.Join(evidenceFolder, fmt.Sprintf("%s.%s", scanName, format))
-		if err := n.waitForDownload(result.Token, outputFile); err != nil {
-			return "", fmt.Errorf("failed to download %s file: %v", format, err)
+	if !strings.Contains(rawURL, "://") {
+		rawURL = "https://" + rawURL
+		if u, err := url.Parse(rawURL); err == nil && u.Port() == "" {
+			u.Host = net.JoinHostPort(u.Hostname(), defaultNessusPort)
+			rawURL = u.String()
 		}
-
-		logging.SuccessLogger.Printf("Exported %s file to %q", format, outputFile)
 	}
 
-	return filepath.Join(evidenceFolder, fmt.Sprintf("%s.nessus", scanName)), nil
-}
-
-func (n *Nessus) getHTMLTemplateID() (string, error) {
-	resp, err := n.makeRequest(http.MethodGet, "/reports/custom/templates", nil)
-	if err != nil {
-		return "", err
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return "", fmt.Errorf("invalid Nessus URL %q", rawURL)
 	}
-	defer resp.Body.Close()
-
-	var templates []map[string]interface{}
-	if err := json.NewDecoder(resp.Body).Decode(&templates); err != nil {
-		return "", err
+	if u.Scheme != "https" && u.Scheme != "http" {
+		return "", fmt.Errorf("invalid Nessus URL %q: scheme must be https or http", rawURL)
 	}
-
-	for _, template := range templates {
-		name, ok := template["name"].(string)
-		if !ok || name != "Detailed Vulnerabilities By Plugin" {
-			continue
-		}
-
-		id := template["id"]
-		switch v := id.(type) {
-		case float64:
-			return fmt.Sprintf("%.0f", v), nil
-		case string:
-			return v, nil
-		default:
-			return "", fmt.Errorf("unexpected type for template ID: %T", v)
-		}
-
-	}
-	return "", fmt.Errorf("template 'Detailed Vulnerabilities By Plugin' not found")
+	return strings.TrimRight(u.String(), "/"), nil
 }
 
 func createInsecureClient() *http.Client {
@@ -1105,33 +832,46 @@ func (n *Nessus) getDroneIP() (string, error) {
 	return strings.TrimSpace(output), nil
 }
 
-func (n *Nessus) discoveryScan() (string, error) {
-	logging.InfoLogger.Printf("Running discovery scan")
+// discoveryProbePorts are the TCP SYN discovery probes added when the top
+// ports are scanned, to find hosts that drop ICMP
+const discoveryProbePorts = "21,22,23,25,80,135,139,443,445,3389,8080"
 
-	cmd := fmt.Sprintf("sudo nmap --exclude %s -T4 -n -sn %s -PE -PP -PM -PO --min-parallelism 100 --max-parallelism 256 -oG -",
-		n.droneIP, n.targetsList)
+// discoveryScan finds the live targets with nmap and saves them as the host
+// inventory in the output folder. With topPorts set, the top TCP ports
+// of each host are scanned as well.
+func (n *Nessus) discoveryScan() (*inventory.Inventory, error) {
+	n.log.Info.Printf("Running discovery scan")
+
+	mode := "-sn"
+	if n.topPorts > 0 {
+		mode = fmt.Sprintf("--top-ports %d --open -PS%s", n.topPorts, discoveryProbePorts)
+	}
+
+	cmd := fmt.Sprintf("sudo nmap --exclude %s -T4 %s %s -PE -PP -PM -PO --min-parallelism 100 --max-parallelism 256 -oX -",
+		n.droneIP, mode, strings.ReplaceAll(n.targetsList, ",", " "))
 
 	output, err := n.remote.ExecuteCommand(cmd)
 	if err != nil {
-		return "", fmt.Errorf("discovery scan failed: %v", err)
+		return nil, fmt.Errorf("discovery scan failed: %v", err)
 	}
 
-	var aliveHosts []string
-	lines := strings.Split(output, "\n")
-	for _, line := range lines {
-		if strings.Contains(line, "Up") {
-			fields := strings.Fields(line)
-			if len(fields) > 1 {
-				aliveHosts = append(aliveHosts, fields[1])
-			}
-		}
+	inv, err := inventory.ParseNmapXML([]byte(output))
+	if err != nil {
+		return nil, err
+	}
+	if len(inv.Hosts) == 0 {
+		return nil, fmt.Errorf("no hosts are up")
 	}
+	inv.Targets = n.targetsList
 
-	if len(aliveHosts) == 0 {
-		return "", fmt.Errorf("no hosts are up")
+	if err := inv.Save(n.outputFolder); err != nil {
+		n.log.Error.Printf("Failed to save host inventory: %v", err)
+	} else {
+		n.log.Info.Printf("Saved host inventory to %s", filepath.Join(n.outputFolder, inventory.JSONFileName))
 	}
 
-	return strings.Join(aliveHosts, ","), nil
+	n.log.Success.Printf("Discovery found %d live hosts", len(inv.Hosts))
+	return inv, nil
 }
 
 func (n *Nessus) Deploy() error {
@@ -1145,7 +885,7 @@ func (n *Nessus) Deploy() error {
 
 		err := n.monitorScan()
 		if err == ErrScanCanceled {
-			logging.InfoLogger.Printf("Scan was canceled, skipping export")
+			n.log.Info.Printf("Scan was canceled, skipping export")
 			return nil // Return nil to prevent crash reporting
 		} else if err != nil {
 			return err
@@ -1173,7 +913,7 @@ func (n *Nessus) Launch() error {
 
 		err := n.monitorScan()
 		if err == ErrScanCanceled {
-			logging.InfoLogger.Printf("Scan was canceled, skipping export")
+			n.log.Info.Printf("Scan was canceled, skipping export")
 			return nil // Return nil to prevent crash reporting
 		} else if err != nil {
 			return err
@@ -1198,7 +938,7 @@ func (n *Nessus) Resume() error {
 
 		err := n.monitorScan()
 		if err == ErrScanCanceled {
-			logging.InfoLogger.Printf("Scan was canceled, skipping export")
+			n.log.Info.Printf("Scan was canceled, skipping export")
 			return nil // Return nil to prevent crash reporting
 		} else if err != nil {
 			return err
@@ -1213,7 +953,7 @@ func (n *Nessus) Monitor() error {
 	return n.safeExecute("Monitor", func() error {
 		err := n.monitorScan()
 		if err == ErrScanCanceled {
-			logging.InfoLogger.Printf("Scan was canceled, skipping export")
+			n.log.Info.Printf("Scan was canceled, skipping export")
 			return nil // Return nil to prevent crash reporting
 		} else if err != nil {
 			return err
@@ -1230,3 +970,42 @@ func (n *Nessus) Export() error {
 		return err
 	})
 }
+
+// Start creates and launches the project's scan, or launches it when it
+// exists but has never run. A scan that is already running or finished is
+// left alone, so Start can be repeated after an interruption.
+func (n *Nessus) Start() error {
+	return n.safeExecute("Start", func() error {
+		scan := n.getScanInfo()
+		if scan == nil {
+			if err := n.excludeTargets(); err != nil {
+				return err
+			}
+			return n.createScan(true)
+		}
+
+		if scan.Status == "empty" && n.schedule == nil {
+			return n.scanAction("launch")
+		}
+		n.log.Info.Printf("Scan %s already exists (%s), not launching it again", scan.Name, scan.Status)
+		return nil
+	})
+}
+
+// Wait monitors the project's scan until it finishes, returning
+// ErrScanCanceled or ErrScanFailed when it did not complete
+func (n *Nessus) Wait() error {
+	return n.safeExecute("Wait", n.monitorScan)
+}
+
+// ExportResults exports the project's scan and returns the path of the
+// .nessus file, or of the first exported file when .nessus was not selected
+func (n *Nessus) ExportResults() (string, error) {
+	var path string
+	err := n.safeExecute("ExportResults", func() error {
+		var err error
+		path, err = n.exportScan()
+		return err
+	})
+	return path, err
+}
@@ -0,0 +1,176 @@
+package nessus
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"NMB/internal/nessusapi"
+)
+
+// Defaults used when no policy, scanner or folder is selected
+const (
+	defaultPolicyName = "Default Good Model Nessus Vulnerability Policy"
+	defaultScannerID  = 1
+	defaultFolderID   = 3
+)
+
+// ListItem is a policy or scanner as listed by the Nessus API
+type ListItem struct {
+	ID     string
+	Name   string
+	Detail string
+}
+
+// ListPolicies returns the policies available on the Nessus server
+func (n *Nessus) ListPolicies() ([]ListItem, error) {
+	policies, err := n.api.ListPolicies()
+	if err != nil {
+		return nil, err
+	}
+	return policyItems(policies), nil
+}
+
+// ListScanners returns the scanners linked to the Nessus server
+func (n *Nessus) ListScanners() ([]ListItem, error) {
+	scanners, err := n.api.ListScanners()
+	if err != nil {
+		return nil, err
+	}
+	return scannerItems(scanners), nil
+}
+
+// resolvePolicy returns the policy for a new scan. An imported policy file
+// wins over a selected policy, which wins over the default policy.
+func (n *Nessus) resolvePolicy() (*nessusapi.Policy, error) {
+	if n.policyFile != "" {
+		imported, err := n.importPolicy(n.policyFile)
+		if err != nil {
+			return nil, err
+		}
+		// The import response does not include the template, so look the
+		// policy up again like any other selection
+		n.policy = imported
+	}
+
+	selector := n.policy
+	if selector == "" {
+		selector = defaultPolicyName
+	}
+
+	policies, err := n.api.ListPolicies()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get policies: %w", err)
+	}
+
+	i := findItem(policyItems(policies), selector)
+	if i < 0 {
+		return nil, fmt.Errorf("policy %q not found", selector)
+	}
+
+	n.log.Info.Printf("Using policy: %s", policies[i].Name)
+	return &policies[i], nil
+}
+
+func (n *Nessus) resolveScanner() (int, error) {
+	if n.scanner == "" {
+		return defaultScannerID, nil
+	}
+
+	scanners, err := n.api.ListScanners()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get scanners: %w", err)
+	}
+
+	i := findItem(scannerItems(scanners), n.scanner)
+	if i < 0 {
+		return 0, fmt.Errorf("scanner %q not found", n.scanner)
+	}
+
+	n.log.Info.Printf("Using scanner: %s", scanners[i].Name)
+	return scanners[i].ID, nil
+}
+
+func (n *Nessus) resolveFolder() (int, error) {
+	if n.folder == "" {
+		return defaultFolderID, nil
+	}
+
+	folders, err := n.api.ListFolders()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get folders: %w", err)
+	}
+
+	items := make([]ListItem, len(folders))
+	for i, folder := range folders {
+		items[i] = ListItem{ID: strconv.Itoa(folder.ID), Name: folder.Name}
+	}
+
+	i := findItem(items, n.folder)
+	if i < 0 {
+		return 0, fmt.Errorf("folder %q not found", n.folder)
+	}
+
+	n.log.Info.Printf("Using folder: %s", folders[i].Name)
+	return folders[i].ID, nil
+}
+
+// importPolicy uploads a .nessus policy file and imports it, returning the ID
+// of the new policy
+func (n *Nessus) importPolicy(policyFile string) (string, error) {
+	n.log.Info.Printf("Importing policy from %s", policyFile)
+
+	file, err := os.Open(policyFile)
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %v", policyFile, err)
+	}
+	defer file.Close()
+
+	uploaded, err := n.api.UploadFile(filepath.Base(policyFile), file)
+	if err != nil {
+		return "", fmt.Errorf("failed to upload %s: %w", policyFile, err)
+	}
+
+	policy, err := n.api.ImportPolicy(uploaded)
+	if err != nil {
+		return "", fmt.Errorf("failed to import policy: %w", err)
+	}
+
+	n.log.Success.Printf("Imported policy %s (ID %d)", policy.Name, policy.ID)
+	return strconv.Itoa(policy.ID), nil
+}
+
+func policyItems(policies []nessusapi.Policy) []ListItem {
+	items := make([]ListItem, len(policies))
+	for i, policy := range policies {
+		items[i] = ListItem{ID: strconv.Itoa(policy.ID), Name: policy.Name, Detail: policy.Description}
+	}
+	return items
+}
+
+func scannerItems(scanners []nessusapi.Scanner) []ListItem {
+	items := make([]ListItem, len(scanners))
+	for i, scanner := range scanners {
+		items[i] = ListItem{ID: strconv.Itoa(scanner.ID), Name: scanner.Name, Detail: scanner.Status}
+	}
+	return items
+}
+
+// findItem matches a selector against the IDs first, then the names, and
+// returns the index of the match or -1
+func findItem(items []ListItem, selector string) int {
+	selector = strings.TrimSpace(selector)
+	for i, item := range items {
+		if item.ID == selector {
+			return i
+		}
+	}
+	for i, item := range items {
+		if strings.EqualFold(item.Name, selector) {
+			return i
+		}
+	}
+	return -1
+}
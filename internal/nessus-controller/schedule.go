@@ -0,0 +1,247 @@
+package nessus
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// scheduleLayout is the format accepted for a scan start time
+const scheduleLayout = "2006-01-02 15:04"
+
+// Schedule is when Nessus should launch a scan, mapped to the scan's
+// launch, starttime, rrules and timezone settings
+type Schedule struct {
+	Start    time.Time
+	Timezone string
+	RRules   string
+}
+
+// ParseSchedule reads a start time ("2006-01-02 15:04") in the given IANA
+// timezone, with optional iCalendar rrules such as "FREQ=WEEKLY;BYDAY=SA".
+// The scan runs once when rrules are empty.
+func ParseSchedule(start, timezone, rrules string) (*Schedule, error) {
+	location, err := loadLocation(timezone)
+	if err != nil {
+		return nil, err
+	}
+
+	startTime, err := time.ParseInLocation(scheduleLayout, strings.TrimSpace(start), location)
+	if err != nil {
+		return nil, fmt.Errorf("invalid start time %q, expected %q", start, scheduleLayout)
+	}
+
+	if rrules == "" {
+		rrules = "FREQ=ONETIME;INTERVAL=1"
+	}
+	if !strings.HasPrefix(strings.ToUpper(rrules), "FREQ=") {
+		return nil, fmt.Errorf("invalid rrules %q, expected FREQ=...", rrules)
+	}
+
+	return &Schedule{
+		Start:    startTime,
+		Timezone: location.String(),
+		RRules:   strings.ToUpper(rrules),
+	}, nil
+}
+
+// launch returns the Nessus launch setting for the schedule's frequency
+func (s *Schedule) launch() string {
+	for _, rule := range strings.Split(s.RRules, ";") {
+		if key, value, ok := strings.Cut(rule, "="); ok && key == "FREQ" {
+			return value
+		}
+	}
+	return "ONETIME"
+}
+
+// apply adds the schedule to a scan's settings
+func (s *Schedule) apply(settings map[string]interface{}) {
+	settings["launch"] = s.launch()
+	settings["starttime"] = s.Start.Format("20060102T150405")
+	settings["rrules"] = s.RRules
+	settings["timezone"] = s.Timezone
+}
+
+// Window is a recurring time of day scanning is allowed in, optionally
+// limited to some weekdays. A window whose end is before its start runs
+// overnight into the next day.
+type Window struct {
+	Start    int // minutes after midnight
+	End      int
+	Days     map[time.Weekday]bool
+	Location *time.Location
+}
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+// ParseWindow reads a window such as "22:00-06:00" or "Mon-Fri 19:00-23:00"
+// or "Sat,Sun 00:00-24:00", in the given IANA timezone
+func ParseWindow(spec, timezone string) (*Window, error) {
+	location, err := loadLocation(timezone)
+	if err != nil {
+		return nil, err
+	}
+
+	fields := strings.Fields(spec)
+	if len(fields) == 0 || len(fields) > 2 {
+		return nil, fmt.Errorf("invalid window %q, expected [days] HH:MM-HH:MM", spec)
+	}
+
+	w := &Window{Location: location}
+	if len(fields) == 2 {
+		if w.Days, err = parseDays(fields[0]); err != nil {
+			return nil, err
+		}
+	}
+
+	from, to, ok := strings.Cut(fields[len(fields)-1], "-")
+	if !ok {
+		return nil, fmt.Errorf("invalid window %q, expected [days] HH:MM-HH:MM", spec)
+	}
+	if w.Start, err = parseClock(from); err != nil {
+		return nil, err
+	}
+	if w.End, err = parseClock(to); err != nil {
+		return nil, err
+	}
+	if w.Start == w.End {
+		return nil, fmt.Errorf("window %q is empty", spec)
+	}
+
+	return w, nil
+}
+
+// Contains reports whether scanning is allowed at t
+func (w *Window) Contains(t time.Time) bool {
+	t = t.In(w.Location)
+	minute := t.Hour()*60 + t.Minute()
+
+	if w.Start < w.End {
+		return w.allowedOn(t.Weekday()) && minute >= w.Start && minute < w.End
+	}
+
+	// Overnight: the part after midnight belongs to the previous day's window
+	if minute >= w.Start {
+		return w.allowedOn(t.Weekday())
+	}
+	if minute < w.End {
+		return w.allowedOn(t.AddDate(0, 0, -1).Weekday())
+	}
+	return false
+}
+
+func (w *Window) allowedOn(day time.Weekday) bool {
+	return len(w.Days) == 0 || w.Days[day]
+}
+
+func (w *Window) String() string {
+	return fmt.Sprintf("%02d:%02d-%02d:%02d %s", w.Start/60, w.Start%60, w.End/60, w.End%60, w.Location)
+}
+
+// parseDays reads "Mon-Fri", "Sat,Sun" or a mix such as "Mon,Wed-Fri"
+func parseDays(spec string) (map[time.Weekday]bool, error) {
+	days := make(map[time.Weekday]bool)
+	for _, part := range strings.Split(strings.ToLower(spec), ",") {
+		from, to, isRange := strings.Cut(part, "-")
+		start, ok := weekdays[from]
+		if !ok {
+			return nil, fmt.Errorf("invalid weekday %q", from)
+		}
+		if !isRange {
+			days[start] = true
+			continue
+		}
+
+		end, ok := weekdays[to]
+		if !ok {
+			return nil, fmt.Errorf("invalid weekday %q", to)
+		}
+		for day := start; ; day = (day + 1) % 7 {
+			days[day] = true
+			if day == end {
+				break
+			}
+		}
+	}
+	return days, nil
+}
+
+// parseClock reads HH:MM, allowing 24:00 as the end of the day
+func parseClock(value string) (int, error) {
+	var hour, minute int
+	if _, err := fmt.Sscanf(value, "%d:%d", &hour, &minute); err != nil {
+		return 0, fmt.Errorf("invalid time %q, expected HH:MM", value)
+	}
+	if hour < 0 || minute < 0 || minute > 59 || hour > 24 || (hour == 24 && minute != 0) {
+		return 0, fmt.Errorf("invalid time %q", value)
+	}
+	return hour*60 + minute, nil
+}
+
+func loadLocation(timezone string) (*time.Location, error) {
+	if timezone == "" {
+		return time.Local, nil
+	}
+	location, err := time.LoadLocation(timezone)
+	if err != nil {
+		return nil, fmt.Errorf("invalid timezone %q: %v", timezone, err)
+	}
+	return location, nil
+}
+
+// windowMarker is the file in the project folder that records NMB paused
+// the scan for the window, so a monitor started again later resumes it
+func (n *Nessus) windowMarker() string {
+	name := strings.NewReplacer("/", "_", "\\", "_").Replace(n.projectName)
+	return filepath.Join(n.outputFolder, fmt.Sprintf("NMB_window_paused_%s", name))
+}
+
+// enforceWindow pauses a running scan outside the scan window and resumes a
+// scan it paused once the window opens again. Scans paused by someone else
+// are left alone.
+func (n *Nessus) enforceWindow(status string) {
+	if n.window == nil {
+		return
+	}
+
+	marker := n.windowMarker()
+	_, err := os.Stat(marker)
+	windowPaused := err == nil
+
+	inside := n.window.Contains(time.Now())
+	switch {
+	case status == "running" && !inside:
+		n.log.Info.Printf("Outside scan window (%s), pausing scan", n.window)
+		if err := n.scanAction("pause"); err != nil {
+			n.log.Error.Printf("Failed to pause scan outside window: %v", err)
+			return
+		}
+		if err := os.WriteFile(marker, []byte(time.Now().Format(time.RFC3339)+"\n"), 0644); err != nil {
+			n.log.Error.Printf("Failed to record the window pause: %v", err)
+		}
+	case status == "paused" && inside && windowPaused:
+		n.log.Info.Printf("Scan window (%s) open, resuming scan", n.window)
+		if err := n.scanAction("resume"); err != nil {
+			n.log.Error.Printf("Failed to resume scan inside window: %v", err)
+			return
+		}
+		n.clearWindowMarker(marker)
+	case windowPaused && (status == "running" || scanFinished(status)):
+		// Resumed or finished by someone else. Nessus reports pausing and
+		// resuming in between, which keep the marker.
+		n.clearWindowMarker(marker)
+	}
+}
+
+func (n *Nessus) clearWindowMarker(marker string) {
+	if err := os.Remove(marker); err != nil && !errors.Is(err, os.ErrNotExist) {
+		n.log.Error.Printf("Failed to clear the window pause: %v", err)
+	}
+}
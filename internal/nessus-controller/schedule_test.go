@@ -0,0 +1,201 @@
+package nessus
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"NMB/internal/logging"
+	"NMB/internal/nessusapi"
+)
+
+func TestParseSchedule(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skipf("no timezone data: %v", err)
+	}
+
+	tests := []struct {
+		start, timezone, rrules string
+		wantStart               time.Time
+		wantRRules, wantLaunch  string
+	}{
+		{"2026-03-01 22:00", "Europe/Berlin", "", time.Date(2026, 3, 1, 22, 0, 0, 0, berlin), "FREQ=ONETIME;INTERVAL=1", "ONETIME"},
+		{" 2026-03-07 01:30 ", "Europe/Berlin", "freq=weekly;byday=sa", time.Date(2026, 3, 7, 1, 30, 0, 0, berlin), "FREQ=WEEKLY;BYDAY=SA", "WEEKLY"},
+	}
+	for _, tt := range tests {
+		schedule, err := ParseSchedule(tt.start, tt.timezone, tt.rrules)
+		if err != nil {
+			t.Errorf("ParseSchedule(%q, %q, %q): %v", tt.start, tt.timezone, tt.rrules, err)
+			continue
+		}
+		if !schedule.Start.Equal(tt.wantStart) || schedule.Timezone != tt.timezone ||
+			schedule.RRules != tt.wantRRules || schedule.launch() != tt.wantLaunch {
+			t.Errorf("ParseSchedule(%q, %q, %q) = %+v, launch %s", tt.start, tt.timezone, tt.rrules, schedule, schedule.launch())
+		}
+	}
+
+	errors := []struct{ start, timezone, rrules string }{
+		{"2026-03-01T22:00", "", ""},
+		{"tomorrow", "", ""},
+		{"2026-03-01 22:00", "Mars/Olympus", ""},
+		{"2026-03-01 22:00", "", "BYDAY=SA"},
+	}
+	for _, tt := range errors {
+		if _, err := ParseSchedule(tt.start, tt.timezone, tt.rrules); err == nil {
+			t.Errorf("ParseSchedule(%q, %q, %q) got no error", tt.start, tt.timezone, tt.rrules)
+		}
+	}
+}
+
+func TestParseWindowErrors(t *testing.T) {
+	for _, spec := range []string{
+		"",
+		"22:00",
+		"Mon Tue 22:00-06:00",
+		"Mon-Fry 19:00-23:00",
+		"25:00-06:00",
+		"22:00-24:30",
+		"10:60-11:00",
+		"10:00-10:00",
+	} {
+		if _, err := ParseWindow(spec, ""); err == nil {
+			t.Errorf("ParseWindow(%q) got no error", spec)
+		}
+	}
+	if _, err := ParseWindow("22:00-06:00", "Mars/Olympus"); err == nil {
+		t.Error("ParseWindow with an unknown timezone got no error")
+	}
+}
+
+func TestWindowContains(t *testing.T) {
+	// 2026-01-05 is a Monday
+	at := func(day, hour, minute int) time.Time {
+		return time.Date(2026, 1, 5+day, hour, minute, 0, 0, time.UTC)
+	}
+	const mon, tue, wed, fri, sat, sun = 0, 1, 2, 4, 5, 6
+
+	tests := []struct {
+		spec, timezone string
+		at             time.Time
+		want           bool
+	}{
+		{"22:00-06:00", "UTC", at(mon, 23, 0), true},
+		{"22:00-06:00", "UTC", at(tue, 5, 59), true},
+		{"22:00-06:00", "UTC", at(tue, 6, 0), false},
+		{"22:00-06:00", "UTC", at(tue, 21, 59), false},
+		{"Mon-Fri 19:00-23:00", "UTC", at(fri, 20, 0), true},
+		{"Mon-Fri 19:00-23:00", "UTC", at(fri, 23, 0), false},
+		{"Mon-Fri 19:00-23:00", "UTC", at(sat, 20, 0), false},
+		{"Fri-Mon 10:00-11:00", "UTC", at(sun, 10, 30), true},
+		{"Fri-Mon 10:00-11:00", "UTC", at(wed, 10, 30), false},
+		{"Sat,Sun 00:00-24:00", "UTC", at(sun, 23, 59), true},
+		{"Sat,Sun 00:00-24:00", "UTC", at(mon, 0, 0), false},
+
+		// After midnight an overnight window belongs to the previous day
+		{"Fri 22:00-02:00", "UTC", at(sat, 1, 0), true},
+		{"Fri 22:00-02:00", "UTC", at(fri, 1, 0), false},
+		{"Fri 22:00-02:00", "UTC", at(fri, 23, 0), true},
+
+		// 14:30 UTC is 09:30 in New York in January
+		{"09:00-17:00", "America/New_York", at(mon, 14, 30), true},
+		{"09:00-17:00", "America/New_York", at(mon, 13, 30), false},
+		{"Mon 20:00-23:00", "America/New_York", at(tue, 2, 0), true},
+	}
+	for _, tt := range tests {
+		w, err := ParseWindow(tt.spec, tt.timezone)
+		if err != nil {
+			t.Errorf("ParseWindow(%q): %v", tt.spec, err)
+			continue
+		}
+		if got := w.Contains(tt.at); got != tt.want {
+			t.Errorf("%q contains %s = %v, want %v", tt.spec, tt.at.Format(time.RFC1123), got, tt.want)
+		}
+	}
+}
+
+// fakeScanActions serves one scan and records the actions taken on it
+type fakeScanActions struct {
+	mu      sync.Mutex
+	actions []string
+}
+
+func (f *fakeScanActions) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	switch {
+	case r.Method == http.MethodGet && r.URL.Path == "/scans":
+		json.NewEncoder(w).Encode(nessusapi.ScanList{Scans: []nessusapi.Scan{{ID: 7, Name: "acme"}}})
+	case r.Method == http.MethodPost && strings.HasPrefix(r.URL.Path, "/scans/7/"):
+		f.actions = append(f.actions, strings.TrimPrefix(r.URL.Path, "/scans/7/"))
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (f *fakeScanActions) take() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	actions := f.actions
+	f.actions = nil
+	return actions
+}
+
+func TestEnforceWindow(t *testing.T) {
+
+	fake := &fakeScanActions{}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	// The closed window only allows a day other than today
+	today := time.Now().Weekday()
+	open := &Window{Start: 0, End: 24 * 60, Location: time.Local}
+	closed := &Window{Start: 0, End: 24 * 60, Days: map[time.Weekday]bool{(today + 3) % 7: true}, Location: time.Local}
+
+	n := &Nessus{
+		api:          nessusapi.NewClient(server.URL, server.Client(), nil),
+		projectName:  "acme",
+		outputFolder: t.TempDir(),
+		log:          logging.ForJob(""),
+	}
+
+	steps := []struct {
+		window     *Window
+		status     string
+		wantAction string
+		wantMarker bool
+	}{
+		{closed, "running", "pause", true},
+		{closed, "pausing", "", true},
+		{closed, "paused", "", true},
+		{open, "paused", "resume", false},
+		{open, "resuming", "", false},
+		{open, "running", "", false},
+
+		// A scan paused by someone else stays paused
+		{open, "paused", "", false},
+
+		// A window pause is forgotten once the scan finishes
+		{closed, "running", "pause", true},
+		{closed, "canceled", "", false},
+	}
+	for i, step := range steps {
+		n.window = step.window
+		n.enforceWindow(step.status)
+
+		actions := fake.take()
+		if got := strings.Join(actions, ","); got != step.wantAction {
+			t.Errorf("step %d (%s): actions %q, want %q", i+1, step.status, got, step.wantAction)
+		}
+		_, err := os.Stat(n.windowMarker())
+		if marker := err == nil; marker != step.wantMarker {
+			t.Errorf("step %d (%s): marker %v, want %v", i+1, step.status, marker, step.wantMarker)
+		}
+	}
+}
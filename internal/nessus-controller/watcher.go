@@ -0,0 +1,211 @@
+package nessus
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"NMB/internal/logging"
+	"NMB/internal/nessusapi"
+)
+
+// EventType identifies what a scan event reports
+type EventType string
+
+// Scan events emitted by a Watcher
+const (
+	// EventStatusChanged is sent when the scan status changes, including
+	// the first status seen
+	EventStatusChanged EventType = "status_changed"
+	// EventProgress is sent on every poll while the scan has not finished
+	EventProgress EventType = "progress"
+	// EventHostCompleted is sent once per host when it has been scanned
+	EventHostCompleted EventType = "host_completed"
+	// EventNewCritical is sent when a host's critical finding count grows
+	EventNewCritical EventType = "new_critical"
+)
+
+// Event is a change in a watched scan. Host and NewCriticals are only set
+// for host events.
+type Event struct {
+	Type           EventType
+	ScanID         int
+	ScanName       string
+	Status         string
+	PreviousStatus string
+	Progress       float64
+	Host           nessusapi.Host
+	NewCriticals   int
+	Time           time.Time
+}
+
+// Subscriber receives the events of a Watcher. Events are delivered in
+// order from the watching goroutine, so subscribers should not block.
+type Subscriber interface {
+	OnScanEvent(Event)
+}
+
+// SubscriberFunc lets a function be used as a Subscriber
+type SubscriberFunc func(Event)
+
+func (f SubscriberFunc) OnScanEvent(e Event) {
+	f(e)
+}
+
+// defaultWatchInterval is how often a Watcher polls the scan
+const defaultWatchInterval = 30 * time.Second
+
+// maxPollErrors is how many polls in a row may fail before watching stops
+const maxPollErrors = 3
+
+// Watcher polls a scan and turns the differences between polls into events
+// for its subscribers
+type Watcher struct {
+	// Interval is the time between polls, 30 seconds by default
+	Interval time.Duration
+
+	api         *nessusapi.Client
+	log         *logging.Loggers
+	scanID      int
+	subscribers []Subscriber
+
+	polled    bool
+	status    string
+	completed map[int]bool
+	criticals map[int]int
+}
+
+// NewWatcher returns a watcher for one of the server's scans
+func (n *Nessus) NewWatcher(scanID int) *Watcher {
+	return &Watcher{
+		Interval:  defaultWatchInterval,
+		api:       n.api,
+		log:       n.log,
+		scanID:    scanID,
+		completed: make(map[int]bool),
+		criticals: make(map[int]int),
+	}
+}
+
+// Subscribe adds a subscriber. Subscribers must be added before Watch.
+func (w *Watcher) Subscribe(s Subscriber) {
+	w.subscribers = append(w.subscribers, s)
+}
+
+// Watch polls the scan until it finishes or ctx is canceled, and returns
+// the last scan details. A finished scan is completed, canceled, stopped,
+// aborted or failed; the caller decides what the final status means.
+func (w *Watcher) Watch(ctx context.Context) (*nessusapi.ScanDetails, error) {
+	failures := 0
+	for {
+		details, err := w.api.ScanDetails(w.scanID)
+		if err != nil {
+			if nessusapi.IsNotFound(err) {
+				return nil, fmt.Errorf("scan %d not found", w.scanID)
+			}
+			failures++
+			if failures >= maxPollErrors {
+				return nil, fmt.Errorf("failed to get scan status: %w", err)
+			}
+			w.log.Warning.Printf("Failed to get scan status, retrying: %v", err)
+		} else {
+			failures = 0
+			w.update(details)
+			if scanFinished(details.Info.Status) {
+				return details, nil
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return details, ctx.Err()
+		case <-time.After(w.Interval):
+		}
+	}
+}
+
+// update compares a poll with the previous one and emits the differences.
+// Hosts already done or with criticals on the first poll are taken as the
+// starting point rather than reported.
+func (w *Watcher) update(details *nessusapi.ScanDetails) {
+	base := Event{
+		ScanID:   w.scanID,
+		ScanName: details.Info.Name,
+		Status:   details.Info.Status,
+		Progress: details.Progress(),
+		Time:     time.Now(),
+	}
+
+	if base.Status != w.status {
+		event := base
+		event.Type = EventStatusChanged
+		event.PreviousStatus = w.status
+		w.status = base.Status
+		w.emit(event)
+	}
+
+	for _, host := range details.Hosts {
+		done := base.Status == "completed" ||
+			(host.ScanProgressTotal > 0 && host.ScanProgressCurrent >= host.ScanProgressTotal)
+		if done && !w.completed[host.ID] {
+			w.completed[host.ID] = true
+			if w.polled {
+				event := base
+				event.Type = EventHostCompleted
+				event.Host = host
+				w.emit(event)
+			}
+		}
+
+		if previous := w.criticals[host.ID]; host.Critical > previous {
+			w.criticals[host.ID] = host.Critical
+			if w.polled {
+				event := base
+				event.Type = EventNewCritical
+				event.Host = host
+				event.NewCriticals = host.Critical - previous
+				w.emit(event)
+			}
+		}
+	}
+	w.polled = true
+
+	if !scanFinished(base.Status) {
+		event := base
+		event.Type = EventProgress
+		w.emit(event)
+	}
+}
+
+func (w *Watcher) emit(event Event) {
+	for _, s := range w.subscribers {
+		s.OnScanEvent(event)
+	}
+}
+
+func scanFinished(status string) bool {
+	switch status {
+	case "completed", "canceled", "stopped", "aborted", "failed", "imported":
+		return true
+	}
+	return false
+}
+
+// logSubscriber logs scan events for the CLI
+type logSubscriber struct {
+	log *logging.Loggers
+}
+
+func (l logSubscriber) OnScanEvent(e Event) {
+	switch e.Type {
+	case EventStatusChanged:
+		l.log.Info.Printf("Scan %s status: %s", e.ScanName, e.Status)
+	case EventProgress:
+		l.log.Info.Printf("Scan %s progress: %.0f%%", e.ScanName, e.Progress)
+	case EventHostCompleted:
+		l.log.Info.Printf("Scan %s finished host %s (%d critical, %d high, %d medium)",
+			e.ScanName, e.Host.Hostname, e.Host.Critical, e.Host.High, e.Host.Medium)
+	case EventNewCritical:
+		l.log.Warning.Printf("Scan %s found %d new critical findings on %s", e.ScanName, e.NewCriticals, e.Host.Hostname)
+	}
+}
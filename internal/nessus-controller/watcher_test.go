@@ -0,0 +1,190 @@
+package nessus
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"NMB/internal/logging"
+	"NMB/internal/nessusapi"
+)
+
+// snapshot builds scan details with hosts given as
+// {id, current progress, total progress, criticals}
+func snapshot(status string, hosts ...[4]int) *nessusapi.ScanDetails {
+	details := &nessusapi.ScanDetails{Info: nessusapi.ScanInfo{Name: "acme", Status: status}}
+	for _, h := range hosts {
+		details.Hosts = append(details.Hosts, nessusapi.Host{
+			ID:                  h[0],
+			Hostname:            fmt.Sprintf("10.0.0.%d", h[0]),
+			ScanProgressCurrent: h[1],
+			ScanProgressTotal:   h[2],
+			Critical:            h[3],
+		})
+	}
+	return details
+}
+
+// describe reduces an event to what the tests compare
+func describe(e Event) string {
+	switch e.Type {
+	case EventStatusChanged:
+		return fmt.Sprintf("status %s->%s", e.PreviousStatus, e.Status)
+	case EventHostCompleted:
+		return "host_completed " + e.Host.Hostname
+	case EventNewCritical:
+		return fmt.Sprintf("new_critical %s +%d", e.Host.Hostname, e.NewCriticals)
+	case EventProgress:
+		return fmt.Sprintf("progress %.0f", e.Progress)
+	}
+	return string(e.Type)
+}
+
+func TestWatcherUpdate(t *testing.T) {
+	tests := []struct {
+		name     string
+		previous *nessusapi.ScanDetails
+		current  *nessusapi.ScanDetails
+		want     []string
+	}{
+		{
+			name:     "no change",
+			previous: snapshot("running", [4]int{1, 10, 100, 0}),
+			current:  snapshot("running", [4]int{1, 10, 100, 0}),
+			want:     []string{"progress 10"},
+		},
+		{
+			name:     "status change",
+			previous: snapshot("running", [4]int{1, 50, 100, 0}),
+			current:  snapshot("paused", [4]int{1, 50, 100, 0}),
+			want:     []string{"status running->paused", "progress 50"},
+		},
+		{
+			name:     "host completed",
+			previous: snapshot("running", [4]int{1, 50, 100, 0}, [4]int{2, 10, 100, 0}),
+			current:  snapshot("running", [4]int{1, 100, 100, 0}, [4]int{2, 20, 100, 0}),
+			want:     []string{"host_completed 10.0.0.1", "progress 60"},
+		},
+		{
+			name:     "host already done on the previous poll",
+			previous: snapshot("running", [4]int{1, 100, 100, 0}, [4]int{2, 10, 100, 0}),
+			current:  snapshot("running", [4]int{1, 100, 100, 0}, [4]int{2, 30, 100, 0}),
+			want:     []string{"progress 65"},
+		},
+		{
+			name:     "new criticals",
+			previous: snapshot("running", [4]int{1, 50, 100, 1}, [4]int{2, 50, 100, 0}),
+			current:  snapshot("running", [4]int{1, 60, 100, 3}, [4]int{2, 60, 100, 0}),
+			want:     []string{"new_critical 10.0.0.1 +2", "progress 60"},
+		},
+		{
+			name:     "new host with criticals",
+			previous: snapshot("running", [4]int{1, 50, 100, 0}),
+			current:  snapshot("running", [4]int{1, 50, 100, 0}, [4]int{2, 50, 100, 1}),
+			want:     []string{"new_critical 10.0.0.2 +1", "progress 50"},
+		},
+		{
+			name:     "completed scan finishes every host",
+			previous: snapshot("running", [4]int{1, 100, 100, 0}, [4]int{2, 80, 100, 0}),
+			current:  snapshot("completed", [4]int{1, 100, 100, 0}, [4]int{2, 80, 100, 2}),
+			want:     []string{"status running->completed", "host_completed 10.0.0.2", "new_critical 10.0.0.2 +2"},
+		},
+		{
+			name:     "canceled scan",
+			previous: snapshot("running", [4]int{1, 50, 100, 0}),
+			current:  snapshot("canceled", [4]int{1, 50, 100, 0}),
+			want:     []string{"status running->canceled"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var events []string
+			w := (&Nessus{}).NewWatcher(7)
+			w.Subscribe(SubscriberFunc(func(e Event) {
+				if e.ScanID != 7 || e.ScanName != "acme" {
+					t.Errorf("event for scan %d %q", e.ScanID, e.ScanName)
+				}
+				events = append(events, describe(e))
+			}))
+
+			w.update(tt.previous)
+			events = nil
+			w.update(tt.current)
+
+			if !reflect.DeepEqual(events, tt.want) {
+				t.Errorf("events = %q, want %q", events, tt.want)
+			}
+		})
+	}
+}
+
+func TestWatcherFirstPoll(t *testing.T) {
+	// Hosts done or with criticals on the first poll are the starting point
+	var events []string
+	w := (&Nessus{}).NewWatcher(7)
+	w.Subscribe(SubscriberFunc(func(e Event) { events = append(events, describe(e)) }))
+
+	w.update(snapshot("running", [4]int{1, 100, 100, 2}, [4]int{2, 0, 100, 0}))
+
+	want := []string{"status ->running", "progress 50"}
+	if !reflect.DeepEqual(events, want) {
+		t.Errorf("events = %q, want %q", events, want)
+	}
+}
+
+func TestWatcherWatch(t *testing.T) {
+
+	polls := []*nessusapi.ScanDetails{
+		snapshot("running", [4]int{1, 0, 100, 0}),
+		snapshot("running", [4]int{1, 50, 100, 1}),
+		snapshot("completed", [4]int{1, 100, 100, 1}),
+	}
+	var mu sync.Mutex
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if r.URL.Path != "/scans/7" {
+			http.NotFound(w, r)
+			return
+		}
+		json.NewEncoder(w).Encode(polls[0])
+		if len(polls) > 1 {
+			polls = polls[1:]
+		}
+	}))
+	defer server.Close()
+
+	n := &Nessus{api: nessusapi.NewClient(server.URL, server.Client(), nil), log: logging.ForJob("")}
+	var events []string
+	w := n.NewWatcher(7)
+	w.Interval = time.Millisecond
+	w.Subscribe(SubscriberFunc(func(e Event) { events = append(events, describe(e)) }))
+
+	details, err := w.Watch(context.Background())
+	if err != nil {
+		t.Fatalf("Watch: %v", err)
+	}
+	if details.Info.Status != "completed" {
+		t.Errorf("last status %q, want completed", details.Info.Status)
+	}
+
+	want := []string{
+		"status ->running", "progress 0",
+		"new_critical 10.0.0.1 +1", "progress 50",
+		"status running->completed", "host_completed 10.0.0.1",
+	}
+	if !reflect.DeepEqual(events, want) {
+		t.Errorf("events = %q, want %q", events, want)
+	}
+
+	if _, err := n.NewWatcher(8).Watch(context.Background()); err == nil {
+		t.Error("Watch of a missing scan got no error")
+	}
+}
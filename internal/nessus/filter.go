@@ -0,0 +1,243 @@
+package nessus
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	"NMB/internal/config"
+)
+
+// Filter narrows the findings a verification run works on. Empty fields do
+// not filter; a finding has to pass every field that is set.
+type Filter struct {
+	Risks            []string `json:"risks,omitempty"`
+	PluginIDs        []string `json:"pluginIds,omitempty"`
+	ExcludePluginIDs []string `json:"excludePluginIds,omitempty"`
+	Categories       []string `json:"categories,omitempty"`
+	IncludeHosts     []string `json:"includeHosts,omitempty"`
+	ExcludeHosts     []string `json:"excludeHosts,omitempty"`
+	Ports            []string `json:"ports,omitempty"`
+}
+
+// IsEmpty reports whether the filter lets every finding through
+func (f Filter) IsEmpty() bool {
+	return len(f.Risks) == 0 && len(f.PluginIDs) == 0 && len(f.ExcludePluginIDs) == 0 &&
+		len(f.Categories) == 0 && len(f.IncludeHosts) == 0 && len(f.ExcludeHosts) == 0 &&
+		len(f.Ports) == 0
+}
+
+// Apply returns the findings that match the filter. Categories are the plugin
+// names in the config, so the config is needed to resolve them to plugin IDs.
+func (f Filter) Apply(findings []Finding, plugins map[string]config.Plugin) ([]Finding, error) {
+	if f.IsEmpty() {
+		return findings, nil
+	}
+
+	m, err := f.compile(plugins)
+	if err != nil {
+		return nil, err
+	}
+
+	var filtered []Finding
+	for _, finding := range findings {
+		if m.match(finding) {
+			filtered = append(filtered, finding)
+		}
+	}
+	return filtered, nil
+}
+
+// Validate checks the filter without applying it, so a request with a bad
+// risk, plugin category, host or port range can be rejected up front
+func (f Filter) Validate(plugins map[string]config.Plugin) error {
+	_, err := f.compile(plugins)
+	return err
+}
+
+type hostMatcher struct {
+	networks []*net.IPNet
+	names    map[string]struct{}
+}
+
+type portRange struct {
+	from, to int
+}
+
+type matcher struct {
+	risks        map[string]struct{}
+	pluginIDs    map[string]struct{}
+	skipIDs      map[string]struct{}
+	includeHosts *hostMatcher
+	excludeHosts *hostMatcher
+	ports        []portRange
+}
+
+func (f Filter) compile(plugins map[string]config.Plugin) (*matcher, error) {
+	m := &matcher{
+		skipIDs: toSet(f.ExcludePluginIDs),
+	}
+
+	if len(f.Risks) > 0 {
+		m.risks = make(map[string]struct{})
+		for _, risk := range f.Risks {
+			normalized := NormalizeRisk(risk)
+			switch normalized {
+			case "Critical", "High", "Medium", "Low", "None":
+				m.risks[normalized] = struct{}{}
+			default:
+				return nil, fmt.Errorf("invalid risk %q", risk)
+			}
+		}
+	}
+
+	if len(f.PluginIDs) > 0 || len(f.Categories) > 0 {
+		m.pluginIDs = toSet(f.PluginIDs)
+		for _, category := range f.Categories {
+			plugin, ok := plugins[strings.TrimSpace(category)]
+			if !ok {
+				return nil, fmt.Errorf("unknown plugin category %q", category)
+			}
+			for _, id := range plugin.IDs {
+				m.pluginIDs[id] = struct{}{}
+			}
+		}
+	}
+
+	var err error
+	if m.includeHosts, err = compileHosts(f.IncludeHosts); err != nil {
+		return nil, err
+	}
+	if m.excludeHosts, err = compileHosts(f.ExcludeHosts); err != nil {
+		return nil, err
+	}
+
+	for _, port := range f.Ports {
+		r, err := parsePortRange(port)
+		if err != nil {
+			return nil, err
+		}
+		m.ports = append(m.ports, r)
+	}
+
+	return m, nil
+}
+
+func (m *matcher) match(finding Finding) bool {
+	// Findings without a risk, such as results of reports from before the
+	// risk was recorded, are kept rather than dropped by every risk filter
+	if m.risks != nil && finding.Risk != "" {
+		if _, ok := m.risks[finding.Risk]; !ok {
+			return false
+		}
+	}
+	if m.pluginIDs != nil {
+		if _, ok := m.pluginIDs[finding.PluginID]; !ok {
+			return false
+		}
+	}
+	if _, skip := m.skipIDs[finding.PluginID]; skip {
+		return false
+	}
+	if m.includeHosts != nil && !m.includeHosts.match(finding.Host) {
+		return false
+	}
+	if m.excludeHosts != nil && m.excludeHosts.match(finding.Host) {
+		return false
+	}
+	if len(m.ports) > 0 {
+		port, err := strconv.Atoi(finding.Port)
+		if err != nil {
+			return false
+		}
+		for _, r := range m.ports {
+			if port >= r.from && port <= r.to {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+// compileHosts accepts CIDRs, single IPs and hostnames
+func compileHosts(entries []string) (*hostMatcher, error) {
+	if len(entries) == 0 {
+		return nil, nil
+	}
+
+	hm := &hostMatcher{names: make(map[string]struct{})}
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		if strings.Contains(entry, "/") {
+			_, network, err := net.ParseCIDR(entry)
+			if err != nil {
+				return nil, fmt.Errorf("invalid CIDR %q: %v", entry, err)
+			}
+			hm.networks = append(hm.networks, network)
+			continue
+		}
+
+		if ip := net.ParseIP(entry); ip != nil {
+			hm.names[ip.String()] = struct{}{}
+			continue
+		}
+
+		hm.names[strings.ToLower(entry)] = struct{}{}
+	}
+	return hm, nil
+}
+
+func (hm *hostMatcher) match(host string) bool {
+	host = strings.TrimSpace(host)
+
+	if ip := net.ParseIP(host); ip != nil {
+		if _, ok := hm.names[ip.String()]; ok {
+			return true
+		}
+		for _, network := range hm.networks {
+			if network.Contains(ip) {
+				return true
+			}
+		}
+		return false
+	}
+
+	_, ok := hm.names[strings.ToLower(host)]
+	return ok
+}
+
+// parsePortRange accepts a single port ("443") or a range ("8000-8100")
+func parsePortRange(value string) (portRange, error) {
+	value = strings.TrimSpace(value)
+	from, to, isRange := strings.Cut(value, "-")
+	if !isRange {
+		to = from
+	}
+
+	start, err := strconv.Atoi(strings.TrimSpace(from))
+	if err != nil {
+		return portRange{}, fmt.Errorf("invalid port %q", value)
+	}
+	end, err := strconv.Atoi(strings.TrimSpace(to))
+	if err != nil || end < start {
+		return portRange{}, fmt.Errorf("invalid port range %q", value)
+	}
+
+	return portRange{from: start, to: end}, nil
+}
+
+func toSet(values []string) map[string]struct{} {
+	set := make(map[string]struct{}, len(values))
+	for _, value := range values {
+		if value = strings.TrimSpace(value); value != "" {
+			set[value] = struct{}{}
+		}
+	}
+	return set
+}
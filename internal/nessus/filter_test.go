@@ -0,0 +1,94 @@
+package nessus
+
+import (
+	"reflect"
+	"testing"
+
+	"NMB/internal/config"
+)
+
+func TestFilterApply(t *testing.T) {
+	findings := []Finding{
+		{PluginID: "10079", Host: "10.0.0.1", Port: "21", Risk: "Medium"},
+		{PluginID: "42873", Host: "10.0.0.2", Port: "443", Risk: "Medium"},
+		{PluginID: "97833", Host: "10.0.1.5", Port: "445", Risk: "Critical"},
+		{PluginID: "11219", Host: "web.example.com", Port: "8080", Risk: "High"},
+		{PluginID: "57608", Host: "10.0.0.3", Port: "0", Risk: "Low"},
+	}
+	plugins := map[string]config.Plugin{
+		"SMB": {IDs: []string{"97833", "57608"}},
+	}
+
+	tests := []struct {
+		name   string
+		filter Filter
+		want   []string
+	}{
+		{"empty", Filter{}, []string{"10079", "42873", "97833", "11219", "57608"}},
+		{"risks", Filter{Risks: []string{"critical", "4", "High"}}, []string{"97833", "11219"}},
+		{"plugin IDs", Filter{PluginIDs: []string{"10079", " 42873 "}}, []string{"10079", "42873"}},
+		{"skip plugins", Filter{ExcludePluginIDs: []string{"10079", "42873"}}, []string{"97833", "11219", "57608"}},
+		{"category", Filter{Categories: []string{"SMB"}}, []string{"97833", "57608"}},
+		{"category and plugin IDs", Filter{Categories: []string{"SMB"}, PluginIDs: []string{"10079"}}, []string{"10079", "97833", "57608"}},
+		{"CIDR", Filter{IncludeHosts: []string{"10.0.0.0/24"}}, []string{"10079", "42873", "57608"}},
+		{"hostname", Filter{IncludeHosts: []string{"WEB.example.com"}}, []string{"11219"}},
+		{"skip hosts", Filter{ExcludeHosts: []string{"10.0.0.0/24", "10.0.1.5"}}, []string{"11219"}},
+		{"ports", Filter{Ports: []string{"21", "440-450"}}, []string{"10079", "42873", "97833"}},
+		{"every field", Filter{Risks: []string{"Medium"}, IncludeHosts: []string{"10.0.0.0/16"}, Ports: []string{"443"}}, []string{"42873"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			filtered, err := test.filter.Apply(findings, plugins)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, finding := range filtered {
+				got = append(got, finding.PluginID)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestFilterErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter Filter
+	}{
+		{"risk", Filter{Risks: []string{"urgent"}}},
+		{"category", Filter{Categories: []string{"FTP"}}},
+		{"CIDR", Filter{IncludeHosts: []string{"10.0.0.0/33"}}},
+		{"port", Filter{Ports: []string{"https"}}},
+		{"port range", Filter{Ports: []string{"8100-8000"}}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := test.filter.Apply(nil, nil); err == nil {
+				t.Error("got no error")
+			}
+			if err := test.filter.Validate(nil); err == nil {
+				t.Error("Validate got no error")
+			}
+		})
+	}
+}
+
+func TestFilterKeepsFindingsWithoutRisk(t *testing.T) {
+	findings := []Finding{
+		{PluginID: "10079", Risk: "Low"},
+		{PluginID: "42873"},
+	}
+
+	filtered, err := Filter{Risks: []string{"High"}}.Apply(findings, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(filtered) != 1 || filtered[0].PluginID != "42873" {
+		t.Errorf("got %+v, want only the finding without a risk", filtered)
+	}
+}
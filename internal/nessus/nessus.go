@@ -1,131 +1,165 @@
 package nessus
 
 import (
-    "NMB/internal/config"
-    "encoding/csv"
-    "fmt"
-    "os"
-    "sort"
+	"fmt"
+	"sort"
+	"strings"
+
+	"NMB/internal/config"
 )
 
 type Finding struct {
-    PluginID    string
-    Host        string
-    Port        string
-    Protocol    string
-    Name        string
-    Risk        string
-    Description string
-    Remedy      string
+	PluginID    string
+	Host        string
+	Port        string
+	Protocol    string
+	Name        string
+	Risk        string
+	Description string
+	Remedy      string
+	Source      string
 }
 
 type PluginData struct {
-    Host string
-    Port string
-    Name string
+	Host   string
+	Port   string
+	Name   string
+	Source string
 }
 
+// ParseCSV reads a Nessus CSV export and returns one finding per plugin,
+// skipping informational ("None" risk) results.
 func ParseCSV(filePath string) ([]Finding, map[string]PluginData, error) {
-    file, err := os.Open(filePath)
-    if err != nil {
-        return nil, nil, err
-    }
-    defer file.Close()
-
-    reader := csv.NewReader(file)
-    reader.LazyQuotes = true
-    reader.FieldsPerRecord = -1
-
-    records, err := reader.ReadAll()
-    if err != nil {
-        return nil, nil, err
-    }
-
-    var findings []Finding
-    pluginData := make(map[string]PluginData)
-    uniqueFindings := make(map[string]struct{})
-
-    for i, record := range records[1:] {
-        if len(record) < 26 {
-            return nil, nil, fmt.Errorf("record on line %d: wrong number of fields (got %d, expected at least 26)", i+2, len(record))
-        }
-
-        if record[3] == "None" { // Skip findings with "None" severity
-            continue
-        }
-
-        pluginID := record[0]
-        if _, exists := uniqueFindings[pluginID]; exists {
-            continue // Skip duplicates
-        }
-
-        finding := Finding{
-            PluginID:    pluginID,
-            Host:        record[4],
-            Protocol:    record[5],
-            Port:        record[6],
-            Name:        record[7],
-            Description: record[9],
-            Remedy:      record[10],
-            Risk:        record[3],
-        }
-        
-        findings = append(findings, finding)
-        pluginData[pluginID] = PluginData{
-            Host: finding.Host,
-            Port: finding.Port,
-            Name: finding.Name,
-        }
-
-        uniqueFindings[pluginID] = struct{}{}
-    }
-
-    return findings, pluginData, nil
+	return ParseFiles([]string{filePath})
+}
+
+// ParseFiles reads several CSV or .nessus exports and merges them into one
+// finding per plugin, as ParseCSV does for a single file.
+func ParseFiles(filePaths []string) ([]Finding, map[string]PluginData, error) {
+	findings, err := ParseAll(filePaths)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	deduped, pluginData := Dedupe(findings)
+	return deduped, pluginData, nil
+}
+
+// ParseAll reads every non-informational finding from the given exports,
+// tagging each with the path of the file it came from, as given
+func ParseAll(filePaths []string) ([]Finding, error) {
+	var findings []Finding
+
+	for _, filePath := range filePaths {
+		rows, err := ReadSource(filePath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", filePath, err)
+		}
+
+		source := filePath
+		for _, row := range rows {
+			if row[ColumnRisk] == "None" { // Skip findings with "None" severity
+				continue
+			}
+			if row[ColumnPluginID] == "" {
+				continue
+			}
+
+			findings = append(findings, Finding{
+				PluginID:    row[ColumnPluginID],
+				Host:        row[ColumnHost],
+				Protocol:    row[ColumnProtocol],
+				Port:        row[ColumnPort],
+				Name:        row[ColumnName],
+				Description: row[ColumnDescription],
+				Remedy:      row[ColumnSolution],
+				Risk:        row[ColumnRisk],
+				Source:      source,
+			})
+		}
+	}
+
+	return findings, nil
+}
+
+// Dedupe keeps the first finding seen for each plugin, which is the one the
+// scanner verifies. Its source lists every export that reported the plugin.
+func Dedupe(findings []Finding) ([]Finding, map[string]PluginData) {
+	var deduped []Finding
+	pluginData := make(map[string]PluginData)
+
+	sources := make(map[string][]string)
+	seen := make(map[string]struct{})
+	for _, finding := range findings {
+		key := finding.PluginID + "|" + finding.Source
+		if _, exists := seen[key]; exists || finding.Source == "" {
+			continue
+		}
+		seen[key] = struct{}{}
+		sources[finding.PluginID] = append(sources[finding.PluginID], finding.Source)
+	}
+
+	for _, finding := range findings {
+		if _, exists := pluginData[finding.PluginID]; exists {
+			continue // Skip duplicates
+		}
+
+		finding.Source = strings.Join(sources[finding.PluginID], ", ")
+		deduped = append(deduped, finding)
+		pluginData[finding.PluginID] = PluginData{
+			Host:   finding.Host,
+			Port:   finding.Port,
+			Name:   finding.Name,
+			Source: finding.Source,
+		}
+	}
+
+	return deduped, pluginData
 }
 
 func GetSupportedAndMissingPlugins(findings []Finding, plugins map[string]config.Plugin) ([]string, []string) {
-    var supportedPlugins []string
-    var missingPlugins []string
-    pluginNames := make(map[string]string)
-    riskFactors := make(map[string]string)
-
-    for _, finding := range findings {
-        pluginNames[finding.PluginID] = finding.Name
-        riskFactors[finding.PluginID] = finding.Risk
-    }
-
-    allPluginIDs := getAllPluginIDs(plugins)
-    matchingPluginIDs := intersect(allPluginIDs, pluginNames)
-
-    for pluginID, pluginName := range pluginNames {
-        if _, found := matchingPluginIDs[pluginID]; found && riskFactors[pluginID] != "None" {
-            supportedPlugins = append(supportedPlugins, pluginName)
-        } else {
-            missingPlugins = append(missingPlugins, pluginName)
-        }
-    }
-
-    sort.Strings(supportedPlugins)
-    sort.Strings(missingPlugins)
-    return supportedPlugins, missingPlugins
+	var supportedPlugins []string
+	var missingPlugins []string
+	pluginNames := make(map[string]string)
+	riskFactors := make(map[string]string)
+
+	for _, finding := range findings {
+		pluginNames[finding.PluginID] = finding.Name
+		riskFactors[finding.PluginID] = finding.Risk
+	}
+
+	allPluginIDs := getAllPluginIDs(plugins)
+	matchingPluginIDs := intersect(allPluginIDs, pluginNames)
+
+	for pluginID, pluginName := range pluginNames {
+		if _, found := matchingPluginIDs[pluginID]; found && riskFactors[pluginID] != "None" {
+			supportedPlugins = append(supportedPlugins, pluginName)
+		} else {
+			missingPlugins = append(missingPlugins, pluginName)
+		}
+	}
+
+	sort.Strings(supportedPlugins)
+	sort.Strings(missingPlugins)
+	return supportedPlugins, missingPlugins
 }
 
 func getAllPluginIDs(plugins map[string]config.Plugin) map[string]struct{} {
-    pluginIDs := make(map[string]struct{})
-    for _, plugin := range plugins {
-        for _, id := range plugin.IDs {
-            pluginIDs[id] = struct{}{}
-        }
-    }
-    return pluginIDs
+	pluginIDs := make(map[string]struct{})
+	for _, plugin := range plugins {
+		for _, id := range plugin.IDs {
+			pluginIDs[id] = struct{}{}
+		}
+	}
+	return pluginIDs
 }
 
 func intersect(a map[string]struct{}, b map[string]string) map[string]struct{} {
-    result := make(map[string]struct{})
-    for k := range b {
-        if _, found := a[k]; found {
-            result[k] = struct{}{}
-        }
-    }
-    return result
+	result := make(map[string]struct{})
+	for k := range b {
+		if _, found := a[k]; found {
+			result[k] = struct{}{}
+		}
+	}
+	return result
 }
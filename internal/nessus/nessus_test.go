@@ -0,0 +1,26 @@
+package nessus
+
+import "testing"
+
+func TestDedupeMergesSources(t *testing.T) {
+	findings := []Finding{
+		{PluginID: "10079", Host: "10.0.0.1", Source: "internal/scan.csv"},
+		{PluginID: "10079", Host: "10.0.0.2", Source: "external/scan.csv"},
+		{PluginID: "10079", Host: "10.0.0.3", Source: "internal/scan.csv"},
+		{PluginID: "42873", Host: "10.0.0.1", Source: "external/scan.csv"},
+	}
+
+	deduped, pluginData := Dedupe(findings)
+	if len(deduped) != 2 {
+		t.Fatalf("got %d findings, want 2", len(deduped))
+	}
+	if got, want := deduped[0].Source, "internal/scan.csv, external/scan.csv"; got != want {
+		t.Errorf("source = %q, want %q", got, want)
+	}
+	if deduped[0].Host != "10.0.0.1" {
+		t.Errorf("host = %s, want the first finding's 10.0.0.1", deduped[0].Host)
+	}
+	if got := pluginData["42873"].Source; got != "external/scan.csv" {
+		t.Errorf("plugin data source = %q, want external/scan.csv", got)
+	}
+}
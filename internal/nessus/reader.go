@@ -0,0 +1,217 @@
+package nessus
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// Canonical column names. They match the headers of a default Nessus CSV
+// export, so rows can be indexed the same way whatever tool produced them.
+const (
+	ColumnPluginID     = "Plugin ID"
+	ColumnCVE          = "CVE"
+	ColumnCVSS         = "CVSS v2.0 Base Score"
+	ColumnRisk         = "Risk"
+	ColumnHost         = "Host"
+	ColumnProtocol     = "Protocol"
+	ColumnPort         = "Port"
+	ColumnName         = "Name"
+	ColumnSynopsis     = "Synopsis"
+	ColumnDescription  = "Description"
+	ColumnSolution     = "Solution"
+	ColumnSeeAlso      = "See Also"
+	ColumnPluginOutput = "Plugin Output"
+)
+
+// columnAliases lists, in order of preference, the header names Nessus,
+// Tenable.io and Tenable.sc exports use for each canonical column. Generic
+// names such as "ID" are left out, as other exports use them for assets.
+var columnAliases = map[string][]string{
+	ColumnPluginID:     {"plugin id", "pluginid", "plugin_id"},
+	ColumnCVE:          {"cve", "cves"},
+	ColumnCVSS:         {"cvss v2.0 base score", "cvss v2 base score", "cvss base score", "cvss"},
+	ColumnRisk:         {"risk", "risk factor", "severity"},
+	ColumnHost:         {"host", "ip address", "ip", "asset ip address", "dns name", "fqdn", "hostname", "asset name"},
+	ColumnProtocol:     {"protocol", "proto"},
+	ColumnPort:         {"port"},
+	ColumnName:         {"name", "plugin name", "plugin_name", "title"},
+	ColumnSynopsis:     {"synopsis"},
+	ColumnDescription:  {"description"},
+	ColumnSolution:     {"solution", "steps to remediate"},
+	ColumnSeeAlso:      {"see also"},
+	ColumnPluginOutput: {"plugin output", "plugin text"},
+}
+
+// MissingColumnsError is returned when a CSV header lacks columns the caller
+// requires
+type MissingColumnsError struct {
+	Columns []string
+}
+
+func (e *MissingColumnsError) Error() string {
+	return fmt.Sprintf("CSV is missing required columns: %s", strings.Join(e.Columns, ", "))
+}
+
+// Row is a single CSV record keyed by canonical column name. Columns without a
+// canonical name are kept under their original header.
+type Row map[string]string
+
+// Reader reads Nessus style CSV exports, mapping columns by header name
+type Reader struct {
+	csv     *csv.Reader
+	header  []string
+	columns map[string]int
+}
+
+// NewReader reads the header row and resolves the column mapping. It fails
+// with a *MissingColumnsError if any of the required columns is absent.
+func NewReader(r io.Reader, required ...string) (*Reader, error) {
+	reader := csv.NewReader(r)
+	reader.LazyQuotes = true
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, fmt.Errorf("empty CSV file")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CSV header: %w", err)
+	}
+
+	for i := range header {
+		header[i] = strings.TrimSpace(strings.TrimPrefix(header[i], "\ufeff"))
+	}
+
+	nr := &Reader{
+		csv:     reader,
+		header:  header,
+		columns: mapColumns(header),
+	}
+
+	var missing []string
+	for _, column := range required {
+		if !nr.Has(column) {
+			missing = append(missing, column)
+		}
+	}
+	if len(missing) > 0 {
+		return nil, &MissingColumnsError{Columns: missing}
+	}
+
+	return nr, nil
+}
+
+func mapColumns(header []string) map[string]int {
+	index := make(map[string]int, len(header))
+	for i, name := range header {
+		lower := strings.ToLower(name)
+		if _, exists := index[lower]; !exists {
+			index[lower] = i
+		}
+	}
+
+	columns := make(map[string]int)
+	for canonical, aliases := range columnAliases {
+		for _, alias := range aliases {
+			if i, ok := index[alias]; ok {
+				columns[canonical] = i
+				break
+			}
+		}
+	}
+	return columns
+}
+
+// Has reports whether the header contains the canonical column
+func (r *Reader) Has(column string) bool {
+	_, ok := r.columns[column]
+	return ok
+}
+
+// Header returns the original header row
+func (r *Reader) Header() []string {
+	return r.header
+}
+
+// Read returns the next record, or io.EOF when there are no more
+func (r *Reader) Read() (Row, error) {
+	record, err := r.csv.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, io.EOF
+		}
+		return nil, err
+	}
+
+	row := make(Row, len(r.header)+len(r.columns))
+	for i, name := range r.header {
+		if i < len(record) {
+			row[name] = strings.TrimSpace(record[i])
+		}
+	}
+	for canonical, i := range r.columns {
+		if i < len(record) {
+			row[canonical] = strings.TrimSpace(record[i])
+		} else {
+			row[canonical] = ""
+		}
+	}
+	if _, ok := r.columns[ColumnRisk]; ok {
+		row[ColumnRisk] = NormalizeRisk(row[ColumnRisk])
+	}
+
+	return row, nil
+}
+
+// ReadAll reads the remaining records
+func (r *Reader) ReadAll() ([]Row, error) {
+	var rows []Row
+	for {
+		row, err := r.Read()
+		if err == io.EOF {
+			return rows, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		rows = append(rows, row)
+	}
+}
+
+// ReadFile opens a CSV export and reads all of its records
+func ReadFile(filePath string, required ...string) ([]Row, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	reader, err := NewReader(file, required...)
+	if err != nil {
+		return nil, err
+	}
+	return reader.ReadAll()
+}
+
+// NormalizeRisk maps the severity spellings used by the different exports
+// onto the Nessus risk names (Critical, High, Medium, Low, None).
+func NormalizeRisk(risk string) string {
+	switch strings.ToLower(strings.TrimSpace(risk)) {
+	case "critical", "4":
+		return "Critical"
+	case "high", "3":
+		return "High"
+	case "medium", "2":
+		return "Medium"
+	case "low", "1":
+		return "Low"
+	case "none", "info", "informational", "0":
+		return "None"
+	default:
+		return risk
+	}
+}
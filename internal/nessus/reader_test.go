@@ -0,0 +1,89 @@
+package nessus
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReaderMapsColumnAliases(t *testing.T) {
+	tests := []struct {
+		name   string
+		csv    string
+		want   Row
+		absent []string
+	}{
+		{
+			name: "nessus",
+			csv: "\ufeffPlugin ID,CVE,Risk,Host,Protocol,Port,Name,Plugin Output\n" +
+				"10079,,Low,10.0.0.1,tcp,21,Anonymous FTP, allowed \n",
+			want: Row{
+				ColumnPluginID:     "10079",
+				ColumnRisk:         "Low",
+				ColumnHost:         "10.0.0.1",
+				ColumnPort:         "21",
+				ColumnName:         "Anonymous FTP",
+				ColumnPluginOutput: "allowed",
+			},
+		},
+		{
+			name: "tenable.io",
+			csv: "Asset UUID,IP Address,Plugin ID,Plugin Name,Severity,Port,Protocol\n" +
+				"abc,10.0.0.2,42873,SSL Medium Strength,2,443,tcp\n",
+			want: Row{
+				ColumnPluginID: "42873",
+				ColumnRisk:     "Medium",
+				ColumnHost:     "10.0.0.2",
+				ColumnPort:     "443",
+				ColumnName:     "SSL Medium Strength",
+				"Asset UUID":   "abc",
+			},
+		},
+		{
+			name: "generic names are not aliases",
+			csv: "ID,Plugin,Host,Port,Output\n" +
+				"7,Scanner,10.0.0.3,80,text\n",
+			want:   Row{ColumnHost: "10.0.0.3", ColumnPort: "80", "ID": "7"},
+			absent: []string{ColumnPluginID, ColumnPluginOutput},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			reader, err := NewReader(strings.NewReader(test.csv))
+			if err != nil {
+				t.Fatal(err)
+			}
+			rows, err := reader.ReadAll()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(rows) != 1 {
+				t.Fatalf("got %d rows, want 1", len(rows))
+			}
+			for column, want := range test.want {
+				if got := rows[0][column]; got != want {
+					t.Errorf("%s = %q, want %q", column, got, want)
+				}
+			}
+			for _, column := range test.absent {
+				if reader.Has(column) {
+					t.Errorf("%s is mapped to %q", column, reader.Header()[reader.columns[column]])
+				}
+			}
+		})
+	}
+}
+
+func TestReaderMissingColumns(t *testing.T) {
+	_, err := NewReader(strings.NewReader("Host,Port\n10.0.0.1,22\n"), ColumnPluginID, ColumnHost, ColumnName)
+
+	var missing *MissingColumnsError
+	if !errors.As(err, &missing) {
+		t.Fatalf("err = %v, want a *MissingColumnsError", err)
+	}
+	if want := []string{ColumnPluginID, ColumnName}; !reflect.DeepEqual(missing.Columns, want) {
+		t.Errorf("missing = %v, want %v", missing.Columns, want)
+	}
+}
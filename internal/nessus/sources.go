@@ -0,0 +1,84 @@
+package nessus
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ResolveSources expands a comma separated list of exports and directories
+// into the export files to read. Directories contribute their top level .csv
+// and .nessus files.
+func ResolveSources(spec string) ([]string, error) {
+	var sources []string
+	seen := make(map[string]struct{})
+
+	add := func(path string) {
+		if _, exists := seen[path]; exists {
+			return
+		}
+		seen[path] = struct{}{}
+		sources = append(sources, path)
+	}
+
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		info, err := os.Stat(entry)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read Nessus source %s: %w", entry, err)
+		}
+
+		if !info.IsDir() {
+			add(entry)
+			continue
+		}
+
+		files, err := os.ReadDir(entry)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read directory %s: %w", entry, err)
+		}
+
+		var found []string
+		for _, file := range files {
+			if !file.IsDir() && isExportFile(file.Name()) {
+				found = append(found, filepath.Join(entry, file.Name()))
+			}
+		}
+		if len(found) == 0 {
+			return nil, fmt.Errorf("no .csv or .nessus files found in %s", entry)
+		}
+
+		sort.Strings(found)
+		for _, path := range found {
+			add(path)
+		}
+	}
+
+	if len(sources) == 0 {
+		return nil, fmt.Errorf("no Nessus files given")
+	}
+
+	return sources, nil
+}
+
+// ReadSource reads a CSV or .nessus export, picking the parser by extension
+func ReadSource(filePath string) ([]Row, error) {
+	if strings.EqualFold(filepath.Ext(filePath), ".nessus") {
+		return ReadNessusFile(filePath)
+	}
+	return ReadFile(filePath, ColumnPluginID, ColumnHost, ColumnPort, ColumnName)
+}
+
+func isExportFile(name string) bool {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".csv", ".nessus":
+		return true
+	}
+	return false
+}
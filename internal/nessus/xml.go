@@ -0,0 +1,88 @@
+package nessus
+
+import (
+	"encoding/xml"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// nessusClientData mirrors the parts of a .nessus (NessusClientData_v2) export
+// NMB uses
+type nessusClientData struct {
+	Report struct {
+		Name  string `xml:"name,attr"`
+		Hosts []struct {
+			Name       string `xml:"name,attr"`
+			Properties []struct {
+				Name  string `xml:"name,attr"`
+				Value string `xml:",chardata"`
+			} `xml:"HostProperties>tag"`
+			Items []struct {
+				Port         string   `xml:"port,attr"`
+				Protocol     string   `xml:"protocol,attr"`
+				Severity     string   `xml:"severity,attr"`
+				PluginID     string   `xml:"pluginID,attr"`
+				PluginName   string   `xml:"pluginName,attr"`
+				RiskFactor   string   `xml:"risk_factor"`
+				Synopsis     string   `xml:"synopsis"`
+				Description  string   `xml:"description"`
+				Solution     string   `xml:"solution"`
+				SeeAlso      string   `xml:"see_also"`
+				PluginOutput string   `xml:"plugin_output"`
+				CVSS         string   `xml:"cvss_base_score"`
+				CVEs         []string `xml:"cve"`
+			} `xml:"ReportItem"`
+		} `xml:"ReportHost"`
+	} `xml:"Report"`
+}
+
+// ReadNessusFile reads a .nessus XML export into rows keyed by the same
+// canonical column names as a CSV export
+func ReadNessusFile(filePath string) ([]Row, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	var export nessusClientData
+	if err := xml.Unmarshal(data, &export); err != nil {
+		return nil, fmt.Errorf("failed to parse .nessus file: %w", err)
+	}
+
+	var rows []Row
+	for _, host := range export.Report.Hosts {
+		address := host.Name
+		for _, property := range host.Properties {
+			if property.Name == "host-ip" && property.Value != "" {
+				address = strings.TrimSpace(property.Value)
+				break
+			}
+		}
+
+		for _, item := range host.Items {
+			risk := item.RiskFactor
+			if risk == "" {
+				risk = item.Severity
+			}
+
+			rows = append(rows, Row{
+				ColumnPluginID:     strings.TrimSpace(item.PluginID),
+				ColumnCVE:          strings.Join(item.CVEs, ","),
+				ColumnCVSS:         strings.TrimSpace(item.CVSS),
+				ColumnRisk:         NormalizeRisk(risk),
+				ColumnHost:         address,
+				ColumnProtocol:     item.Protocol,
+				ColumnPort:         item.Port,
+				ColumnName:         strings.TrimSpace(item.PluginName),
+				ColumnSynopsis:     strings.TrimSpace(item.Synopsis),
+				ColumnDescription:  strings.TrimSpace(item.Description),
+				ColumnSolution:     strings.TrimSpace(item.Solution),
+				ColumnSeeAlso:      strings.TrimSpace(item.SeeAlso),
+				ColumnPluginOutput: strings.TrimSpace(item.PluginOutput),
+			})
+		}
+	}
+
+	return rows, nil
+}
@@ -0,0 +1,147 @@
+package nessusapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Text is a value Nessus returns as a string, a number or a list of strings
+// depending on the version and plugin. Lists are joined with newlines.
+type Text string
+
+func (t *Text) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		*t = Text(s)
+		return nil
+	}
+
+	var list []string
+	if err := json.Unmarshal(data, &list); err == nil {
+		*t = Text(strings.Join(list, "\n"))
+		return nil
+	}
+
+	var number json.Number
+	if err := json.Unmarshal(data, &number); err == nil {
+		*t = Text(number.String())
+		return nil
+	}
+
+	if string(data) == "null" {
+		*t = ""
+		return nil
+	}
+	return fmt.Errorf("unexpected value %s", data)
+}
+
+// HostInfo describes a scanned host
+type HostInfo struct {
+	IP              Text `json:"host-ip"`
+	FQDN            Text `json:"host-fqdn"`
+	OperatingSystem Text `json:"operating-system"`
+	MACAddress      Text `json:"mac-address"`
+	NetBIOSName     Text `json:"netbios-name"`
+	Start           Text `json:"host_start"`
+	End             Text `json:"host_end"`
+}
+
+// HostDetails is the response of GET /scans/{id}/hosts/{host_id}
+type HostDetails struct {
+	Info            HostInfo        `json:"info"`
+	Vulnerabilities []Vulnerability `json:"vulnerabilities"`
+}
+
+// PluginDescription describes the plugin behind a finding
+type PluginDescription struct {
+	ID         Text             `json:"pluginid"`
+	Name       string           `json:"pluginname"`
+	Family     string           `json:"pluginfamily"`
+	Severity   int              `json:"severity"`
+	Attributes PluginAttributes `json:"pluginattributes"`
+}
+
+// PluginAttributes are the plugin's write-up
+type PluginAttributes struct {
+	Synopsis        Text `json:"synopsis"`
+	Description     Text `json:"description"`
+	Solution        Text `json:"solution"`
+	SeeAlso         Text `json:"see_also"`
+	RiskInformation struct {
+		RiskFactor Text `json:"risk_factor"`
+	} `json:"risk_information"`
+}
+
+// PluginOutput is one output of a plugin, with the ports it was seen on as
+// "port / protocol / service" keys
+type PluginOutput struct {
+	Output   string                `json:"plugin_output"`
+	Severity int                   `json:"severity"`
+	Ports    map[string][]PortHost `json:"ports"`
+}
+
+// PortHost is a host a plugin output was seen on
+type PortHost struct {
+	Hostname string `json:"hostname"`
+}
+
+// PortList returns the output's ports, sorted
+func (o PluginOutput) PortList() []string {
+	ports := make([]string, 0, len(o.Ports))
+	for port := range o.Ports {
+		ports = append(ports, port)
+	}
+	sort.Strings(ports)
+	return ports
+}
+
+// PluginDetails is the response of
+// GET /scans/{id}/hosts/{host_id}/plugins/{plugin_id}
+type PluginDetails struct {
+	Info struct {
+		Description PluginDescription `json:"plugindescription"`
+	} `json:"info"`
+	Outputs []PluginOutput `json:"outputs"`
+}
+
+// HostDetails returns a host's information and findings. A historyID of 0
+// reads the latest run.
+func (c *Client) HostDetails(scanID, hostID, historyID int) (*HostDetails, error) {
+	var details HostDetails
+	path := withHistory(fmt.Sprintf("/scans/%d/hosts/%d", scanID, hostID), historyID)
+	if err := c.do(http.MethodGet, path, nil, &details); err != nil {
+		return nil, err
+	}
+	return &details, nil
+}
+
+// PluginOutput returns the output of one plugin on one host. A historyID of
+// 0 reads the latest run.
+func (c *Client) PluginOutput(scanID, hostID, pluginID, historyID int) (*PluginDetails, error) {
+	var details PluginDetails
+	path := withHistory(fmt.Sprintf("/scans/%d/hosts/%d/plugins/%d", scanID, hostID, pluginID), historyID)
+	if err := c.do(http.MethodGet, path, nil, &details); err != nil {
+		return nil, err
+	}
+	return &details, nil
+}
+
+// ScanHistory returns the previous runs of a scan, newest last
+func (c *Client) ScanHistory(scanID int) ([]History, error) {
+	details, err := c.ScanDetails(scanID)
+	if err != nil {
+		return nil, err
+	}
+	return details.History, nil
+}
+
+func withHistory(path string, historyID int) string {
+	if historyID == 0 {
+		return path
+	}
+	return path + "?history_id=" + strconv.Itoa(historyID)
+}
@@ -0,0 +1,546 @@
+// Package nessusapi is a typed client for the Nessus REST API. Responses are
+// decoded into structs and non-2xx responses are returned as *APIError.
+package nessusapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"strings"
+)
+
+// Client talks to one Nessus server
+type Client struct {
+	BaseURL    string
+	HTTPClient *http.Client
+
+	// Authorize adds the authentication headers to each request
+	Authorize func(*http.Request)
+
+	// Reauthorize renews the session after Nessus rejected a request with
+	// a 401. The rejected request is then sent once more with the headers
+	// from Authorize.
+	Reauthorize func(rejected *http.Request) error
+}
+
+// authHeaders are the headers Authorize may set, cleared before a retry
+var authHeaders = []string{"X-Cookie", "X-API-Token", "X-ApiKeys"}
+
+// NewClient creates a client for a Nessus base URL such as
+// https://10.0.0.5:8834
+func NewClient(baseURL string, httpClient *http.Client, authorize func(*http.Request)) *Client {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return &Client{
+		BaseURL:    strings.TrimSuffix(baseURL, "/"),
+		HTTPClient: httpClient,
+		Authorize:  authorize,
+	}
+}
+
+// APIError is a non-2xx response from Nessus
+type APIError struct {
+	Method     string
+	Path       string
+	StatusCode int
+	Status     string
+	Message    string
+}
+
+func (e *APIError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("%s %s: %s", e.Method, e.Path, e.Status)
+	}
+	return fmt.Sprintf("%s %s: %s - %s", e.Method, e.Path, e.Status, e.Message)
+}
+
+// IsNotFound reports whether err is a 404 from Nessus
+func IsNotFound(err error) bool {
+	return hasStatus(err, http.StatusNotFound)
+}
+
+// IsUnauthorized reports whether err is a 401 from Nessus, usually an
+// expired session
+func IsUnauthorized(err error) bool {
+	return hasStatus(err, http.StatusUnauthorized)
+}
+
+// ErrInvalidArgument marks errors caused by a malformed ID or option passed
+// in by the caller rather than by Nessus
+var ErrInvalidArgument = errors.New("invalid argument")
+
+// IsInvalidArgument reports whether err is an ErrInvalidArgument or a 400
+// from Nessus
+func IsInvalidArgument(err error) bool {
+	return errors.Is(err, ErrInvalidArgument) || hasStatus(err, http.StatusBadRequest)
+}
+
+func hasStatus(err error, status int) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == status
+}
+
+// ErrNotReady is returned by Download while an export is still being
+// generated
+var ErrNotReady = errors.New("export is not ready")
+
+// Scan is an entry of the scan list
+type Scan struct {
+	ID                   int    `json:"id"`
+	UUID                 string `json:"uuid"`
+	Name                 string `json:"name"`
+	Status               string `json:"status"`
+	FolderID             int    `json:"folder_id"`
+	Owner                string `json:"owner"`
+	Enabled              bool   `json:"enabled"`
+	CreationDate         int64  `json:"creation_date"`
+	LastModificationDate int64  `json:"last_modification_date"`
+	StartTime            string `json:"starttime"`
+	RRules               string `json:"rrules"`
+	Timezone             string `json:"timezone"`
+}
+
+// ScanList is the response of GET /scans
+type ScanList struct {
+	Scans     []Scan   `json:"scans"`
+	Folders   []Folder `json:"folders"`
+	Timestamp int64    `json:"timestamp"`
+}
+
+// ScanInfo is the info section of a scan's details
+type ScanInfo struct {
+	Name        string `json:"name"`
+	UUID        string `json:"uuid"`
+	Status      string `json:"status"`
+	Targets     string `json:"targets"`
+	Policy      string `json:"policy"`
+	ScannerName string `json:"scanner_name"`
+	FolderID    int    `json:"folder_id"`
+	HostCount   int    `json:"hostcount"`
+	ScanStart   int64  `json:"scan_start"`
+	ScanEnd     int64  `json:"scan_end"`
+}
+
+// Host is a scanned host with its finding counts by severity
+type Host struct {
+	ID                  int    `json:"host_id"`
+	Hostname            string `json:"hostname"`
+	Progress            string `json:"progress"`
+	ScanProgressCurrent int    `json:"scanprogresscurrent"`
+	ScanProgressTotal   int    `json:"scanprogresstotal"`
+	Critical            int    `json:"critical"`
+	High                int    `json:"high"`
+	Medium              int    `json:"medium"`
+	Low                 int    `json:"low"`
+	Info                int    `json:"info"`
+}
+
+// Vulnerability is a plugin that reported on one or more hosts. Severity runs
+// from 0 (info) to 4 (critical).
+type Vulnerability struct {
+	PluginID     int    `json:"plugin_id"`
+	PluginName   string `json:"plugin_name"`
+	PluginFamily string `json:"plugin_family"`
+	Count        int    `json:"count"`
+	Severity     int    `json:"severity"`
+}
+
+// History is a previous run of a scan
+type History struct {
+	ID                   int    `json:"history_id"`
+	UUID                 string `json:"uuid"`
+	Status               string `json:"status"`
+	Type                 string `json:"type"`
+	CreationDate         int64  `json:"creation_date"`
+	LastModificationDate int64  `json:"last_modification_date"`
+}
+
+// ScanDetails is the response of GET /scans/{id}
+type ScanDetails struct {
+	Info            ScanInfo        `json:"info"`
+	Hosts           []Host          `json:"hosts"`
+	Vulnerabilities []Vulnerability `json:"vulnerabilities"`
+	History         []History       `json:"history"`
+}
+
+// Progress is the scan's completion in percent, from the hosts' progress
+func (d *ScanDetails) Progress() float64 {
+	var current, total int
+	for _, host := range d.Hosts {
+		current += host.ScanProgressCurrent
+		total += host.ScanProgressTotal
+	}
+	if total == 0 {
+		if d.Info.Status == "completed" {
+			return 100
+		}
+		return 0
+	}
+	return float64(current) * 100 / float64(total)
+}
+
+// Severities names the severity levels by their number
+var Severities = []string{"info", "low", "medium", "high", "critical"}
+
+// SeverityCounts totals the findings by severity name
+func (d *ScanDetails) SeverityCounts() map[string]int {
+	counts := make(map[string]int, len(Severities))
+	for _, name := range Severities {
+		counts[name] = 0
+	}
+	for _, vuln := range d.Vulnerabilities {
+		if vuln.Severity >= 0 && vuln.Severity < len(Severities) {
+			counts[Severities[vuln.Severity]] += vuln.Count
+		}
+	}
+	return counts
+}
+
+// Policy is a scan policy
+type Policy struct {
+	ID                   int    `json:"id"`
+	TemplateUUID         string `json:"template_uuid"`
+	Name                 string `json:"name"`
+	Description          string `json:"description"`
+	Owner                string `json:"owner"`
+	Visibility           string `json:"visibility"`
+	CreationDate         int64  `json:"creation_date"`
+	LastModificationDate int64  `json:"last_modification_date"`
+}
+
+// Scanner is a scanner linked to the Nessus server
+type Scanner struct {
+	ID     int    `json:"id"`
+	UUID   string `json:"uuid"`
+	Name   string `json:"name"`
+	Type   string `json:"type"`
+	Status string `json:"status"`
+}
+
+// Folder is a scan folder
+type Folder struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// Template is a report template used by html and pdf exports
+type Template struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+// ExportToken identifies a requested export
+type ExportToken struct {
+	File  int    `json:"file"`
+	Token string `json:"token"`
+}
+
+// Session is the user a client is authenticated as
+type Session struct {
+	ID          int    `json:"id"`
+	Username    string `json:"username"`
+	Name        string `json:"name"`
+	Permissions int    `json:"permissions"`
+	LastLogin   int64  `json:"lastlogin"`
+}
+
+// Session returns the authenticated user, which also checks the credentials
+func (c *Client) Session() (*Session, error) {
+	var session Session
+	if err := c.do(http.MethodGet, "/session", nil, &session); err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// ListScans returns every scan on the server
+func (c *Client) ListScans() (*ScanList, error) {
+	var list ScanList
+	if err := c.do(http.MethodGet, "/scans", nil, &list); err != nil {
+		return nil, err
+	}
+	return &list, nil
+}
+
+// ScanDetails returns a scan's info, hosts, vulnerabilities and history
+func (c *Client) ScanDetails(scanID int) (*ScanDetails, error) {
+	var details ScanDetails
+	if err := c.do(http.MethodGet, fmt.Sprintf("/scans/%d", scanID), nil, &details); err != nil {
+		return nil, err
+	}
+	return &details, nil
+}
+
+// CreateScan creates a scan from a template UUID and its settings
+func (c *Client) CreateScan(templateUUID string, settings map[string]interface{}) (*Scan, error) {
+	request := map[string]interface{}{
+		"uuid":     templateUUID,
+		"settings": settings,
+	}
+	var result struct {
+		Scan Scan `json:"scan"`
+	}
+	if err := c.do(http.MethodPost, "/scans", request, &result); err != nil {
+		return nil, err
+	}
+	return &result.Scan, nil
+}
+
+// ScanAction runs an action such as launch, pause, resume or stop on a scan
+func (c *Client) ScanAction(scanID int, action string) error {
+	return c.do(http.MethodPost, fmt.Sprintf("/scans/%d/%s", scanID, action), nil, nil)
+}
+
+// DeleteScan deletes a scan and its results
+func (c *Client) DeleteScan(scanID int) error {
+	return c.do(http.MethodDelete, fmt.Sprintf("/scans/%d", scanID), nil, nil)
+}
+
+// ListPolicies returns the scan policies
+func (c *Client) ListPolicies() ([]Policy, error) {
+	var result struct {
+		Policies []Policy `json:"policies"`
+	}
+	if err := c.do(http.MethodGet, "/policies", nil, &result); err != nil {
+		return nil, err
+	}
+	return result.Policies, nil
+}
+
+// ImportPolicy imports a policy from a file previously sent to UploadFile
+func (c *Client) ImportPolicy(uploadedFile string) (*Policy, error) {
+	var policy Policy
+	if err := c.do(http.MethodPost, "/policies/import", map[string]string{"file": uploadedFile}, &policy); err != nil {
+		return nil, err
+	}
+	if policy.ID == 0 {
+		return nil, fmt.Errorf("policy import returned no policy ID")
+	}
+	return &policy, nil
+}
+
+// ListScanners returns the scanners linked to the server
+func (c *Client) ListScanners() ([]Scanner, error) {
+	var result struct {
+		Scanners []Scanner `json:"scanners"`
+	}
+	if err := c.do(http.MethodGet, "/scanners", nil, &result); err != nil {
+		return nil, err
+	}
+	return result.Scanners, nil
+}
+
+// ListFolders returns the scan folders
+func (c *Client) ListFolders() ([]Folder, error) {
+	var result struct {
+		Folders []Folder `json:"folders"`
+	}
+	if err := c.do(http.MethodGet, "/folders", nil, &result); err != nil {
+		return nil, err
+	}
+	return result.Folders, nil
+}
+
+// ListReportTemplates returns the report templates
+func (c *Client) ListReportTemplates() ([]Template, error) {
+	var templates []Template
+	if err := c.do(http.MethodGet, "/reports/custom/templates", nil, &templates); err != nil {
+		return nil, err
+	}
+	return templates, nil
+}
+
+// UploadFile sends a file to the server and returns the name it was stored
+// under
+func (c *Client) UploadFile(name string, content io.Reader) (string, error) {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	part, err := writer.CreateFormFile("Filedata", name)
+	if err != nil {
+		return "", err
+	}
+	if _, err := io.Copy(part, content); err != nil {
+		return "", err
+	}
+	if err := writer.Close(); err != nil {
+		return "", err
+	}
+
+	req, err := c.newRequest(http.MethodPost, "/file/upload", &body)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+
+	var result struct {
+		FileUploaded string `json:"fileuploaded"`
+	}
+	if err := c.send(req, &result); err != nil {
+		return "", err
+	}
+	if result.FileUploaded == "" {
+		return "", fmt.Errorf("upload of %s returned no file name", name)
+	}
+	return result.FileUploaded, nil
+}
+
+// Export requests an export of a scan. The request is the export body: the
+// format plus any template, columns and filters.
+func (c *Client) Export(scanID int, request interface{}) (*ExportToken, error) {
+	var result struct {
+		ExportToken
+		Error string `json:"error"`
+	}
+	if err := c.do(http.MethodPost, fmt.Sprintf("/scans/%d/export", scanID), request, &result); err != nil {
+		return nil, err
+	}
+	if result.Token == "" {
+		if result.Error != "" {
+			return nil, fmt.Errorf("no export token received: %s", result.Error)
+		}
+		return nil, fmt.Errorf("no export token received")
+	}
+	return &result.ExportToken, nil
+}
+
+// Download fetches a finished export, returning ErrNotReady while it is
+// still being generated
+func (c *Client) Download(token string) ([]byte, error) {
+	req, err := c.newRequest(http.MethodGet, "/tokens/"+token+"/download", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.roundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %v", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, newAPIError(req, resp, body)
+	}
+	if bytes.Contains(body, []byte("not ready")) {
+		return nil, ErrNotReady
+	}
+	return body, nil
+}
+
+// do sends a JSON request and decodes the JSON response into out, which may
+// be nil when the response is not needed
+func (c *Client) do(method, path string, in, out interface{}) error {
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return fmt.Errorf("failed to serialize request: %v", err)
+		}
+		body = bytes.NewReader(data)
+	}
+
+	req, err := c.newRequest(method, path, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	return c.send(req, out)
+}
+
+func (c *Client) newRequest(method, path string, body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequest(method, c.BaseURL+path, body)
+	if err != nil {
+		return nil, err
+	}
+	if c.Authorize != nil {
+		c.Authorize(req)
+	}
+	return req, nil
+}
+
+func (c *Client) send(req *http.Request, out interface{}) error {
+	resp, err := c.roundTrip(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		body, _ := io.ReadAll(resp.Body)
+		return newAPIError(req, resp, body)
+	}
+
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode %s response: %v", req.URL.Path, err)
+	}
+	return nil
+}
+
+// roundTrip sends a request, renewing the session and sending it again when
+// it is rejected with a 401
+func (c *Client) roundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil || resp.StatusCode != http.StatusUnauthorized || c.Reauthorize == nil {
+		return resp, err
+	}
+	if req.Body != nil && req.GetBody == nil {
+		// The body was consumed and cannot be sent again
+		return resp, nil
+	}
+
+	if err := c.Reauthorize(req); err != nil {
+		resp.Body.Close()
+		return nil, fmt.Errorf("failed to renew Nessus session: %w", err)
+	}
+
+	retry := req.Clone(req.Context())
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return resp, nil
+		}
+		retry.Body = body
+	}
+	for _, header := range authHeaders {
+		retry.Header.Del(header)
+	}
+	if c.Authorize != nil {
+		c.Authorize(retry)
+	}
+
+	resp.Body.Close()
+	return c.HTTPClient.Do(retry)
+}
+
+// newAPIError uses the error field of a JSON error body as the message, or
+// the raw body otherwise
+func newAPIError(req *http.Request, resp *http.Response, body []byte) *APIError {
+	message := strings.TrimSpace(string(body))
+	var result struct {
+		Error string `json:"error"`
+	}
+	if json.Unmarshal(body, &result) == nil && result.Error != "" {
+		message = result.Error
+	}
+
+	return &APIError{
+		Method:     req.Method,
+		Path:       req.URL.Path,
+		StatusCode: resp.StatusCode,
+		Status:     resp.Status,
+		Message:    message,
+	}
+}
@@ -0,0 +1,324 @@
+package nessusapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// fakeNessus serves canned responses for a few Nessus endpoints and rejects
+// requests without the expected API keys
+func fakeNessus(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /session", func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, `{"id":2,"username":"nmb","name":"nmb","permissions":128,"lastlogin":1700000000}`)
+	})
+	mux.HandleFunc("GET /scans", func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, `{"folders":[{"id":3,"name":"My Scans","type":"main"}],
+			"scans":[{"id":5,"uuid":"abc","name":"weekly","status":"running","folder_id":3,"creation_date":1700000000}],
+			"timestamp":1700000100}`)
+	})
+	mux.HandleFunc("GET /scans/5", func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, `{"info":{"name":"weekly","status":"running","targets":"10.0.0.0/24","hostcount":2},
+			"hosts":[{"host_id":1,"hostname":"10.0.0.1","scanprogresscurrent":50,"scanprogresstotal":100,"critical":1},
+				{"host_id":2,"hostname":"10.0.0.2","scanprogresscurrent":100,"scanprogresstotal":100}],
+			"vulnerabilities":[{"plugin_id":10881,"plugin_name":"SSH Protocol Versions Supported","count":2,"severity":0},
+				{"plugin_id":20007,"plugin_name":"SSL Version 2 and 3 Protocol Detection","count":1,"severity":4},
+				{"plugin_id":57582,"plugin_name":"SSL Self-Signed Certificate","count":3,"severity":2}],
+			"history":[{"history_id":9,"status":"completed","creation_date":1690000000}]}`)
+	})
+	mux.HandleFunc("GET /scans/6", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		io.WriteString(w, `{"error":"The requested file was not found."}`)
+	})
+	mux.HandleFunc("GET /scans/5/hosts/1", func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, `{"info":{"host-ip":"10.0.0.1","host-fqdn":"web01.local","operating-system":["Linux Kernel 5.4","Ubuntu 20.04"]},
+			"vulnerabilities":[{"plugin_id":20007,"plugin_name":"SSL Version 2 and 3 Protocol Detection","count":1,"severity":4}]}`)
+	})
+	mux.HandleFunc("GET /scans/5/hosts/1/plugins/20007", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("history_id") != "9" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		io.WriteString(w, `{"info":{"plugindescription":{"pluginid":"20007","pluginname":"SSL Version 2 and 3 Protocol Detection","severity":4,
+				"pluginattributes":{"synopsis":"Weak SSL","see_also":["https://example.com/a","https://example.com/b"],"risk_information":{"risk_factor":"Critical"}}}},
+			"outputs":[{"plugin_output":"SSLv3 is enabled","severity":4,"ports":{"995 / tcp / pop3":[{"hostname":"10.0.0.1"}],"443 / tcp / www":[{"hostname":"10.0.0.1"}]}}]}`)
+	})
+	mux.HandleFunc("POST /scans/5/pause", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusConflict)
+		io.WriteString(w, `{"error":"Scan is not running"}`)
+	})
+	mux.HandleFunc("POST /scans", func(w http.ResponseWriter, r *http.Request) {
+		var request struct {
+			UUID     string                 `json:"uuid"`
+			Settings map[string]interface{} `json:"settings"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil || request.UUID == "" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"scan": map[string]interface{}{"id": 7, "name": request.Settings["name"]},
+		})
+	})
+	mux.HandleFunc("GET /policies", func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, `{"policies":[{"id":4,"template_uuid":"tmpl","name":"Internal"}]}`)
+	})
+	mux.HandleFunc("POST /file/upload", func(w http.ResponseWriter, r *http.Request) {
+		file, header, err := r.FormFile("Filedata")
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		file.Close()
+		json.NewEncoder(w).Encode(map[string]string{"fileuploaded": "stored-" + header.Filename})
+	})
+	mux.HandleFunc("POST /scans/5/export", func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, `{"file":11,"token":"tok"}`)
+	})
+	mux.HandleFunc("POST /scans/8/export", func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, `{"error":"Report template not found"}`)
+	})
+	downloads := 0
+	mux.HandleFunc("GET /tokens/tok/download", func(w http.ResponseWriter, r *http.Request) {
+		downloads++
+		if downloads == 1 {
+			io.WriteString(w, `{"status":"not ready"}`)
+			return
+		}
+		io.WriteString(w, "Plugin ID,Host\n")
+	})
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-ApiKeys") != "accessKey=a; secretKey=b" {
+			w.WriteHeader(http.StatusUnauthorized)
+			io.WriteString(w, `{"error":"Invalid Credentials"}`)
+			return
+		}
+		mux.ServeHTTP(w, r)
+	}))
+}
+
+func newTestClient(t *testing.T) *Client {
+	server := fakeNessus(t)
+	t.Cleanup(server.Close)
+	return NewClient(server.URL, server.Client(), func(req *http.Request) {
+		req.Header.Set("X-ApiKeys", "accessKey=a; secretKey=b")
+	})
+}
+
+func TestListScans(t *testing.T) {
+	client := newTestClient(t)
+
+	list, err := client.ListScans()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list.Scans) != 1 || len(list.Folders) != 1 {
+		t.Fatalf("got %d scans and %d folders, want 1 and 1", len(list.Scans), len(list.Folders))
+	}
+	scan := list.Scans[0]
+	if scan.ID != 5 || scan.Name != "weekly" || scan.Status != "running" || scan.FolderID != 3 {
+		t.Errorf("unexpected scan %+v", scan)
+	}
+}
+
+func TestScanDetails(t *testing.T) {
+	client := newTestClient(t)
+
+	details, err := client.ScanDetails(5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if details.Info.Targets != "10.0.0.0/24" || details.Info.HostCount != 2 {
+		t.Errorf("unexpected info %+v", details.Info)
+	}
+	if len(details.Hosts) != 2 || details.Hosts[0].ID != 1 || details.Hosts[0].Critical != 1 {
+		t.Errorf("unexpected hosts %+v", details.Hosts)
+	}
+	if len(details.History) != 1 || details.History[0].ID != 9 {
+		t.Errorf("unexpected history %+v", details.History)
+	}
+	if progress := details.Progress(); progress != 75 {
+		t.Errorf("progress = %v, want 75", progress)
+	}
+
+	counts := details.SeverityCounts()
+	want := map[string]int{"critical": 1, "high": 0, "medium": 3, "low": 0, "info": 2}
+	for severity, count := range want {
+		if counts[severity] != count {
+			t.Errorf("%s = %d, want %d", severity, counts[severity], count)
+		}
+	}
+}
+
+func TestErrors(t *testing.T) {
+	client := newTestClient(t)
+
+	_, err := client.ScanDetails(6)
+	if !IsNotFound(err) {
+		t.Fatalf("expected a not found error, got %v", err)
+	}
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.Message != "The requested file was not found." || apiErr.Path != "/scans/6" {
+		t.Errorf("unexpected error %#v", err)
+	}
+
+	err = client.ScanAction(5, "pause")
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusConflict || apiErr.Message != "Scan is not running" {
+		t.Errorf("unexpected error %v", err)
+	}
+	if IsInvalidArgument(err) || IsInvalidArgument(errors.New("invalid scan ID")) {
+		t.Errorf("only ErrInvalidArgument and 400s are invalid arguments")
+	}
+	if !IsInvalidArgument(&APIError{StatusCode: http.StatusBadRequest}) || !IsInvalidArgument(fmt.Errorf("scan: %w", ErrInvalidArgument)) {
+		t.Errorf("400s and wrapped ErrInvalidArgument are invalid arguments")
+	}
+
+	session, err := client.Session()
+	if err != nil || session.Username != "nmb" {
+		t.Errorf("unexpected session %+v, %v", session, err)
+	}
+
+	client.Authorize = nil
+	if _, err := client.Session(); !IsUnauthorized(err) {
+		t.Errorf("expected an unauthorized error, got %v", err)
+	}
+}
+
+func TestCreateScan(t *testing.T) {
+	client := newTestClient(t)
+
+	scan, err := client.CreateScan("tmpl", map[string]interface{}{"name": "weekly-2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if scan.ID != 7 || scan.Name != "weekly-2" {
+		t.Errorf("unexpected scan %+v", scan)
+	}
+}
+
+func TestPoliciesAndUpload(t *testing.T) {
+	client := newTestClient(t)
+
+	policies, err := client.ListPolicies()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(policies) != 1 || policies[0].ID != 4 || policies[0].TemplateUUID != "tmpl" {
+		t.Errorf("unexpected policies %+v", policies)
+	}
+
+	name, err := client.UploadFile("policy.nessus", bytes.NewReader([]byte("<NessusClientData_v2/>")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name != "stored-policy.nessus" {
+		t.Errorf("uploaded as %q", name)
+	}
+}
+
+func TestExportAndDownload(t *testing.T) {
+	client := newTestClient(t)
+
+	token, err := client.Export(5, map[string]string{"format": "csv"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token.Token != "tok" || token.File != 11 {
+		t.Errorf("unexpected token %+v", token)
+	}
+
+	if _, err := client.Export(8, map[string]string{"format": "html"}); err == nil {
+		t.Error("expected an error for an export without a token")
+	}
+
+	if _, err := client.Download("tok"); !errors.Is(err, ErrNotReady) {
+		t.Fatalf("expected ErrNotReady, got %v", err)
+	}
+	data, err := client.Download("tok")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "Plugin ID,Host\n" {
+		t.Errorf("downloaded %q", data)
+	}
+}
+
+func TestDrillDown(t *testing.T) {
+	client := newTestClient(t)
+
+	host, err := client.HostDetails(5, 1, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if host.Info.IP != "10.0.0.1" || host.Info.OperatingSystem != "Linux Kernel 5.4\nUbuntu 20.04" {
+		t.Errorf("unexpected host info %+v", host.Info)
+	}
+	if len(host.Vulnerabilities) != 1 || host.Vulnerabilities[0].PluginID != 20007 {
+		t.Errorf("unexpected host vulnerabilities %+v", host.Vulnerabilities)
+	}
+
+	if _, err := client.PluginOutput(5, 1, 20007, 0); !IsNotFound(err) {
+		t.Errorf("expected the latest run to be missing, got %v", err)
+	}
+	plugin, err := client.PluginOutput(5, 1, 20007, 9)
+	if err != nil {
+		t.Fatal(err)
+	}
+	description := plugin.Info.Description
+	if description.ID != "20007" || description.Attributes.RiskInformation.RiskFactor != "Critical" ||
+		description.Attributes.SeeAlso != "https://example.com/a\nhttps://example.com/b" {
+		t.Errorf("unexpected plugin description %+v", description)
+	}
+	if len(plugin.Outputs) != 1 || plugin.Outputs[0].Output != "SSLv3 is enabled" {
+		t.Fatalf("unexpected outputs %+v", plugin.Outputs)
+	}
+	if ports := plugin.Outputs[0].PortList(); len(ports) != 2 || ports[0] != "443 / tcp / www" {
+		t.Errorf("unexpected ports %v", ports)
+	}
+
+	history, err := client.ScanHistory(5)
+	if err != nil || len(history) != 1 || history[0].Status != "completed" {
+		t.Errorf("unexpected history %+v, %v", history, err)
+	}
+}
+
+func TestReauthorize(t *testing.T) {
+	server := fakeNessus(t)
+	t.Cleanup(server.Close)
+
+	keys := "accessKey=old; secretKey=old"
+	renewed := 0
+	client := NewClient(server.URL, server.Client(), func(req *http.Request) {
+		req.Header.Set("X-ApiKeys", keys)
+	})
+	client.Reauthorize = func(rejected *http.Request) error {
+		renewed++
+		keys = "accessKey=a; secretKey=b"
+		return nil
+	}
+
+	scan, err := client.CreateScan("tmpl", map[string]interface{}{"name": "weekly-2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if renewed != 1 || scan.Name != "weekly-2" {
+		t.Errorf("renewed %d times, scan %+v", renewed, scan)
+	}
+
+	client.Reauthorize = func(rejected *http.Request) error {
+		return errors.New("login failed")
+	}
+	keys = "accessKey=old; secretKey=old"
+	if _, err := client.Session(); err == nil {
+		t.Error("expected the failed renewal to be returned")
+	}
+}
@@ -1,7 +1,6 @@
 package plugin
 
 import (
-	"encoding/csv"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -10,6 +9,8 @@ import (
 	"sort"
 	"strings"
 	"sync"
+
+	"NMB/internal/nessus"
 )
 
 // Constants
@@ -182,50 +183,15 @@ func (pm *Manager) LoadFindings() error {
 	}
 	defer file.Close()
 
-	// Create a CSV reader
-	reader := csv.NewReader(file)
-	reader.FieldsPerRecord = -1 // Allow variable number of fields
-
-	// Read all records
-	records, err := reader.ReadAll()
+	// Map the Plugin ID, Name and Risk columns by header name
+	reader, err := nessus.NewReader(file, nessus.ColumnPluginID, nessus.ColumnName)
 	if err != nil {
 		return fmt.Errorf("error reading CSV file: %w", err)
 	}
 
-	if len(records) == 0 {
-		return fmt.Errorf("empty CSV file")
-	}
-
-	// Get header row
-	headerRow := records[0]
-
-	// Find column indices
-	pluginIDIdx := -1
-	nameIdx := -1
-	riskIdx := -1
-
-	// Look for exact column names
-	for i, col := range headerRow {
-		col = strings.TrimSpace(col)
-		switch strings.ToLower(col) {
-		case "plugin id":
-			pluginIDIdx = i
-		case "name":
-			nameIdx = i
-		case "risk":
-			riskIdx = i
-		}
-	}
-
-	// If columns not found, use defaults for standard Nessus format
-	if pluginIDIdx == -1 {
-		pluginIDIdx = 0 // First column is usually Plugin ID
-	}
-	if nameIdx == -1 && len(headerRow) > 1 {
-		nameIdx = 1 // Second column is usually Name
-	}
-	if riskIdx == -1 && len(headerRow) > 2 {
-		riskIdx = 2 // Third column is usually Risk
+	records, err := reader.ReadAll()
+	if err != nil {
+		return fmt.Errorf("error reading CSV file: %w", err)
 	}
 
 	// Clear existing findings
@@ -233,40 +199,25 @@ func (pm *Manager) LoadFindings() error {
 	pm.PluginNames = make(map[string]string)
 
 	// Process data rows
-	for i := 1; i < len(records); i++ {
-		row := records[i]
-
-		// Skip if not enough fields
-		if len(row) <= pluginIDIdx || (nameIdx >= 0 && len(row) <= nameIdx) {
-			continue
-		}
-
-		// Get Plugin ID and Name
-		pluginID := strings.TrimSpace(row[pluginIDIdx])
+	for _, row := range records {
+		// Get Plugin ID
+		pluginID := row[nessus.ColumnPluginID]
 
 		// Skip if plugin ID is empty
 		if pluginID == "" {
 			continue
 		}
 
-		// Get name
-		var name string
-		if nameIdx >= 0 && len(row) > nameIdx {
-			name = strings.TrimSpace(row[nameIdx])
-		}
-
-		// If name is empty, use plugin ID
+		// Get name, using the plugin ID if it is empty
+		name := row[nessus.ColumnName]
 		if name == "" {
 			name = "Plugin " + pluginID
 		}
 
 		// Get risk if available
-		risk := "Medium" // Default
-		if riskIdx >= 0 && len(row) > riskIdx {
-			risk = strings.TrimSpace(row[riskIdx])
-			if risk == "" {
-				risk = "Medium" // Default
-			}
+		risk := row[nessus.ColumnRisk]
+		if risk == "" {
+			risk = "Medium" // Default
 		}
 
 		// Skip specific plugins
@@ -319,103 +270,37 @@ func (pm *Manager) LoadPluginNames() error {
 	}
 	defer file.Close()
 
-	reader := csv.NewReader(file)
-
-	// Make the reader more permissive
-	reader.FieldsPerRecord = -1    // Allow variable number of fields
-	reader.LazyQuotes = true       // Allow lazy quotes
-	reader.TrimLeadingSpace = true // Trim leading space
-
-	// Read header
-	header, err := reader.Read()
+	reader, err := nessus.NewReader(file, nessus.ColumnPluginID, nessus.ColumnName)
 	if err != nil {
 		return err
 	}
 
-	// Trim and normalize header fields
-	for i := range header {
-		header[i] = strings.TrimSpace(header[i])
-	}
-
-	// Try different possible header names
-	var pluginIDIdx, nameIdx int = -1, -1
-
-	// Possible column names for Plugin ID
-	pluginIDNames := []string{"Plugin ID", "PluginID", "Plugin Id", "plugin id", "plugin_id", "ID", "id"}
-	// Possible column names for Name
-	nameNames := []string{"Name", "name", "Plugin Name", "plugin name", "plugin_name", "Title", "title"}
-
-	// Find indices using case-insensitive matching
-	for i, col := range header {
-		colLower := strings.ToLower(col)
-
-		// Check for Plugin ID
-		for _, name := range pluginIDNames {
-			if strings.ToLower(name) == colLower {
-				pluginIDIdx = i
-				break
-			}
-		}
-
-		// Check for Name
-		for _, name := range nameNames {
-			if strings.ToLower(name) == colLower {
-				nameIdx = i
-				break
-			}
-		}
-	}
-
-	// If we couldn't find the columns by name, try to guess by position for common formats
-	if pluginIDIdx == -1 && len(header) > 0 {
-		pluginIDIdx = 0 // First column is often Plugin ID
-	}
-
-	if nameIdx == -1 && len(header) > 1 {
-		nameIdx = 1 // Second column is often Name
-	}
-
-	// If we still couldn't identify columns, return an error
-	if pluginIDIdx == -1 || nameIdx == -1 {
-		return errors.New("couldn't identify required columns in the CSV file")
-	}
-
 	// Clear existing plugin names
 	pm.PluginNames = make(map[string]string)
 
 	// Use a map to ensure uniqueness
 	uniquePlugins := make(map[string]string)
 
-	// Read the rest of the records
-	lineNumber := 1 // Start at line 1 for the header
+	// Read the records
 	for {
-		lineNumber++
-		record, err := reader.Read()
+		row, err := reader.Read()
 		if err == io.EOF {
 			break
 		}
 		if err != nil {
 			// Log the error but continue processing
-			fmt.Printf("Warning: error reading CSV line %d: %v\n", lineNumber, err)
-			continue
-		}
-
-		// Skip if we don't have enough fields
-		if len(record) <= pluginIDIdx || len(record) <= nameIdx {
+			fmt.Printf("Warning: error reading CSV: %v\n", err)
 			continue
 		}
 
-		// Get Plugin ID and Name, trim whitespace
-		pluginID := strings.TrimSpace(record[pluginIDIdx])
-		name := strings.TrimSpace(record[nameIdx])
-
 		// Skip if plugin ID is empty
+		pluginID := row[nessus.ColumnPluginID]
 		if pluginID == "" {
 			continue
 		}
 
 		// Store in map to ensure uniqueness
-		uniquePlugins[pluginID] = name
+		uniquePlugins[pluginID] = row[nessus.ColumnName]
 	}
 
 	// Set plugin names
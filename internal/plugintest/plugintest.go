@@ -0,0 +1,146 @@
+// Package plugintest runs plugin verification logic against recorded command
+// output, so changes to verify_words can be checked without a live target.
+//
+// Fixtures live in a "fixtures" directory next to the config file, one folder
+// per plugin with positive and negative samples:
+//
+//	fixtures/<plugin name>/positive/21-anonymous-allowed.txt
+//	fixtures/<plugin name>/negative/21-port-closed.txt
+//
+// A leading number in the file name is used as the port the check ran
+// against, which matters for nmap based plugins.
+package plugintest
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+
+	"NMB/internal/config"
+	"NMB/internal/scanner"
+)
+
+const (
+	FixturesDir = "fixtures"
+	positiveDir = "positive"
+	negativeDir = "negative"
+)
+
+// Fixture is a single recorded output sample for a plugin
+type Fixture struct {
+	Plugin   string
+	Name     string
+	Positive bool
+	Port     string
+	Output   string
+}
+
+// Result is the outcome of running a plugin's verification against a fixture
+type Result struct {
+	Fixture Fixture
+	Status  string
+	Passed  bool
+}
+
+// FixturesFS returns the fixtures that belong to the given config file, or the
+// embedded fixtures when no config file is used.
+func FixturesFS(configPath string) fs.FS {
+	if configPath == "" {
+		return config.EmbeddedFixtures()
+	}
+	return os.DirFS(filepath.Join(filepath.Dir(configPath), FixturesDir))
+}
+
+// Load reads all positive and negative fixtures recorded for a plugin. A plugin
+// without fixtures returns an empty slice and no error.
+func Load(fsys fs.FS, pluginName string) ([]Fixture, error) {
+	var fixtures []Fixture
+
+	for _, kind := range []string{positiveDir, negativeDir} {
+		dir := path.Join(pluginName, kind)
+		entries, err := fs.ReadDir(fsys, dir)
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+			return nil, fmt.Errorf("failed to read fixtures in %s: %w", dir, err)
+		}
+
+		for _, entry := range entries {
+			if entry.IsDir() {
+				continue
+			}
+
+			data, err := fs.ReadFile(fsys, path.Join(dir, entry.Name()))
+			if err != nil {
+				return nil, fmt.Errorf("failed to read fixture %s: %w", entry.Name(), err)
+			}
+
+			fixtures = append(fixtures, Fixture{
+				Plugin:   pluginName,
+				Name:     path.Join(kind, entry.Name()),
+				Positive: kind == positiveDir,
+				Port:     portFromName(entry.Name()),
+				Output:   string(data),
+			})
+		}
+	}
+
+	return fixtures, nil
+}
+
+// Run checks every fixture against the plugin's verification logic. Positive
+// fixtures pass when they verify, negative fixtures pass when they do not.
+func Run(plugin config.Plugin, fixtures []Fixture) []Result {
+	results := make([]Result, 0, len(fixtures))
+	for _, fixture := range fixtures {
+		status := scanner.Verify(plugin, fixture.Port, fixture.Output)
+		results = append(results, Result{
+			Fixture: fixture,
+			Status:  status,
+			Passed:  (status == "Verified") == fixture.Positive,
+		})
+	}
+	return results
+}
+
+// RunPlugin loads and runs the fixtures for a single named plugin
+func RunPlugin(cfg config.Config, fsys fs.FS, pluginName string) ([]Result, error) {
+	plugin, ok := cfg.Plugins[pluginName]
+	if !ok {
+		return nil, fmt.Errorf("plugin %s not found in config", pluginName)
+	}
+
+	fixtures, err := Load(fsys, pluginName)
+	if err != nil {
+		return nil, err
+	}
+
+	return Run(plugin, fixtures), nil
+}
+
+// PluginNames returns the config entries in a stable order
+func PluginNames(cfg config.Config) []string {
+	names := make([]string, 0, len(cfg.Plugins))
+	for name := range cfg.Plugins {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// portFromName extracts a leading port number from a fixture file name,
+// e.g. "443-weak-dh.txt" returns "443".
+func portFromName(name string) string {
+	end := strings.IndexFunc(name, func(r rune) bool { return !unicode.IsDigit(r) })
+	if end <= 0 {
+		return ""
+	}
+	return name[:end]
+}
@@ -0,0 +1,37 @@
+package plugintest
+
+import (
+	"io/fs"
+	"testing"
+
+	"NMB/internal/config"
+)
+
+func TestEmbeddedConfigFixtures(t *testing.T) {
+	checkConfig(t, config.LoadEmbeddedConfig(), config.EmbeddedFixtures())
+}
+
+// checkConfig runs the fixtures of every config entry as a subtest. Entries
+// without recorded fixtures are skipped rather than failed.
+func checkConfig(t *testing.T, cfg config.Config, fsys fs.FS) {
+	t.Helper()
+
+	for _, name := range PluginNames(cfg) {
+		t.Run(name, func(t *testing.T) {
+			results, err := RunPlugin(cfg, fsys, name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(results) == 0 {
+				t.Skip("no fixtures recorded")
+			}
+
+			for _, result := range results {
+				if !result.Passed {
+					t.Errorf("%s: got status %q, want verified=%t",
+						result.Fixture.Name, result.Status, result.Fixture.Positive)
+				}
+			}
+		})
+	}
+}
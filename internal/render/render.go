@@ -2,6 +2,7 @@ package render
 
 import (
 	"fmt"
+	"html"
 	"strings"
 	"time"
 
@@ -27,6 +28,27 @@ func Generate(r *report.Report) (string, error) {
 	sb.WriteString("<h1 class='text-4xl font-bold mb-4'>NMB Scan Report</h1>")
 	sb.WriteString(fmt.Sprintf("<p class='mb-4'><strong>Date:</strong> %s</p>", time.Now().Format(time.RFC1123)))
 
+	if len(r.Sources) > 0 {
+		sb.WriteString("<h2 class='text-2xl font-semibold mt-4'>Source Scans</h2>")
+		sb.WriteString("<ul class='list-disc list-inside'>")
+		for _, source := range r.Sources {
+			sb.WriteString(fmt.Sprintf("<li class='mb-2'>%s</li>", html.EscapeString(source)))
+		}
+		sb.WriteString("</ul>")
+	}
+
+	if len(r.Assets) > 0 {
+		sb.WriteString("<h2 class='text-2xl font-semibold mt-4'>Assets</h2>")
+		sb.WriteString("<table class='table-auto w-full text-left mb-4'>")
+		sb.WriteString("<thead><tr><th class='px-2'>IP</th><th class='px-2'>Hostname</th><th class='px-2'>Discovered By</th><th class='px-2'>Latency</th><th class='px-2'>Open Ports</th><th class='px-2'>Verified Findings</th></tr></thead><tbody>")
+		for _, asset := range r.Assets {
+			sb.WriteString(fmt.Sprintf("<tr class='border-t border-gray-600'><td class='px-2'>%s</td><td class='px-2'>%s</td><td class='px-2'>%s</td><td class='px-2'>%s</td><td class='px-2'>%s</td><td class='px-2'>%d</td></tr>",
+				html.EscapeString(asset.IP), html.EscapeString(asset.Hostname), html.EscapeString(asset.Method),
+				asset.Latency(), asset.Ports(), r.VerifiedOn(asset)))
+		}
+		sb.WriteString("</tbody></table>")
+	}
+
 	sb.WriteString("<h2 class='text-2xl font-semibold mt-4'>Supported Plugins</h2>")
 	if len(r.SupportedPlugins) > 0 {
 		sb.WriteString("<ul class='list-disc list-inside'>")
@@ -60,6 +82,9 @@ func Generate(r *report.Report) (string, error) {
 			sb.WriteString(fmt.Sprintf("<p><strong>Port:</strong> %s</p>", result.Port))
 			sb.WriteString(fmt.Sprintf("<p><strong>Name:</strong> %s</p>", result.Name))
 			sb.WriteString(fmt.Sprintf("<p class='status-verified'><strong>Status:</strong> %s</p>", result.Status))
+			if result.Source != "" {
+				sb.WriteString(fmt.Sprintf("<p><strong>Source:</strong> %s</p>", html.EscapeString(result.Source)))
+			}
 			sb.WriteString(fmt.Sprintf("<p><strong>Command:</strong> <code>%s</code></p>", result.Command))
 			sb.WriteString(fmt.Sprintf("<p><strong>Output:</strong><pre><code class='language-bash'>%s</code></pre></p>", result.Output))
 			sb.WriteString("</div><br>")
@@ -79,6 +104,9 @@ func Generate(r *report.Report) (string, error) {
 			sb.WriteString(fmt.Sprintf("<p><strong>Port:</strong> %s</p>", result.Port))
 			sb.WriteString(fmt.Sprintf("<p><strong>Name:</strong> %s</p>", result.Name))
 			sb.WriteString(fmt.Sprintf("<p class='status-failed'><strong>Status:</strong> %s</p>", result.Status))
+			if result.Source != "" {
+				sb.WriteString(fmt.Sprintf("<p><strong>Source:</strong> %s</p>", html.EscapeString(result.Source)))
+			}
 			sb.WriteString(fmt.Sprintf("<p><strong>Command:</strong> <code>%s</code></p>", result.Command))
 			sb.WriteString(fmt.Sprintf("<p><strong>Output:</strong><pre><code class='language-bash'>%s</code></pre></p>", result.Output))
 			sb.WriteString("</div><br>")
@@ -1,29 +1,41 @@
 package report
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
 	"time"
+
+	"NMB/internal/inventory"
 )
 
+// JSONFileName is the machine readable report written next to the markdown
+// and HTML reports, used to compare runs
+const JSONFileName = "NMB_scan_report.json"
+
 type ScanResult struct {
-	PluginID   string
-	Host       string
-	Port       string
-	Name       string
-	Status     string
-	OutputPath string
-	Command    string
-	Output     string
+	PluginID   string `json:"pluginId"`
+	Host       string `json:"host"`
+	Port       string `json:"port"`
+	Name       string `json:"name"`
+	Risk       string `json:"risk,omitempty"`
+	Status     string `json:"status"`
+	OutputPath string `json:"outputPath,omitempty"`
+	Command    string `json:"command"`
+	Output     string `json:"output"`
+	Source     string `json:"source,omitempty"`
 }
 
 type Report struct {
-	ProjectFolder    string
-	SupportedPlugins []string
-	MissingPlugins   []string
-	ScanResults      []ScanResult
+	ProjectFolder    string           `json:"projectFolder"`
+	GeneratedAt      time.Time        `json:"generatedAt"`
+	Sources          []string         `json:"sources,omitempty"`
+	Assets           []inventory.Host `json:"assets,omitempty"`
+	SupportedPlugins []string         `json:"supportedPlugins"`
+	MissingPlugins   []string         `json:"missingPlugins"`
+	ScanResults      []ScanResult     `json:"scanResults"`
 }
 
 func (r *Report) Generate() error {
@@ -49,6 +61,25 @@ func (r *Report) generateReportContent() string {
 	sb.WriteString("# NMB Scan Report\n\n")
 	sb.WriteString(fmt.Sprintf("**Date:** %s\n\n", time.Now().Format(time.RFC1123)))
 
+	if len(r.Sources) > 0 {
+		sb.WriteString("## Source Scans\n")
+		for _, source := range r.Sources {
+			sb.WriteString(fmt.Sprintf("- %s\n", source))
+		}
+		sb.WriteString("\n")
+	}
+
+	if len(r.Assets) > 0 {
+		sb.WriteString("## Assets\n")
+		sb.WriteString("| IP | Hostname | Discovered By | Latency | Open Ports | Verified Findings |\n")
+		sb.WriteString("|----|----------|---------------|---------|------------|-------------------|\n")
+		for _, asset := range r.Assets {
+			sb.WriteString(fmt.Sprintf("| %s | %s | %s | %s | %s | %d |\n",
+				asset.IP, asset.Hostname, asset.Method, asset.Latency(), asset.Ports(), r.VerifiedOn(asset)))
+		}
+		sb.WriteString("\n")
+	}
+
 	sb.WriteString("## Supported Plugins\n")
 	if len(r.SupportedPlugins) > 0 {
 		for _, plugin := range r.SupportedPlugins {
@@ -74,6 +105,9 @@ func (r *Report) generateReportContent() string {
 		sb.WriteString(fmt.Sprintf("  - **Port:** %s\n", result.Port))
 		sb.WriteString(fmt.Sprintf("  - **Name:** %s\n", result.Name))
 		sb.WriteString(fmt.Sprintf("  - **Status:** %s\n", result.Status))
+		if result.Source != "" {
+			sb.WriteString(fmt.Sprintf("  - **Source:** %s\n", result.Source))
+		}
 		sb.WriteString(fmt.Sprintf("  - **Command:** `%s`\n", result.Command))
 		sb.WriteString(fmt.Sprintf("  - **Output:**\n```\n%s\n```\n", result.Output))
 		sb.WriteString("\n")
@@ -81,3 +115,56 @@ func (r *Report) generateReportContent() string {
 
 	return sb.String()
 }
+
+// VerifiedOn counts the verified results on an asset, by address or hostname
+func (r *Report) VerifiedOn(asset inventory.Host) int {
+	count := 0
+	for _, result := range r.ScanResults {
+		if result.Status != "Verified" {
+			continue
+		}
+		if result.Host == asset.IP || (asset.Hostname != "" && strings.EqualFold(result.Host, asset.Hostname)) {
+			count++
+		}
+	}
+	return count
+}
+
+// Save writes the report as JSON into the project folder
+func (r *Report) Save() error {
+	if r.GeneratedAt.IsZero() {
+		r.GeneratedAt = time.Now()
+	}
+
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return fmt.Errorf("[x] Failed to encode report: %v", err)
+	}
+
+	filename := filepath.Join(r.ProjectFolder, JSONFileName)
+	if err := os.WriteFile(filename, data, 0644); err != nil {
+		return fmt.Errorf("[x] Failed to write report: %v", err)
+	}
+
+	return nil
+}
+
+// Load reads a saved JSON report, either from the file itself or from the
+// project folder it was saved in
+func Load(path string) (*Report, error) {
+	if info, err := os.Stat(path); err == nil && info.IsDir() {
+		path = filepath.Join(path, JSONFileName)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read report: %w", err)
+	}
+
+	var r Report
+	if err := json.Unmarshal(data, &r); err != nil {
+		return nil, fmt.Errorf("failed to parse report %s: %w", path, err)
+	}
+
+	return &r, nil
+}
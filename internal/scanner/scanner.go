@@ -10,6 +10,7 @@ import (
 	"sync"
 	"time"
 
+	"NMB/internal/cassette"
 	"NMB/internal/config"
 	"NMB/internal/logging"
 	"NMB/internal/nessus"
@@ -25,7 +26,13 @@ type Scanner struct {
 	ProjectFolder string
 	Report        *report.Report
 	RemoteExec    *remote.RemoteExecutor
-	mu            sync.Mutex
+	Cassette      *cassette.Cassette
+	// PerHost verifies every host and port of a plugin instead of stopping
+	// at the first verified one, as retests need
+	PerHost bool
+	// Log is where the scanner logs, per run when the API started it
+	Log *logging.Loggers
+	mu  sync.Mutex
 }
 
 const (
@@ -38,7 +45,7 @@ func (s *Scanner) RunScans(wg *sync.WaitGroup, jobs <-chan nessus.Finding) {
 	defer wg.Done()
 	defer func() {
 		if r := recover(); r != nil {
-			logging.ErrorLogger.Printf("Recovered from panic: %v", r)
+			s.Log.Error.Printf("Recovered from panic: %v", r)
 		}
 	}()
 
@@ -46,7 +53,8 @@ func (s *Scanner) RunScans(wg *sync.WaitGroup, jobs <-chan nessus.Finding) {
 	var scanWg sync.WaitGroup
 
 	for finding := range jobs {
-		if _, verified := verifiedPlugins.Load(finding.PluginID); verified || !s.isInPluginData(finding.PluginID) {
+		key := s.verificationKey(finding)
+		if _, verified := verifiedPlugins.Load(key); verified || !s.isInPluginData(finding.PluginID) {
 			continue
 		}
 
@@ -57,7 +65,7 @@ func (s *Scanner) RunScans(wg *sync.WaitGroup, jobs <-chan nessus.Finding) {
 			for _, plugin := range s.Config.Plugins {
 				if contains(plugin.IDs, f.PluginID) {
 					if s.verifyFinding(plugin, f) {
-						verifiedPlugins.Store(f.PluginID, true)
+						verifiedPlugins.Store(key, true)
 						break
 					}
 				}
@@ -76,7 +84,7 @@ func (s *Scanner) verifyFinding(plugin config.Plugin, finding nessus.Finding) bo
 	go func() {
 		success := s.ExecuteScan(plugin, finding, false)
 		if !success && plugin.ScanType == nmapScanType {
-			logging.WarningLogger.Printf("Initial scan failed for %s, retrying with -Pn", finding.Name)
+			s.Log.Warning.Printf("Initial scan failed for %s, retrying with -Pn", finding.Name)
 			success = s.ExecuteScan(plugin, finding, true)
 		}
 		resultChan <- success
@@ -86,7 +94,7 @@ func (s *Scanner) verifyFinding(plugin config.Plugin, finding nessus.Finding) bo
 	case success := <-resultChan:
 		return success
 	case <-ctx.Done():
-		logging.ErrorLogger.Printf("Scan timed out for %s (%s:%s)", finding.Name, finding.Host, finding.Port)
+		s.Log.Error.Printf("Scan timed out for %s (%s:%s)", finding.Name, finding.Host, finding.Port)
 		s.recordScanResult(finding, plugin, "", "Timeout", "")
 		return false
 	}
@@ -94,40 +102,52 @@ func (s *Scanner) verifyFinding(plugin config.Plugin, finding nessus.Finding) bo
 
 func (s *Scanner) ExecuteScan(plugin config.Plugin, hostFinding nessus.Finding, retry bool) bool {
 	command := buildCommand(plugin, hostFinding, retry)
-	logging.InfoLogger.Printf("Testing: %s:%s for %s", hostFinding.Host, hostFinding.Port, hostFinding.Name)
+	s.Log.Info.Printf("Testing: %s:%s for %s", hostFinding.Host, hostFinding.Port, hostFinding.Name)
 
-	output, err := executeCommand(command, s.RemoteExec)
+	output, err := s.executeCommand(command)
 	if err != nil {
-		logging.ErrorLogger.Printf("Command failed: %v, Command: %s", err, command)
+		s.Log.Error.Printf("Command failed: %v, Command: %s", err, command)
 		s.recordScanResult(hostFinding, plugin, command, "Command Failed", output)
 		return false
 	}
 
-	if plugin.ScanType == nmapScanType && !isPortOpen(output, hostFinding.Port) {
-		logging.WarningLogger.Printf("Port %s closed: %s:%s for %s",
+	switch status := Verify(plugin, hostFinding.Port, output); status {
+	case "Verified":
+		s.handleSuccessfulScan(hostFinding, plugin, command, output)
+		return true
+	case "Port Closed":
+		s.Log.Warning.Printf("Port %s closed: %s:%s for %s",
 			hostFinding.Port, hostFinding.Host, hostFinding.Port, hostFinding.Name)
-		s.recordScanResult(hostFinding, plugin, command, "Port Closed", output)
+		s.recordScanResult(hostFinding, plugin, command, status, output)
+		return false
+	default:
+		s.Log.Error.Printf("Verification failed: %s (%s:%s)",
+			hostFinding.Name, hostFinding.Host, hostFinding.Port)
+		s.recordScanResult(hostFinding, plugin, command, status, output)
 		return false
 	}
+}
 
+// Verify decides the outcome of a plugin check from the command output alone.
+// It returns "Verified", "Port Closed" or "Verification Failed", matching the
+// statuses recorded in the report.
+func Verify(plugin config.Plugin, port, output string) string {
+	if plugin.ScanType == nmapScanType && !isPortOpen(output, port) {
+		return "Port Closed"
+	}
 	if verifyOutput(output, plugin.VerifyWords) {
-		s.handleSuccessfulScan(hostFinding, plugin, command, output)
-		return true
+		return "Verified"
 	}
-
-	logging.ErrorLogger.Printf("Verification failed: %s (%s:%s)",
-		hostFinding.Name, hostFinding.Host, hostFinding.Port)
-	s.recordScanResult(hostFinding, plugin, command, "Verification Failed", output)
-	return false
+	return "Verification Failed"
 }
 
 func (s *Scanner) handleSuccessfulScan(finding nessus.Finding, plugin config.Plugin, command, output string) {
-	logging.SuccessLogger.Printf("Verified: %s (%s:%s)", finding.Name, finding.Host, finding.Port)
+	s.Log.Success.Printf("Verified: %s (%s:%s)", finding.Name, finding.Host, finding.Port)
 
 	pluginNameHash := md5.Sum([]byte(strings.ToLower(finding.Name)))
 	screenshotPath := fmt.Sprintf("%s.png", fmt.Sprintf("%x", pluginNameHash))
 
-	screenshot.Take(s.ProjectFolder, screenshotPath, output, plugin.VerifyWords, command)
+	screenshot.Take(s.Log, s.ProjectFolder, screenshotPath, output, plugin.VerifyWords, command)
 
 	s.recordScanResult(finding, plugin, command, "Verified", output, filepath.Join(s.ProjectFolder, screenshotPath))
 }
@@ -147,10 +167,12 @@ func (s *Scanner) recordScanResult(finding nessus.Finding, plugin config.Plugin,
 		Host:       finding.Host,
 		Port:       finding.Port,
 		Name:       finding.Name,
+		Risk:       finding.Risk,
 		Status:     status,
 		Command:    command,
 		Output:     output,
 		OutputPath: outputPath,
+		Source:     finding.Source,
 	})
 }
 
@@ -162,7 +184,23 @@ func buildCommand(plugin config.Plugin, finding nessus.Finding, retry bool) stri
 	return strings.ReplaceAll(command, "{port}", finding.Port)
 }
 
-func executeCommand(command string, remoteExec *remote.RemoteExecutor) (string, error) {
+// executeCommand runs a command, or answers it from the cassette when
+// replaying. Executed commands are recorded when a cassette is attached.
+func (s *Scanner) executeCommand(command string) (string, error) {
+	if s.Cassette != nil && s.Cassette.Replaying() {
+		return s.Cassette.Play(command)
+	}
+
+	output, err := runCommand(command, s.RemoteExec)
+	if s.Cassette != nil {
+		if saveErr := s.Cassette.Record(command, output, err); saveErr != nil {
+			s.Log.Error.Printf("Failed to save cassette: %v", saveErr)
+		}
+	}
+	return output, err
+}
+
+func runCommand(command string, remoteExec *remote.RemoteExecutor) (string, error) {
 	if remoteExec != nil {
 		return remoteExec.ExecuteCommand(command)
 	}
@@ -197,6 +235,13 @@ func isPortOpen(output, port string) bool {
 	return strings.Contains(output, fmt.Sprintf("%s/tcp open", port))
 }
 
+func (s *Scanner) verificationKey(finding nessus.Finding) string {
+	if s.PerHost {
+		return fmt.Sprintf("%s|%s:%s", finding.PluginID, finding.Host, finding.Port)
+	}
+	return finding.PluginID
+}
+
 func (s *Scanner) isInPluginData(pluginID string) bool {
 	_, exists := s.PluginData[pluginID]
 	return exists
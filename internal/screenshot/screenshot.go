@@ -66,9 +66,9 @@ func createHTMLContent(output, command string, verifyWords []string) string {
 	return htmlContent
 }
 
-func Take(projectFolder, screenshotPath, output string, verifyWords []string, command string) {
+func Take(logger *logging.Loggers, projectFolder, screenshotPath, output string, verifyWords []string, command string) {
 	if err := os.MkdirAll(projectFolder, os.ModePerm); err != nil {
-		logging.ErrorLogger.Printf("Failed to create project folder: %v", err)
+		logger.Error.Printf("Failed to create project folder: %v", err)
 		return
 	}
 
@@ -79,20 +79,20 @@ func Take(projectFolder, screenshotPath, output string, verifyWords []string, co
 	// Create HTML content and write to temporary file
 	htmlContent := createHTMLContent(output, command, verifyWords)
 	if err := os.WriteFile(tmpHTML, []byte(htmlContent), 0644); err != nil {
-		logging.ErrorLogger.Printf("Failed to create temporary HTML file: %v", err)
+		logger.Error.Printf("Failed to create temporary HTML file: %v", err)
 		return
 	}
 
 	// Ensure temporary file is cleaned up
 	defer func() {
 		if err := os.Remove(tmpHTML); err != nil {
-			logging.ErrorLogger.Printf("Failed to remove temporary HTML file: %v", err)
+			logger.Error.Printf("Failed to remove temporary HTML file: %v", err)
 		}
 	}()
 
 	_, wkhtmltoimagePath, err := getWkHtmlPaths()
 	if err != nil {
-		logging.ErrorLogger.Printf("Failed to get wkhtmltoimage path: %v", err)
+		logger.Error.Printf("Failed to get wkhtmltoimage path: %v", err)
 		return
 	}
 
@@ -101,9 +101,9 @@ func Take(projectFolder, screenshotPath, output string, verifyWords []string, co
 	// Convert HTML to PNG using wkhtmltoimage
 	cmd := exec.Command(wkhtmltoimagePath, "--quality", "100", tmpHTML, filename)
 	if err := cmd.Run(); err != nil {
-		logging.ErrorLogger.Printf("Failed to generate screenshot: %v", err)
+		logger.Error.Printf("Failed to generate screenshot: %v", err)
 		return
 	}
 
-	logging.SuccessLogger.Printf("Screenshot successfully saved to: %s", filename)
+	logger.Success.Printf("Screenshot successfully saved to: %s", filename)
 }
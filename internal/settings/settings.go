@@ -0,0 +1,182 @@
+// Package settings stores the user's NMB defaults in settings.json in the
+// user's config folder. The CLI uses them as flag defaults, and the API
+// server and desktop app use them where a request leaves a value out.
+package settings
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// FileName is the settings file in the user's NMB config folder
+const FileName = "settings.json"
+
+// MaxWorkers is the most workers the settings allow
+const MaxWorkers = 32
+
+// Settings are the user's defaults. Nessus credentials are not part of the
+// settings; NessusProfile names a profile in the credential vault.
+type Settings struct {
+	DefaultProjectFolder string   `json:"defaultProjectFolder"`
+	SSHKeyFile           string   `json:"sshKeyFile"`
+	MaxWorkers           int      `json:"maxWorkers"`
+	AutoStart            bool     `json:"autoStart"`
+	Telemetry            bool     `json:"telemetry"`
+	Drones               []Drone  `json:"drones"`
+	PlextracServers      []string `json:"plextracServers"`
+	NessusProfile        string   `json:"nessusProfile"`
+}
+
+// Drone is a scanner host the team connects to over SSH
+type Drone struct {
+	Name string `json:"name"`
+	Host string `json:"host"`
+	User string `json:"user"`
+}
+
+// plextracName is the instance name in https://<name>.kevlar.bulletproofsi.net
+var plextracName = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9-]*$`)
+
+// Defaults returns the settings used before any are saved, which match the
+// CLI's flag defaults
+func Defaults() Settings {
+	return Settings{
+		DefaultProjectFolder: "output",
+		MaxWorkers:           10,
+		AutoStart:            true,
+		Drones:               []Drone{},
+		PlextracServers:      []string{"report"},
+	}
+}
+
+// Path returns the settings file in the user's config folder
+func Path() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to find config folder: %v", err)
+	}
+	return filepath.Join(dir, "nmb", FileName), nil
+}
+
+// Load reads the saved settings, or returns the defaults when there are none
+func Load() (Settings, error) {
+	path, err := Path()
+	if err != nil {
+		return Defaults(), err
+	}
+	return LoadFile(path)
+}
+
+// LoadFile reads settings from a file. Values missing from the file keep
+// their defaults.
+func LoadFile(path string) (Settings, error) {
+	s := Defaults()
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return s, fmt.Errorf("failed to read settings: %v", err)
+	}
+	if err := json.Unmarshal(data, &s); err != nil {
+		return Defaults(), fmt.Errorf("failed to parse settings %s: %v", path, err)
+	}
+	if s.Drones == nil {
+		s.Drones = []Drone{}
+	}
+	return s, nil
+}
+
+// Save validates the settings and writes them to the user's config folder
+func Save(s Settings) error {
+	path, err := Path()
+	if err != nil {
+		return err
+	}
+	return SaveFile(path, s)
+}
+
+// SaveFile validates the settings and writes them to a file
+func SaveFile(path string, s Settings) error {
+	if err := s.Validate(); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("failed to create settings folder: %v", err)
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("failed to write settings: %v", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to write settings: %v", err)
+	}
+	return nil
+}
+
+// Validate checks the settings before they are saved
+func (s Settings) Validate() error {
+	var problems []string
+
+	if strings.TrimSpace(s.DefaultProjectFolder) == "" {
+		problems = append(problems, "default project folder is required")
+	}
+	if s.MaxWorkers < 1 || s.MaxWorkers > MaxWorkers {
+		problems = append(problems, fmt.Sprintf("max workers must be between 1 and %d", MaxWorkers))
+	}
+	if s.SSHKeyFile != "" {
+		if info, err := os.Stat(s.SSHKeyFile); err != nil || info.IsDir() {
+			problems = append(problems, fmt.Sprintf("SSH key file %s not found", s.SSHKeyFile))
+		}
+	}
+
+	drones := make(map[string]bool)
+	for i, drone := range s.Drones {
+		switch {
+		case drone.Name == "" || drone.Host == "":
+			problems = append(problems, fmt.Sprintf("drone %d needs a name and a host", i+1))
+		case drones[drone.Name]:
+			problems = append(problems, fmt.Sprintf("drone %s is listed twice", drone.Name))
+		}
+		drones[drone.Name] = true
+	}
+
+	if len(s.PlextracServers) == 0 {
+		problems = append(problems, "at least one Plextrac server is required")
+	}
+	servers := make(map[string]bool)
+	for _, server := range s.PlextracServers {
+		switch {
+		case !plextracName.MatchString(server):
+			problems = append(problems, fmt.Sprintf("invalid Plextrac server name %q", server))
+		case servers[server]:
+			problems = append(problems, fmt.Sprintf("Plextrac server %s is listed twice", server))
+		}
+		servers[server] = true
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid settings: %s", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// PlextracServer returns the first Plextrac server, the default target
+func (s Settings) PlextracServer() string {
+	if len(s.PlextracServers) == 0 {
+		return "report"
+	}
+	return s.PlextracServers[0]
+}
@@ -0,0 +1,121 @@
+package settings
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLoadFile(t *testing.T) {
+	dir := t.TempDir()
+
+	s, err := LoadFile(filepath.Join(dir, "missing.json"))
+	if err != nil {
+		t.Fatalf("LoadFile without a file: %v", err)
+	}
+	if !reflect.DeepEqual(s, Defaults()) {
+		t.Errorf("settings without a file = %+v, want the defaults", s)
+	}
+
+	// Values missing from the file keep their defaults
+	partial := filepath.Join(dir, "partial.json")
+	if err := os.WriteFile(partial, []byte(`{"maxWorkers": 4}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	s, err = LoadFile(partial)
+	if err != nil {
+		t.Fatalf("LoadFile: %v", err)
+	}
+	if s.MaxWorkers != 4 || s.DefaultProjectFolder != "output" || s.PlextracServer() != "report" || s.Drones == nil {
+		t.Errorf("partial settings = %+v", s)
+	}
+
+	broken := filepath.Join(dir, "broken.json")
+	if err := os.WriteFile(broken, []byte(`{"maxWorkers": `), 0600); err != nil {
+		t.Fatal(err)
+	}
+	s, err = LoadFile(broken)
+	if err == nil {
+		t.Fatal("LoadFile accepted invalid JSON")
+	}
+	if !reflect.DeepEqual(s, Defaults()) {
+		t.Errorf("settings from invalid JSON = %+v, want the defaults", s)
+	}
+}
+
+func TestSaveFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nmb", FileName)
+
+	s := Defaults()
+	s.MaxWorkers = 8
+	s.Drones = []Drone{{Name: "drone1", Host: "10.0.0.5", User: "root"}}
+	s.PlextracServers = []string{"acme", "report"}
+	s.NessusProfile = "lab"
+	if err := SaveFile(path, s); err != nil {
+		t.Fatalf("SaveFile: %v", err)
+	}
+
+	loaded, err := LoadFile(path)
+	if err != nil {
+		t.Fatalf("LoadFile: %v", err)
+	}
+	if !reflect.DeepEqual(loaded, s) {
+		t.Errorf("loaded settings = %+v, want %+v", loaded, s)
+	}
+
+	s.MaxWorkers = 0
+	if err := SaveFile(path, s); err == nil {
+		t.Fatal("SaveFile accepted invalid settings")
+	}
+	loaded, _ = LoadFile(path)
+	if loaded.MaxWorkers != 8 {
+		t.Errorf("invalid settings overwrote the file: max workers %d", loaded.MaxWorkers)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	keyFile := filepath.Join(t.TempDir(), "id_rsa")
+	if err := os.WriteFile(keyFile, []byte("key"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		change func(*Settings)
+		want   string
+	}{
+		{"defaults", func(*Settings) {}, ""},
+		{"key file", func(s *Settings) { s.SSHKeyFile = keyFile }, ""},
+		{"no project folder", func(s *Settings) { s.DefaultProjectFolder = " " }, "default project folder is required"},
+		{"no workers", func(s *Settings) { s.MaxWorkers = 0 }, "max workers must be between 1 and 32"},
+		{"too many workers", func(s *Settings) { s.MaxWorkers = MaxWorkers + 1 }, "max workers must be between 1 and 32"},
+		{"missing key file", func(s *Settings) { s.SSHKeyFile = keyFile + ".missing" }, "not found"},
+		{"key file is a folder", func(s *Settings) { s.SSHKeyFile = filepath.Dir(keyFile) }, "not found"},
+		{"drone without host", func(s *Settings) { s.Drones = []Drone{{Name: "drone1"}} }, "drone 1 needs a name and a host"},
+		{"duplicate drone", func(s *Settings) {
+			s.Drones = []Drone{{Name: "drone1", Host: "a"}, {Name: "drone1", Host: "b"}}
+		}, "drone drone1 is listed twice"},
+		{"no Plextrac server", func(s *Settings) { s.PlextracServers = nil }, "at least one Plextrac server is required"},
+		{"invalid Plextrac server", func(s *Settings) { s.PlextracServers = []string{"acme.evil.com"} }, `invalid Plextrac server name "acme.evil.com"`},
+		{"duplicate Plextrac server", func(s *Settings) { s.PlextracServers = []string{"acme", "acme"} }, "Plextrac server acme is listed twice"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := Defaults()
+			tt.change(&s)
+			err := s.Validate()
+			if tt.want == "" {
+				if err != nil {
+					t.Errorf("Validate: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Validate = %v, want an error containing %q", err, tt.want)
+			}
+		})
+	}
+}
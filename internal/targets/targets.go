@@ -0,0 +1,447 @@
+// Package targets parses scan target lists: single addresses, CIDRs, IPv4
+// ranges and hostnames, one or more per line, with # comments. IPv4 targets
+// are kept as an address set so exclusions work on overlapping networks, not
+// only on identical lines.
+package targets
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math/bits"
+	"net"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ParseError is a single invalid entry in a target list
+type ParseError struct {
+	Line  int
+	Entry string
+	Err   string
+}
+
+func (e ParseError) Error() string {
+	return fmt.Sprintf("line %d: %q: %s", e.Line, e.Entry, e.Err)
+}
+
+// ParseErrors collects every invalid entry of a target list
+type ParseErrors []ParseError
+
+func (e ParseErrors) Error() string {
+	messages := make([]string, len(e))
+	for i, err := range e {
+		messages[i] = err.Error()
+	}
+	return fmt.Sprintf("invalid targets:\n  %s", strings.Join(messages, "\n  "))
+}
+
+// ipRange is an inclusive range of IPv4 addresses
+type ipRange struct {
+	start, end uint32
+}
+
+// List is a parsed target list
+type List struct {
+	ranges []ipRange
+	hosts  []string
+}
+
+// ParseFile reads a target list from a file
+func ParseFile(path string) (*List, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read targets file: %v", err)
+	}
+	defer file.Close()
+
+	list, err := Parse(file)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return list, nil
+}
+
+// Parse reads a target list. Entries are separated by newlines, commas or
+// whitespace; everything after a # is a comment. All invalid entries are
+// reported together as ParseErrors.
+func Parse(r io.Reader) (*List, error) {
+	list := &List{}
+	var errs ParseErrors
+
+	scanner := bufio.NewScanner(r)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := scanner.Text()
+		if i := strings.Index(text, "#"); i >= 0 {
+			text = text[:i]
+		}
+
+		for _, entry := range strings.FieldsFunc(text, isSeparator) {
+			if err := list.add(entry); err != nil {
+				errs = append(errs, ParseError{Line: line, Entry: entry, Err: err.Error()})
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(errs) > 0 {
+		return nil, errs
+	}
+
+	list.normalize()
+	return list, nil
+}
+
+// ParseString parses a comma or whitespace separated target string
+func ParseString(s string) (*List, error) {
+	return Parse(strings.NewReader(s))
+}
+
+func isSeparator(r rune) bool {
+	return r == ',' || r == ';' || r == ' ' || r == '\t' || r == '\r'
+}
+
+func (l *List) add(entry string) error {
+	// CIDR
+	if strings.Contains(entry, "/") {
+		ip, network, err := net.ParseCIDR(entry)
+		if err != nil || ip.To4() == nil {
+			return fmt.Errorf("invalid IPv4 CIDR")
+		}
+		ones, _ := network.Mask.Size()
+		start := toUint32(network.IP)
+		end := start | (^uint32(0) >> uint(ones))
+		l.ranges = append(l.ranges, ipRange{start, end})
+		return nil
+	}
+
+	// Range, either 10.0.0.1-50 or 10.0.0.1-10.0.0.50
+	if from, to, ok := strings.Cut(entry, "-"); ok && net.ParseIP(from) != nil {
+		startIP := net.ParseIP(from).To4()
+		if startIP == nil {
+			return fmt.Errorf("ranges are only supported for IPv4")
+		}
+
+		var endIP net.IP
+		if last, err := strconv.Atoi(to); err == nil {
+			if last < 0 || last > 255 {
+				return fmt.Errorf("range end %d is not a valid octet", last)
+			}
+			endIP = net.IPv4(startIP[0], startIP[1], startIP[2], byte(last)).To4()
+		} else if endIP = net.ParseIP(to).To4(); endIP == nil {
+			return fmt.Errorf("invalid range end %q", to)
+		}
+
+		start, end := toUint32(startIP), toUint32(endIP)
+		if end < start {
+			return fmt.Errorf("range end is before its start")
+		}
+		l.ranges = append(l.ranges, ipRange{start, end})
+		return nil
+	}
+
+	// Single address
+	if ip := net.ParseIP(entry); ip != nil {
+		if ip4 := ip.To4(); ip4 != nil {
+			addr := toUint32(ip4)
+			l.ranges = append(l.ranges, ipRange{addr, addr})
+		} else {
+			l.hosts = append(l.hosts, ip.String())
+		}
+		return nil
+	}
+
+	// Hostname
+	if !validHostname(entry) {
+		return fmt.Errorf("not an IP address, CIDR, range or hostname")
+	}
+	l.hosts = append(l.hosts, strings.ToLower(entry))
+	return nil
+}
+
+func validHostname(name string) bool {
+	if len(name) == 0 || len(name) > 253 {
+		return false
+	}
+	for _, label := range strings.Split(strings.TrimSuffix(name, "."), ".") {
+		if len(label) == 0 || len(label) > 63 || label[0] == '-' || label[len(label)-1] == '-' {
+			return false
+		}
+		for _, r := range label {
+			if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_') {
+				return false
+			}
+		}
+	}
+	// A name made only of digits and dots is a mistyped address
+	return strings.Trim(name, "0123456789.") != ""
+}
+
+// normalize sorts and merges overlapping or adjacent ranges and removes
+// duplicate hostnames
+func (l *List) normalize() {
+	sort.Slice(l.ranges, func(i, j int) bool { return l.ranges[i].start < l.ranges[j].start })
+
+	var merged []ipRange
+	for _, r := range l.ranges {
+		if n := len(merged); n > 0 && (merged[n-1].end == ^uint32(0) || r.start <= merged[n-1].end+1) {
+			if r.end > merged[n-1].end {
+				merged[n-1].end = r.end
+			}
+			continue
+		}
+		merged = append(merged, r)
+	}
+	l.ranges = merged
+
+	seen := make(map[string]struct{}, len(l.hosts))
+	var hosts []string
+	for _, host := range l.hosts {
+		if _, ok := seen[host]; !ok {
+			seen[host] = struct{}{}
+			hosts = append(hosts, host)
+		}
+	}
+	sort.Strings(hosts)
+	l.hosts = hosts
+}
+
+// Subtract returns the targets of l that are not in exclude. IPv4 targets are
+// subtracted as address sets; hostnames and IPv6 addresses by exact match.
+func (l *List) Subtract(exclude *List) *List {
+	result := &List{}
+
+	ranges := append([]ipRange(nil), l.ranges...)
+	for _, ex := range exclude.ranges {
+		var next []ipRange
+		for _, r := range ranges {
+			if ex.end < r.start || ex.start > r.end {
+				next = append(next, r)
+				continue
+			}
+			if ex.start > r.start {
+				next = append(next, ipRange{r.start, ex.start - 1})
+			}
+			if ex.end < r.end {
+				next = append(next, ipRange{ex.end + 1, r.end})
+			}
+		}
+		ranges = next
+	}
+	result.ranges = ranges
+
+	excluded := make(map[string]struct{}, len(exclude.hosts))
+	for _, host := range exclude.hosts {
+		excluded[host] = struct{}{}
+	}
+	for _, host := range l.hosts {
+		if _, ok := excluded[host]; !ok {
+			result.hosts = append(result.hosts, host)
+		}
+	}
+
+	return result
+}
+
+// Contains reports whether an address or hostname is in the list
+func (l *List) Contains(host string) bool {
+	host = strings.TrimSpace(host)
+	if ip := net.ParseIP(host); ip != nil {
+		if ip4 := ip.To4(); ip4 != nil {
+			addr := toUint32(ip4)
+			for _, r := range l.ranges {
+				if addr >= r.start && addr <= r.end {
+					return true
+				}
+			}
+			return false
+		}
+		host = ip.String()
+	}
+
+	host = strings.ToLower(host)
+	for _, name := range l.hosts {
+		if name == host {
+			return true
+		}
+	}
+	return false
+}
+
+// Addresses returns the number of IPv4 addresses in the list
+func (l *List) Addresses() uint64 {
+	var total uint64
+	for _, r := range l.ranges {
+		total += uint64(r.end-r.start) + 1
+	}
+	return total
+}
+
+// Hostnames returns the hostnames and IPv6 addresses in the list
+func (l *List) Hostnames() []string {
+	return l.hosts
+}
+
+// Empty reports whether the list has no targets at all
+func (l *List) Empty() bool {
+	return len(l.ranges) == 0 && len(l.hosts) == 0
+}
+
+// Summary describes the size of the list, e.g. "254 addresses in 1 range,
+// 2 hostnames"
+func (l *List) Summary() string {
+	summary := fmt.Sprintf("%d %s in %d %s", l.Addresses(), plural(l.Addresses(), "address", "addresses"),
+		len(l.ranges), plural(uint64(len(l.ranges)), "range", "ranges"))
+	if len(l.hosts) > 0 {
+		summary += fmt.Sprintf(", %d %s", len(l.hosts), plural(uint64(len(l.hosts)), "hostname", "hostnames"))
+	}
+	return summary
+}
+
+// Strings renders the list as entries both nmap and Nessus accept: single
+// addresses, CIDR blocks and hostnames
+func (l *List) Strings() []string {
+	var entries []string
+	for _, r := range l.ranges {
+		entries = append(entries, cidrs(r)...)
+	}
+	return append(entries, l.hosts...)
+}
+
+// String renders the list comma separated, as Nessus text_targets expects
+func (l *List) String() string {
+	return strings.Join(l.Strings(), ",")
+}
+
+// cidrs splits a range into the fewest CIDR blocks covering it
+func cidrs(r ipRange) []string {
+	var blocks []string
+	start := uint64(r.start)
+	end := uint64(r.end)
+
+	for start <= end {
+		// Largest block aligned on start that does not pass end
+		size := uint(bits.TrailingZeros32(uint32(start)))
+		if start == 0 {
+			size = 32
+		}
+		for size > 0 && start+(uint64(1)<<size)-1 > end {
+			size--
+		}
+
+		ip := fromUint32(uint32(start))
+		if size == 0 {
+			blocks = append(blocks, ip.String())
+		} else {
+			blocks = append(blocks, fmt.Sprintf("%s/%d", ip, 32-size))
+		}
+		start += uint64(1) << size
+	}
+	return blocks
+}
+
+func toUint32(ip net.IP) uint32 {
+	return binary.BigEndian.Uint32(ip.To4())
+}
+
+func fromUint32(n uint32) net.IP {
+	ip := make(net.IP, 4)
+	binary.BigEndian.PutUint32(ip, n)
+	return ip
+}
+
+func plural(n uint64, singular, pluralForm string) string {
+	if n == 1 {
+		return singular
+	}
+	return pluralForm
+}
+
+// Split divides the list into lists of at most size targets, in address
+// order. Hostnames and IPv6 addresses count as one target each and fill the
+// last lists.
+func (l *List) Split(size uint64) []*List {
+	if size == 0 {
+		return []*List{l}
+	}
+
+	var parts []*List
+	current := &List{}
+	var count uint64
+	next := func() {
+		parts = append(parts, current)
+		current = &List{}
+		count = 0
+	}
+
+	for _, r := range l.ranges {
+		start := uint64(r.start)
+		end := uint64(r.end)
+		for start <= end {
+			take := end - start + 1
+			if room := size - count; take > room {
+				take = room
+			}
+			current.ranges = append(current.ranges, ipRange{uint32(start), uint32(start + take - 1)})
+			count += take
+			start += take
+			if count == size {
+				next()
+			}
+		}
+	}
+	for _, host := range l.hosts {
+		current.hosts = append(current.hosts, host)
+		count++
+		if count == size {
+			next()
+		}
+	}
+	if !current.Empty() {
+		parts = append(parts, current)
+	}
+	return parts
+}
+
+// SplitBySubnet divides the list by IPv4 subnet of the given prefix length,
+// so 10.0.0.0/23 split by /24 gives two lists. Hostnames and IPv6 addresses
+// go into a list of their own.
+func (l *List) SplitBySubnet(prefix int) ([]*List, error) {
+	if prefix < 1 || prefix > 32 {
+		return nil, fmt.Errorf("invalid subnet prefix length %d", prefix)
+	}
+	mask := ^uint32(0) << (32 - prefix)
+
+	var parts []*List
+	var current *List
+	var subnet uint32
+	for _, r := range l.ranges {
+		start := uint64(r.start)
+		end := uint64(r.end)
+		for start <= end {
+			network := uint32(start) & mask
+			last := uint64(network | ^mask)
+			if last > end {
+				last = end
+			}
+			if current == nil || network != subnet {
+				current = &List{}
+				parts = append(parts, current)
+				subnet = network
+			}
+			current.ranges = append(current.ranges, ipRange{uint32(start), uint32(last)})
+			start = last + 1
+		}
+	}
+	if len(l.hosts) > 0 {
+		parts = append(parts, &List{hosts: append([]string(nil), l.hosts...)})
+	}
+	return parts, nil
+}
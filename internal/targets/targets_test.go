@@ -0,0 +1,133 @@
+package targets
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name      string
+		input     string
+		want      []string
+		addresses uint64
+	}{
+		{"single address", "10.0.0.1", []string{"10.0.0.1"}, 1},
+		{"/32", "10.0.0.1/32", []string{"10.0.0.1"}, 1},
+		{"/0", "0.0.0.0/0", []string{"0.0.0.0/0"}, 1 << 32},
+		{"CIDR with host bits", "10.0.0.77/24", []string{"10.0.0.0/24"}, 256},
+		{"short range", "10.0.0.1-6", []string{"10.0.0.1", "10.0.0.2/31", "10.0.0.4/31", "10.0.0.6"}, 6},
+		{"full range", "10.0.0.254-10.0.1.1", []string{"10.0.0.254/31", "10.0.1.0/31"}, 4},
+		{"overlapping and adjacent", "10.0.0.0/25, 10.0.0.100-10.0.0.200\n10.0.0.201-255", []string{"10.0.0.0/24"}, 256},
+		{"hostnames and comments", "# scope\nWeb.Example.com # main site\nweb.example.com;db.example.com", []string{"db.example.com", "web.example.com"}, 0},
+		{"IPv6", "2001:db8::1", []string{"2001:db8::1"}, 0},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			list, err := ParseString(test.input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := list.Strings(); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("Strings() = %v, want %v", got, test.want)
+			}
+			if got := list.Addresses(); got != test.addresses {
+				t.Errorf("Addresses() = %d, want %d", got, test.addresses)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	input := "10.0.0.1\n10.0.0.300\n\n10.0.0.9-3, 10.0.0.1-256\n# 10.0.0.0/33\n10.0.0.0/33 -bad-.example.com\n"
+
+	_, err := ParseString(input)
+	var errs ParseErrors
+	if !errors.As(err, &errs) {
+		t.Fatalf("err = %v, want ParseErrors", err)
+	}
+
+	want := []struct {
+		line  int
+		entry string
+	}{
+		{2, "10.0.0.300"},
+		{4, "10.0.0.9-3"},
+		{4, "10.0.0.1-256"},
+		{6, "10.0.0.0/33"},
+		{6, "-bad-.example.com"},
+	}
+	if len(errs) != len(want) {
+		t.Fatalf("got %d errors, want %d: %v", len(errs), len(want), errs)
+	}
+	for i, w := range want {
+		if errs[i].Line != w.line || errs[i].Entry != w.entry {
+			t.Errorf("error %d is line %d %q, want line %d %q", i, errs[i].Line, errs[i].Entry, w.line, w.entry)
+		}
+	}
+}
+
+func TestSubtract(t *testing.T) {
+	tests := []struct {
+		name    string
+		targets string
+		exclude string
+		want    []string
+	}{
+		{"nothing excluded", "10.0.0.0/30", "10.0.1.0/24", []string{"10.0.0.0/30"}},
+		{"single address", "10.0.0.0/30", "10.0.0.2", []string{"10.0.0.0/31", "10.0.0.3"}},
+		{"/32", "10.0.0.0/31", "10.0.0.0/32", []string{"10.0.0.1"}},
+		{"overlapping excludes", "10.0.0.0/24", "10.0.0.0/25, 10.0.0.64-10.0.0.191", []string{"10.0.0.192/26"}},
+		{"everything", "10.0.0.0/24", "0.0.0.0/0", nil},
+		{"from /0", "0.0.0.0/0", "128.0.0.0/1", []string{"0.0.0.0/1"}},
+		{"hostnames", "a.example.com, b.example.com", "B.example.com", []string{"a.example.com"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			list, err := ParseString(test.targets)
+			if err != nil {
+				t.Fatal(err)
+			}
+			exclude, err := ParseString(test.exclude)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := list.Subtract(exclude).Strings(); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestSubtractAddressSpaceEdges(t *testing.T) {
+	list, _ := ParseString("0.0.0.0/0")
+	exclude, _ := ParseString("0.0.0.0, 255.255.255.255")
+
+	got := list.Subtract(exclude)
+	if got.Addresses() != 1<<32-2 || got.Contains("0.0.0.0") || got.Contains("255.255.255.255") || !got.Contains("10.0.0.1") {
+		t.Errorf("got %s, want every address but the first and the last", got.Summary())
+	}
+}
+
+func TestSplit(t *testing.T) {
+	list, err := ParseString("10.0.0.0/29, 10.0.1.0/30, host.example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got [][]string
+	for _, part := range list.Split(5) {
+		got = append(got, part.Strings())
+	}
+	want := [][]string{
+		{"10.0.0.0/30", "10.0.0.4"},
+		{"10.0.0.5", "10.0.0.6/31", "10.0.1.0/31"},
+		{"10.0.1.2/31", "host.example.com"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Split(5) = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,336 @@
+// Package vault stores Nessus connection profiles encrypted at rest. The
+// profiles are sealed with AES-256-GCM under a key derived from a master
+// passphrase with scrypt, and are only readable while the vault is unlocked.
+package vault
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+// EnvPassphrase unlocks the vault when the API server starts
+const EnvPassphrase = "NMB_VAULT_PASSPHRASE"
+
+// FileName is the vault file in the user's NMB config folder
+const FileName = "vault.json"
+
+var (
+	ErrLocked          = errors.New("credential vault is locked")
+	ErrWrongPassphrase = errors.New("wrong vault passphrase")
+	ErrNoProfile       = errors.New("profile not found")
+)
+
+// scrypt parameters for new vaults; existing vaults keep the ones they were
+// created with
+const (
+	scryptN = 1 << 15
+	scryptR = 8
+	scryptP = 1
+	keyLen  = 32
+	saltLen = 16
+)
+
+// Profile is a Nessus server and the credentials to reach it. URL is the
+// Nessus URL and Host the SSH host, either of which may be empty.
+type Profile struct {
+	Name      string `json:"name"`
+	URL       string `json:"url,omitempty"`
+	Host      string `json:"host,omitempty"`
+	Username  string `json:"username,omitempty"`
+	Password  string `json:"password,omitempty"`
+	AccessKey string `json:"accessKey,omitempty"`
+	SecretKey string `json:"secretKey,omitempty"`
+	KeyFile   string `json:"keyFile,omitempty"`
+}
+
+// Summary describes a profile without its secrets
+type Summary struct {
+	Name        string `json:"name"`
+	URL         string `json:"url,omitempty"`
+	Host        string `json:"host,omitempty"`
+	Username    string `json:"username,omitempty"`
+	KeyFile     string `json:"keyFile,omitempty"`
+	HasPassword bool   `json:"hasPassword"`
+	HasAPIKeys  bool   `json:"hasApiKeys"`
+}
+
+// Summary returns the profile without its secrets
+func (p Profile) Summary() Summary {
+	return Summary{
+		Name:        p.Name,
+		URL:         p.URL,
+		Host:        p.Host,
+		Username:    p.Username,
+		KeyFile:     p.KeyFile,
+		HasPassword: p.Password != "",
+		HasAPIKeys:  p.AccessKey != "" && p.SecretKey != "",
+	}
+}
+
+// Validate checks that a profile names a server
+func (p Profile) Validate() error {
+	if strings.TrimSpace(p.Name) == "" {
+		return fmt.Errorf("profile name is required")
+	}
+	if p.URL == "" && p.Host == "" {
+		return fmt.Errorf("profile %s needs a Nessus URL or host", p.Name)
+	}
+	return nil
+}
+
+// sealed is the vault file
+type sealed struct {
+	Version int    `json:"version"`
+	N       int    `json:"n"`
+	R       int    `json:"r"`
+	P       int    `json:"p"`
+	Salt    []byte `json:"salt"`
+	Nonce   []byte `json:"nonce"`
+	Data    []byte `json:"data"`
+}
+
+// Vault is an encrypted profile store backed by a file
+type Vault struct {
+	path string
+
+	mutex    sync.RWMutex
+	file     *sealed
+	key      []byte
+	profiles map[string]Profile
+}
+
+// DefaultPath returns the vault file in the user's config folder
+func DefaultPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to find config folder: %v", err)
+	}
+	return filepath.Join(dir, "nmb", FileName), nil
+}
+
+// New returns a locked vault for a file, which does not need to exist yet
+func New(path string) *Vault {
+	return &Vault{path: path}
+}
+
+// Path returns the vault file
+func (v *Vault) Path() string {
+	return v.path
+}
+
+// Unlock decrypts the vault with the passphrase. Without a vault file the
+// passphrase becomes the passphrase of a new, empty vault, which is written
+// when the first profile is saved.
+func (v *Vault) Unlock(passphrase string) error {
+	if passphrase == "" {
+		return fmt.Errorf("vault passphrase is required")
+	}
+
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+
+	data, err := os.ReadFile(v.path)
+	if errors.Is(err, os.ErrNotExist) {
+		salt := make([]byte, saltLen)
+		if _, err := rand.Read(salt); err != nil {
+			return err
+		}
+		file := &sealed{Version: 1, N: scryptN, R: scryptR, P: scryptP, Salt: salt}
+		key, err := file.deriveKey(passphrase)
+		if err != nil {
+			return err
+		}
+		v.file, v.key, v.profiles = file, key, make(map[string]Profile)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read vault: %v", err)
+	}
+
+	var file sealed
+	if err := json.Unmarshal(data, &file); err != nil {
+		return fmt.Errorf("failed to parse vault %s: %v", v.path, err)
+	}
+	key, err := file.deriveKey(passphrase)
+	if err != nil {
+		return err
+	}
+	plain, err := open(key, file.Nonce, file.Data)
+	if err != nil {
+		return ErrWrongPassphrase
+	}
+
+	profiles := make(map[string]Profile)
+	if err := json.Unmarshal(plain, &profiles); err != nil {
+		return fmt.Errorf("failed to parse vault profiles: %v", err)
+	}
+	v.file, v.key, v.profiles = &file, key, profiles
+	return nil
+}
+
+// Lock forgets the key and the decrypted profiles
+func (v *Vault) Lock() {
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+
+	for i := range v.key {
+		v.key[i] = 0
+	}
+	v.file, v.key, v.profiles = nil, nil, nil
+}
+
+// Locked reports whether the vault needs to be unlocked first
+func (v *Vault) Locked() bool {
+	v.mutex.RLock()
+	defer v.mutex.RUnlock()
+	return v.key == nil
+}
+
+// Profiles returns the profiles without their secrets, sorted by name
+func (v *Vault) Profiles() ([]Summary, error) {
+	v.mutex.RLock()
+	defer v.mutex.RUnlock()
+	if v.key == nil {
+		return nil, ErrLocked
+	}
+
+	summaries := make([]Summary, 0, len(v.profiles))
+	for _, profile := range v.profiles {
+		summaries = append(summaries, profile.Summary())
+	}
+	sort.Slice(summaries, func(i, j int) bool { return summaries[i].Name < summaries[j].Name })
+	return summaries, nil
+}
+
+// Get returns a profile with its secrets
+func (v *Vault) Get(name string) (Profile, error) {
+	v.mutex.RLock()
+	defer v.mutex.RUnlock()
+	if v.key == nil {
+		return Profile{}, ErrLocked
+	}
+
+	profile, ok := v.profiles[name]
+	if !ok {
+		return Profile{}, fmt.Errorf("%w: %s", ErrNoProfile, name)
+	}
+	return profile, nil
+}
+
+// Put adds or replaces a profile and saves the vault. Secrets left empty
+// keep the values of the profile being replaced, so a profile can be edited
+// without entering its password again.
+func (v *Vault) Put(profile Profile) error {
+	if err := profile.Validate(); err != nil {
+		return err
+	}
+
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+	if v.key == nil {
+		return ErrLocked
+	}
+
+	if existing, ok := v.profiles[profile.Name]; ok {
+		if profile.Password == "" {
+			profile.Password = existing.Password
+		}
+		if profile.AccessKey == "" && profile.SecretKey == "" {
+			profile.AccessKey, profile.SecretKey = existing.AccessKey, existing.SecretKey
+		}
+	}
+	v.profiles[profile.Name] = profile
+	return v.save()
+}
+
+// Delete removes a profile and saves the vault
+func (v *Vault) Delete(name string) error {
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+	if v.key == nil {
+		return ErrLocked
+	}
+
+	if _, ok := v.profiles[name]; !ok {
+		return fmt.Errorf("%w: %s", ErrNoProfile, name)
+	}
+	delete(v.profiles, name)
+	return v.save()
+}
+
+// save seals the profiles under a fresh nonce and replaces the vault file
+func (v *Vault) save() error {
+	plain, err := json.Marshal(v.profiles)
+	if err != nil {
+		return err
+	}
+
+	block, err := aes.NewCipher(v.key)
+	if err != nil {
+		return err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+	v.file.Nonce = nonce
+	v.file.Data = gcm.Seal(nil, nonce, plain, nil)
+
+	data, err := json.MarshalIndent(v.file, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(v.path), 0700); err != nil {
+		return fmt.Errorf("failed to create vault folder: %v", err)
+	}
+
+	// Write next to the vault and rename, so a failed write cannot leave a
+	// truncated vault behind
+	tmp := v.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("failed to write vault: %v", err)
+	}
+	if err := os.Rename(tmp, v.path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to write vault: %v", err)
+	}
+	return nil
+}
+
+func (f *sealed) deriveKey(passphrase string) ([]byte, error) {
+	key, err := scrypt.Key([]byte(passphrase), f.Salt, f.N, f.R, f.P, keyLen)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive vault key: %v", err)
+	}
+	return key, nil
+}
+
+func open(key, nonce, data []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	if len(nonce) != gcm.NonceSize() {
+		return nil, errors.New("invalid nonce")
+	}
+	return gcm.Open(nil, nonce, data, nil)
+}
@@ -0,0 +1,140 @@
+package vault
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// newVault returns an unlocked vault holding one profile, saved to a
+// temporary file
+func newVault(t *testing.T) *Vault {
+	t.Helper()
+	v := New(filepath.Join(t.TempDir(), FileName))
+	if err := v.Unlock("correct horse"); err != nil {
+		t.Fatalf("Unlock new vault: %v", err)
+	}
+	profile := Profile{Name: "lab", URL: "https://nessus:8834", Username: "admin", Password: "secret"}
+	if err := v.Put(profile); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	return v
+}
+
+func TestUnlock(t *testing.T) {
+	v := newVault(t)
+	v.Lock()
+	if !v.Locked() {
+		t.Fatal("vault still unlocked after Lock")
+	}
+	if _, err := v.Get("lab"); !errors.Is(err, ErrLocked) {
+		t.Errorf("Get on a locked vault = %v, want ErrLocked", err)
+	}
+
+	if err := v.Unlock("correct horse"); err != nil {
+		t.Fatalf("Unlock: %v", err)
+	}
+	profile, err := v.Get("lab")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if profile.Password != "secret" {
+		t.Errorf("password = %q, want %q", profile.Password, "secret")
+	}
+
+	data, err := os.ReadFile(v.Path())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var file sealed
+	if err := json.Unmarshal(data, &file); err != nil {
+		t.Fatalf("vault file is not JSON: %v", err)
+	}
+	if len(file.Data) == 0 || json.Valid(file.Data) {
+		t.Error("vault file does not hold sealed profiles")
+	}
+}
+
+func TestUnlockWrongPassphrase(t *testing.T) {
+	v := newVault(t)
+	v.Lock()
+
+	if err := v.Unlock("battery staple"); !errors.Is(err, ErrWrongPassphrase) {
+		t.Fatalf("Unlock = %v, want ErrWrongPassphrase", err)
+	}
+	if !v.Locked() {
+		t.Error("vault unlocked with the wrong passphrase")
+	}
+	if err := v.Unlock(""); err == nil {
+		t.Error("Unlock accepted an empty passphrase")
+	}
+}
+
+func TestUnlockCorruptedFile(t *testing.T) {
+	tests := []struct {
+		name    string
+		corrupt func(file map[string]json.RawMessage) []byte
+	}{
+		{
+			name: "not JSON",
+			corrupt: func(map[string]json.RawMessage) []byte {
+				return []byte("{truncated")
+			},
+		},
+		{
+			name: "tampered data",
+			corrupt: func(file map[string]json.RawMessage) []byte {
+				var data []byte
+				json.Unmarshal(file["data"], &data)
+				data[0] ^= 0xff
+				file["data"], _ = json.Marshal(data)
+				out, _ := json.Marshal(file)
+				return out
+			},
+		},
+		{
+			name: "short nonce",
+			corrupt: func(file map[string]json.RawMessage) []byte {
+				file["nonce"], _ = json.Marshal([]byte{1, 2, 3})
+				out, _ := json.Marshal(file)
+				return out
+			},
+		},
+		{
+			name: "invalid scrypt parameters",
+			corrupt: func(file map[string]json.RawMessage) []byte {
+				file["n"] = json.RawMessage("3")
+				out, _ := json.Marshal(file)
+				return out
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := newVault(t)
+			v.Lock()
+
+			data, err := os.ReadFile(v.Path())
+			if err != nil {
+				t.Fatal(err)
+			}
+			var file map[string]json.RawMessage
+			if err := json.Unmarshal(data, &file); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(v.Path(), tt.corrupt(file), 0600); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := v.Unlock("correct horse"); err == nil {
+				t.Fatal("Unlock accepted a corrupted vault")
+			}
+			if !v.Locked() {
+				t.Error("vault unlocked from a corrupted file")
+			}
+		})
+	}
+}
@@ -3,10 +3,10 @@ package websocket
 
 import (
 	"log"
-	"net/http"
 	"regexp"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/gorilla/websocket"
@@ -14,23 +14,78 @@ import (
 
 var ansiRegex = regexp.MustCompile(`\x1b\[[0-9;]*[a-zA-Z]`)
 
-var Upgrader = websocket.Upgrader{
-	CheckOrigin: func(r *http.Request) bool {
-		return true // Allow all origins in development
-	},
+// Upgrader only accepts same-origin connections unless CheckOrigin is
+// replaced, as the API server does with its allowed origins
+var Upgrader = websocket.Upgrader{}
+
+// Event topics. Clients subscribe to topics by name, or to every topic
+// under a prefix with a pattern such as scan.*
+const (
+	TopicScanProgress = "scan.progress"
+	TopicScanResult   = "scan.result"
+	TopicNessusStatus = "nessus.status"
+	TopicLog          = "log"
+)
+
+const (
+	// HistorySize is how many recent events are kept for clients that
+	// connect or subscribe late
+	HistorySize = 500
+
+	// clientBuffer is how many events wait for a slow client before newer
+	// ones are dropped; it holds a full replay of the history
+	clientBuffer = HistorySize + 100
+
+	pingInterval = 30 * time.Second
+	writeTimeout = 10 * time.Second
+)
+
+// Event is the envelope of everything sent to clients. Seq increases with
+// every event, so a client that reconnects can ask for the events it missed.
+type Event struct {
+	Seq   uint64      `json:"seq"`
+	Topic string      `json:"topic"`
+	JobID string      `json:"jobId,omitempty"`
+	Time  string      `json:"time"`
+	Data  interface{} `json:"data"`
 }
 
-type LogMessage struct {
-	Type    string `json:"type"`
+// LogData is the data of a log event
+type LogData struct {
+	Level   string `json:"level"`
 	Message string `json:"message"`
-	Time    string `json:"time"`
+}
+
+// Stats counts the events published and dropped since the server started
+type Stats struct {
+	Clients   int    `json:"clients"`
+	Published uint64 `json:"published"`
+	Dropped   uint64 `json:"dropped"`
+	Buffered  int    `json:"buffered"`
+}
+
+// Client is a connection and the topics it subscribed to
+type Client struct {
+	conn   *websocket.Conn
+	send   chan Event
+	topics []string
+	done   chan struct{}
+	once   sync.Once
+
+	dropped atomic.Uint64
 }
 
 type WebSocketManager struct {
-	clients   map[*websocket.Conn]bool
-	broadcast chan LogMessage
-	mutex     sync.RWMutex
-	writeMu   sync.Map // Per-connection write mutex
+	clients map[*Client]bool
+	mutex   sync.Mutex
+
+	// history is a ring of the last HistorySize events, next is where the
+	// next event goes
+	history []Event
+	next    int
+	seq     uint64
+
+	dropped atomic.Uint64
 }
 
 var (
@@ -42,82 +97,165 @@ var (
 func GetInstance() *WebSocketManager {
 	once.Do(func() {
 		instance = &WebSocketManager{
-			clients:   make(map[*websocket.Conn]bool),
-			broadcast: make(chan LogMessage, 100), // Buffered channel
+			clients: make(map[*Client]bool),
+			history: make([]Event, 0, HistorySize),
 		}
-		go instance.Start()
 	})
 	return instance
 }
 
-func (wsm *WebSocketManager) Start() {
-	ticker := time.NewTicker(30 * time.Second)
+// matches reports whether a topic is one of the patterns; no patterns match
+// every topic
+func matches(patterns []string, topic string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+	for _, pattern := range patterns {
+		if pattern == topic || pattern == "*" {
+			return true
+		}
+		if prefix, ok := strings.CutSuffix(pattern, ".*"); ok && strings.HasPrefix(topic, prefix+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// AddClient registers a connection subscribed to topics, sends it the
+// buffered events after since and starts writing events to it. The caller
+// reads from the connection and removes the client when it closes.
+func (wsm *WebSocketManager) AddClient(conn *websocket.Conn, topics []string, since uint64) *Client {
+	client := &Client{
+		conn: conn,
+		send: make(chan Event, clientBuffer),
+		done: make(chan struct{}),
+	}
+
+	wsm.mutex.Lock()
+	wsm.clients[client] = true
+	wsm.subscribe(client, topics, since)
+	log.Printf("Client added. Total clients: %d", len(wsm.clients))
+	wsm.mutex.Unlock()
+
+	go wsm.write(client)
+	return client
+}
+
+// Subscribe replaces a client's topics and sends it the buffered events of
+// those topics after since
+func (wsm *WebSocketManager) Subscribe(client *Client, topics []string, since uint64) {
+	wsm.mutex.Lock()
+	defer wsm.mutex.Unlock()
+	wsm.subscribe(client, topics, since)
+}
+
+// subscribe needs wsm.mutex, so no event is published between the replay
+// and the new subscription
+func (wsm *WebSocketManager) subscribe(client *Client, topics []string, since uint64) {
+	client.topics = topics
+	for i := range wsm.history {
+		event := wsm.history[(wsm.next+i)%len(wsm.history)]
+		if event.Seq > since && matches(topics, event.Topic) {
+			wsm.deliver(client, event)
+		}
+	}
+}
+
+func (wsm *WebSocketManager) RemoveClient(client *Client) {
+	wsm.mutex.Lock()
+	defer wsm.mutex.Unlock()
+
+	if _, ok := wsm.clients[client]; ok {
+		delete(wsm.clients, client)
+		client.once.Do(func() { close(client.done) })
+		client.conn.Close()
+		log.Printf("Client removed, %d events dropped for it. Total clients: %d", client.dropped.Load(), len(wsm.clients))
+	}
+}
+
+// write sends a client's events and keeps the connection alive with pings
+func (wsm *WebSocketManager) write(client *Client) {
+	ticker := time.NewTicker(pingInterval)
 	defer ticker.Stop()
 
 	for {
 		select {
-		case message := <-wsm.broadcast:
-			wsm.mutex.RLock()
-			for client := range wsm.clients {
-				// Get or create a write mutex for this connection
-				mutex, _ := wsm.writeMu.LoadOrStore(client, &sync.Mutex{})
-				writeMu := mutex.(*sync.Mutex)
-
-				// Safe write with mutex
-				go func(c *websocket.Conn, msg LogMessage) {
-					writeMu.Lock()
-					defer writeMu.Unlock()
-
-					if err := c.WriteJSON(msg); err != nil {
-						log.Printf("Error writing to client: %v", err)
-						wsm.RemoveClient(c)
-					}
-				}(client, message)
+		case event := <-client.send:
+			client.conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+			if err := client.conn.WriteJSON(event); err != nil {
+				log.Printf("Error writing to client: %v", err)
+				wsm.RemoveClient(client)
+				return
 			}
-			wsm.mutex.RUnlock()
-
 		case <-ticker.C:
-			wsm.mutex.RLock()
-			for client := range wsm.clients {
-				mutex, _ := wsm.writeMu.LoadOrStore(client, &sync.Mutex{})
-				writeMu := mutex.(*sync.Mutex)
-
-				go func(c *websocket.Conn) {
-					writeMu.Lock()
-					defer writeMu.Unlock()
-
-					if err := c.WriteMessage(websocket.PingMessage, nil); err != nil {
-						log.Printf("Error sending ping: %v", err)
-						wsm.RemoveClient(c)
-					}
-				}(client)
+			if err := client.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(writeTimeout)); err != nil {
+				log.Printf("Error sending ping: %v", err)
+				wsm.RemoveClient(client)
+				return
 			}
-			wsm.mutex.RUnlock()
+		case <-client.done:
+			return
 		}
 	}
 }
 
-func (wsm *WebSocketManager) AddClient(conn *websocket.Conn) {
+// deliver queues an event for a client without waiting, dropping it when
+// the client is too far behind
+func (wsm *WebSocketManager) deliver(client *Client, event Event) {
+	select {
+	case client.send <- event:
+	default:
+		client.dropped.Add(1)
+		if wsm.dropped.Add(1)%100 == 1 {
+			log.Printf("Dropping events for a slow WebSocket client (%d dropped in total)", wsm.dropped.Load())
+		}
+	}
+}
+
+// Publish sends an event to the clients subscribed to its topic and keeps it
+// for late joiners. It never blocks on slow clients. jobID ties the event to
+// a scan or operation and may be empty.
+func (wsm *WebSocketManager) Publish(topic, jobID string, data interface{}) {
 	wsm.mutex.Lock()
 	defer wsm.mutex.Unlock()
 
-	wsm.clients[conn] = true
-	log.Printf("Client added. Total clients: %d", len(wsm.clients))
+	wsm.seq++
+	event := Event{
+		Seq:   wsm.seq,
+		Topic: topic,
+		JobID: jobID,
+		Time:  time.Now().Format("2006/01/02 15:04:05"),
+		Data:  data,
+	}
 
-	// Initialize write mutex for this connection
-	wsm.writeMu.Store(conn, &sync.Mutex{})
+	if len(wsm.history) < HistorySize {
+		wsm.history = append(wsm.history, event)
+	} else {
+		wsm.history[wsm.next] = event
+		wsm.next = (wsm.next + 1) % HistorySize
+	}
+
+	for client := range wsm.clients {
+		if matches(client.topics, topic) {
+			wsm.deliver(client, event)
+		}
+	}
+}
 
+// Log publishes a log line, cleaned of terminal colors, under the log topic
+func (wsm *WebSocketManager) Log(level, jobID, message string) {
+	wsm.Publish(TopicLog, jobID, LogData{Level: level, Message: cleanMessage(message)})
 }
 
-func (wsm *WebSocketManager) RemoveClient(conn *websocket.Conn) {
+// Stats returns the event counters
+func (wsm *WebSocketManager) Stats() Stats {
 	wsm.mutex.Lock()
 	defer wsm.mutex.Unlock()
-
-	if _, ok := wsm.clients[conn]; ok {
-		conn.Close()
-		delete(wsm.clients, conn)
-		wsm.writeMu.Delete(conn) // Clean up the write mutex
-		log.Printf("Client removed. Total clients: %d", len(wsm.clients))
+	return Stats{
+		Clients:   len(wsm.clients),
+		Published: wsm.seq,
+		Dropped:   wsm.dropped.Load(),
+		Buffered:  len(wsm.history),
 	}
 }
 
@@ -136,14 +274,3 @@ func cleanMessage(message string) string {
 
 	return strings.TrimSpace(cleaned)
 }
-
-func (wsm *WebSocketManager) BroadcastMessage(msgType, message string) {
-	cleanedMessage := cleanMessage(message)
-
-	// log.Printf("Broadcasting message: %s - %s", msgType, cleanedMessage)
-	wsm.broadcast <- LogMessage{
-		Type:    msgType,
-		Message: cleanedMessage,
-		Time:    time.Now().Format("2006/01/02 15:04:05"),
-	}
-}
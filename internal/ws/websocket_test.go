@@ -0,0 +1,95 @@
+package websocket
+
+import "testing"
+
+func newManager() *WebSocketManager {
+	return &WebSocketManager{
+		clients: make(map[*Client]bool),
+		history: make([]Event, 0, HistorySize),
+	}
+}
+
+// newClient is a client without a connection; tests read its send channel
+func newClient(buffer int) *Client {
+	return &Client{send: make(chan Event, buffer), done: make(chan struct{})}
+}
+
+func TestMatches(t *testing.T) {
+	tests := []struct {
+		patterns []string
+		topic    string
+		want     bool
+	}{
+		{nil, TopicLog, true},
+		{[]string{"*"}, TopicScanResult, true},
+		{[]string{TopicLog}, TopicLog, true},
+		{[]string{TopicLog}, TopicScanResult, false},
+		{[]string{"scan.*"}, TopicScanProgress, true},
+		{[]string{"scan.*"}, TopicNessusStatus, false},
+		{[]string{"scan.*"}, "scanner", false},
+	}
+	for _, test := range tests {
+		if got := matches(test.patterns, test.topic); got != test.want {
+			t.Errorf("matches(%v, %q) = %v, want %v", test.patterns, test.topic, got, test.want)
+		}
+	}
+}
+
+func TestHistoryReplay(t *testing.T) {
+	wsm := newManager()
+	for i := 0; i < HistorySize+10; i++ {
+		wsm.Publish(TopicLog, "", i)
+	}
+	wsm.Publish(TopicScanResult, "job", "done")
+
+	if stats := wsm.Stats(); stats.Buffered != HistorySize || stats.Published != HistorySize+11 {
+		t.Fatalf("stats = %+v, want %d buffered of %d published", stats, HistorySize, HistorySize+11)
+	}
+
+	// The oldest events were overwritten, so replay starts after them
+	client := newClient(clientBuffer)
+	wsm.subscribe(client, []string{TopicLog}, 0)
+	if n := len(client.send); n != HistorySize-1 {
+		t.Fatalf("replayed %d log events, want %d", n, HistorySize-1)
+	}
+	if first := <-client.send; first.Seq != 12 {
+		t.Errorf("first replayed seq = %d, want 12", first.Seq)
+	}
+
+	client = newClient(clientBuffer)
+	wsm.subscribe(client, nil, HistorySize+9)
+	if n := len(client.send); n != 2 {
+		t.Fatalf("replayed %d events since %d, want 2", n, HistorySize+9)
+	}
+	<-client.send
+	if last := <-client.send; last.Topic != TopicScanResult || last.JobID != "job" {
+		t.Errorf("last event = %+v, want the scan result of job", last)
+	}
+}
+
+func TestPublishDropsForSlowClients(t *testing.T) {
+	wsm := newManager()
+	slow := newClient(2)
+	logs := newClient(10)
+	wsm.clients[slow] = true
+	wsm.clients[logs] = true
+	logs.topics = []string{TopicLog}
+
+	for i := 0; i < 5; i++ {
+		wsm.Publish(TopicScanProgress, "", i)
+	}
+	wsm.Log("info", "", "\x1b[32mdone\x1b[0m")
+
+	if got := slow.dropped.Load(); got != 4 {
+		t.Errorf("slow client dropped %d events, want 4", got)
+	}
+	if got := wsm.Stats().Dropped; got != 4 {
+		t.Errorf("stats dropped = %d, want 4", got)
+	}
+	if n := len(logs.send); n != 1 {
+		t.Fatalf("log client got %d events, want 1", n)
+	}
+	if data := (<-logs.send).Data.(LogData); data.Message != "done" || data.Level != "info" {
+		t.Errorf("log data = %+v, want info done", data)
+	}
+}
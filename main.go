@@ -11,10 +11,12 @@ import (
 	"NMB/internal/n2p/client"
 	"NMB/internal/n2p/plextrac"
 	"NMB/internal/plugin"
+	"NMB/internal/settings"
 	"context"
 	"embed"
 	"encoding/json"
 	"fmt"
+	"io/fs"
 	"log"
 	"os"
 	"path/filepath"
@@ -61,6 +63,7 @@ type App struct {
 	screenshotManager *editor.ScreenshotManager
 	pluginManager     *plugin.Manager
 	logger            *logrus.Logger
+	apiConfig         api.ClientConfig
 }
 
 func NewApp() *App {
@@ -153,6 +156,11 @@ func (a *App) startup(ctx context.Context) {
 
 }
 
+// GetAPIConfig returns the API server's URL and token for the UI
+func (a *App) GetAPIConfig() api.ClientConfig {
+	return a.apiConfig
+}
+
 // SelectFile opens a file selection dialog
 func (a *App) SelectFile(filter string) (string, error) {
 	var dialogOptions runtime.OpenDialogOptions
@@ -686,10 +694,23 @@ func (a *App) BrowseForClientConfig() (string, error) {
 	})
 }
 
-// GetPlextracServers returns a list of available Plextrac servers
+// GetPlextracServers returns the Plextrac servers from the settings, the
+// default target first
 func (a *App) GetPlextracServers() []string {
-	// In a real implementation, this might come from a configuration file or API
-	return []string{"report"}
+	saved, err := settings.Load()
+	if err != nil {
+		log.Printf("Ignoring saved settings: %v", err)
+	}
+	return saved.PlextracServers
+}
+
+// defaultPlextrac returns the Plextrac server used when none is chosen
+func defaultPlextrac() string {
+	saved, err := settings.Load()
+	if err != nil {
+		log.Printf("Ignoring saved settings: %v", err)
+	}
+	return saved.PlextracServer()
 }
 
 // GetScopes returns a list of available scopes
@@ -748,7 +769,7 @@ func (a *App) GetReportTemplates(userCreds map[string]string) ([]map[string]stri
 	}
 
 	if targetPlextrac == "" {
-		targetPlextrac = "report" // Default value
+		targetPlextrac = defaultPlextrac()
 	}
 
 	// Create a properly formatted map for the n2p.Engine
@@ -877,7 +898,7 @@ func (a *App) GetFieldTemplates(userCreds map[string]string) ([]map[string]strin
 	}
 
 	if targetPlextrac == "" {
-		targetPlextrac = "report" // Default value
+		targetPlextrac = defaultPlextrac()
 	}
 
 	// Create a properly formatted map for the n2p.Engine
@@ -1776,10 +1797,51 @@ func (a *App) BulkUpdateFindings(request BulkUpdateRequest) UpdateResponse {
 	}
 }
 
+// runServer runs the API server and serves the embedded UI build to
+// browsers instead of opening the desktop window
+func runServer(cmdArgs []string) {
+	server, err := api.NewServer(api.ParseFlags("server", cmdArgs))
+	if err != nil {
+		log.Fatalf("Failed to start API server: %v", err)
+	}
+	ui, err := fs.Sub(assets, "ui-core/build")
+	if err != nil {
+		log.Fatalf("Failed to load the UI build: %v", err)
+	}
+	server.ServeUI(ui)
+
+	log.Printf("Open %s in a browser and enter the API token under Settings", server.ClientConfig().URL)
+
+	reporter := crash.NewReporter("crash_reports")
+	defer reporter.RecoverWithCrashReport("APIServer", nil)
+
+	if err := server.Run(); err != nil {
+		log.Fatalf("API server error: %v", err)
+	}
+}
+
 func main() {
 	// Setup global panic handler for uncaught exceptions
 	setupGlobalPanicHandler()
 
+	// Plugin fixture tests
+	if len(os.Args) > 1 && os.Args[1] == "plugin" {
+		engine.HandlePluginCommand(os.Args[2:])
+		return
+	}
+
+	// Compare two runs
+	if len(os.Args) > 1 && os.Args[1] == "diff" {
+		engine.HandleDiffCommand(os.Args[2:])
+		return
+	}
+
+	// API server without the desktop window, for headless drones
+	if len(os.Args) > 1 && os.Args[1] == "server" {
+		runServer(os.Args[2:])
+		return
+	}
+
 	// Command line handling
 	if len(os.Args) > 1 && os.Args[1] != "serve" {
 		parsedArgs := args.ParseArgs()
@@ -1792,7 +1854,9 @@ func main() {
 					"host":    parsedArgs.RemoteHost,
 					"project": parsedArgs.ProjectName,
 				})
-				engine.HandleNessusController(parsedArgs)
+				if err := engine.HandleNessusController(parsedArgs); err != nil {
+					logging.ErrorLogger.Fatalf("Nessus %s operation failed: %v", parsedArgs.NessusMode, err)
+				}
 			}()
 			return
 		}
@@ -1806,25 +1870,36 @@ func main() {
 				"numWorkers": fmt.Sprintf("%d", parsedArgs.NumWorkers),
 				"configFile": parsedArgs.ConfigFilePath,
 			})
-			engine.RunNMB(parsedArgs)
+			if err := engine.RunNMB(parsedArgs); err != nil {
+				logging.ErrorLogger.Fatalf("NMB run failed: %v", err)
+			}
 		}()
 		return
 	}
 
+	var serverConfig api.Config
+	if len(os.Args) > 1 {
+		serverConfig = api.ParseFlags("serve", os.Args[2:])
+	}
+	server, err := api.NewServer(serverConfig)
+	if err != nil {
+		log.Fatalf("Failed to start API server: %v", err)
+	}
+
 	app := NewApp()
+	app.apiConfig = server.ClientConfig()
 
 	// Start API server with crash reporting
 	go func() {
 		reporter := crash.NewReporter("crash_reports")
 		defer reporter.RecoverWithCrashReport("APIServer", nil)
 
-		server := api.NewServer()
 		if err := server.Run(); err != nil {
 			log.Printf("API server error: %v", err)
 		}
 	}()
 
-	err := wails.Run(&options.App{
+	err = wails.Run(&options.App{
 		Title:            "NMB",
 		Width:            1200,
 		Height:           800,
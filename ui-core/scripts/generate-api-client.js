@@ -0,0 +1,94 @@
+// scripts/generate-api-client.js
+//
+// Generates src/api/client.js from the API server's OpenAPI document:
+//
+//   npm run generate-api
+//
+// Every operation becomes a function named after its operationId. Path and
+// query parameters are passed in one object, and the request body, if any,
+// as the second argument. Empty query parameters are left out, so the
+// server can apply its defaults.
+const fs = require('fs');
+const path = require('path');
+
+const specFile = path.join(__dirname, '../../internal/api/openapi.json');
+const outFile = path.join(__dirname, '../src/api/client.js');
+
+// The axios instance the client is created with already points at /api
+const BASE_PATH = '/api';
+
+const spec = JSON.parse(fs.readFileSync(specFile, 'utf8'));
+
+const resolve = (node) => {
+  while (node.$ref) {
+    node = node.$ref
+      .replace(/^#\//, '')
+      .split('/')
+      .reduce((current, key) => current[key], spec);
+  }
+  return node;
+};
+
+const operations = [];
+for (const [route, item] of Object.entries(spec.paths)) {
+  const shared = (item.parameters || []).map(resolve);
+  for (const [method, operation] of Object.entries(item)) {
+    if (method === 'parameters' || !route.startsWith(`${BASE_PATH}/`)) {
+      continue;
+    }
+    const parameters = [...shared, ...(operation.parameters || []).map(resolve)];
+    operations.push({
+      name: operation.operationId,
+      summary: operation.summary,
+      method,
+      route: route.slice(BASE_PATH.length),
+      pathParams: parameters.filter((p) => p.in === 'path').map((p) => p.name),
+      queryParams: parameters.filter((p) => p.in === 'query').map((p) => p.name),
+      hasBody: Boolean(operation.requestBody),
+    });
+  }
+}
+operations.sort((a, b) => a.name.localeCompare(b.name));
+
+const renderOperation = (op) => {
+  const url = op.route.replace(/\{(\w+)\}/g, '${encodeURIComponent(params.$1)}');
+  const args = op.hasBody ? 'params = {}, body' : 'params = {}';
+  const options = op.queryParams.length
+    ? `{ params: query(params, [${op.queryParams.map((name) => `'${name}'`).join(', ')}]) }`
+    : '{}';
+  const request = op.hasBody
+    ? `http.${op.method}(\`${url}\`, body, ${options})`
+    : ['get', 'delete'].includes(op.method)
+      ? `http.${op.method}(\`${url}\`, ${options})`
+      : `http.${op.method}(\`${url}\`, undefined, ${options})`;
+
+  return `  // ${op.summary}
+  ${op.name}: (${args}) => ${request},`;
+};
+
+const source = `// src/api/client.js
+// Generated from internal/api/openapi.json by scripts/generate-api-client.js.
+// Do not edit; run \`npm run generate-api\` after changing the API.
+
+// query keeps the named query parameters that are set
+const query = (params, names) => {
+  const values = {};
+  for (const name of names) {
+    if (params[name] !== undefined && params[name] !== null && params[name] !== '') {
+      values[name] = params[name];
+    }
+  }
+  return values;
+};
+
+// createClient returns the API operations sent with an axios instance whose
+// baseURL points at ${BASE_PATH}. Each operation resolves to the axios response.
+export const createClient = (http) => ({
+${operations.map(renderOperation).join('\n\n')}
+});
+
+export default createClient;
+`;
+
+fs.writeFileSync(outFile, source);
+console.log(`Wrote ${operations.length} operations to ${path.relative(process.cwd(), outFile)}`);
@@ -0,0 +1,80 @@
+// src/api/client.js
+// Generated from internal/api/openapi.json by scripts/generate-api-client.js.
+// Do not edit; run `npm run generate-api` after changing the API.
+
+// query keeps the named query parameters that are set
+const query = (params, names) => {
+  const values = {};
+  for (const name of names) {
+    if (params[name] !== undefined && params[name] !== null && params[name] !== '') {
+      values[name] = params[name];
+    }
+  }
+  return values;
+};
+
+// createClient returns the API operations sent with an axios instance whose
+// baseURL points at /api. Each operation resolves to the axios response.
+export const createClient = (http) => ({
+  // Start, stop, pause, resume, export or delete a scan
+  controlScan: (params = {}, body) => http.post(`/nessus/scan/${encodeURIComponent(params.id)}/${encodeURIComponent(params.action)}`, body, { params: query(params, ['profile']) }),
+
+  // Delete a profile
+  deleteProfile: (params = {}) => http.delete(`/profiles/${encodeURIComponent(params.name)}`, {}),
+
+  // Get the WebSocket event counters
+  getEventStats: (params = {}) => http.get(`/events/stats`, {}),
+
+  // Get a host's information and findings
+  getHostDetail: (params = {}) => http.get(`/nessus/scan/${encodeURIComponent(params.id)}/hosts/${encodeURIComponent(params.host)}`, { params: query(params, ['profile', 'history']) }),
+
+  // This document
+  getOpenAPI: (params = {}) => http.get(`/openapi.json`, {}),
+
+  // Get the output of one plugin on one host
+  getPluginOutput: (params = {}) => http.get(`/nessus/scan/${encodeURIComponent(params.id)}/hosts/${encodeURIComponent(params.host)}/plugins/${encodeURIComponent(params.plugin)}`, { params: query(params, ['profile', 'history']) }),
+
+  // List the Nessus profiles without their secrets
+  getProfiles: (params = {}) => http.get(`/profiles`, {}),
+
+  // Get a scan with its hosts, findings and history
+  getScanDetail: (params = {}) => http.get(`/nessus/scan/${encodeURIComponent(params.id)}`, { params: query(params, ['profile']) }),
+
+  // List the previous runs of a scan
+  getScanHistory: (params = {}) => http.get(`/nessus/scan/${encodeURIComponent(params.id)}/history`, { params: query(params, ['profile']) }),
+
+  // List the hosts of a scan with their finding counts
+  getScanHosts: (params = {}) => http.get(`/nessus/scan/${encodeURIComponent(params.id)}/hosts`, { params: query(params, ['profile']) }),
+
+  // List the scans on a Nessus server
+  getScans: (params = {}) => http.get(`/nessus/scans`, { params: query(params, ['profile']) }),
+
+  // Get the saved settings
+  getSettings: (params = {}) => http.get(`/settings`, {}),
+
+  // List the plugins NMB can verify
+  getSupportedPlugins: (params = {}) => http.get(`/supported-plugins`, {}),
+
+  // Get whether the credential vault is locked
+  getVaultStatus: (params = {}) => http.get(`/vault`, {}),
+
+  // Lock the vault and drop the Nessus sessions
+  lockVault: (params = {}) => http.post(`/vault/lock`, undefined, {}),
+
+  // Run a Nessus controller operation
+  runNessusController: (params = {}, body) => http.post(`/nessus-controller`, body, {}),
+
+  // Add or replace a profile
+  saveProfile: (params = {}, body) => http.put(`/profiles/${encodeURIComponent(params.name)}`, body, {}),
+
+  // Validate and save the settings
+  saveSettings: (params = {}, body) => http.post(`/settings`, body, {}),
+
+  // Verify the findings of a Nessus file
+  startScan: (params = {}, body) => http.post(`/scan`, body, {}),
+
+  // Unlock the vault, creating it on first use
+  unlockVault: (params = {}, body) => http.post(`/vault/unlock`, body, {}),
+});
+
+export default createClient;
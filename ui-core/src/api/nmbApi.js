@@ -1,19 +1,52 @@
 // src/api/nmbApi.js
 import axios from 'axios';
+import { createClient } from './client';
 
-const API_BASE_URL = 'http://localhost:8080/api';
+const DEFAULT_API_URL = 'http://localhost:8080';
+const TOKEN_KEY = 'nmb_api_token';
+
+// The desktop app hands over the API server's URL and token; in a browser
+// the token is entered once and kept in localStorage
+let apiConfig = null;
+
+const isDesktop = () => Boolean(window.go?.main?.App);
+
+// `nmb server` serves the UI itself, so the API is on the page's origin;
+// the React development server is not
+const browserApiUrl = () => {
+  const { protocol, port, origin } = window.location;
+  if (!protocol.startsWith('http') || port === '3000') {
+    return DEFAULT_API_URL;
+  }
+  return origin;
+};
+
+const getApiConfig = async () => {
+  if (apiConfig) return apiConfig;
+  if (window.go?.main?.App?.GetAPIConfig) {
+    apiConfig = await window.go.main.App.GetAPIConfig();
+  } else {
+    apiConfig = {
+      url: browserApiUrl(),
+      token: localStorage.getItem(TOKEN_KEY) || '',
+    };
+  }
+  return apiConfig;
+};
 
 // Create an axios instance with default config
 const apiClient = axios.create({
-  baseURL: API_BASE_URL,
   headers: {
     'Content-Type': 'application/json',
   },
 });
 
-// Add request interceptor for logging
+// Add request interceptor for the API URL, token and logging
 apiClient.interceptors.request.use(
-  (config) => {
+  async (config) => {
+    const { url, token } = await getApiConfig();
+    config.baseURL = `${url}/api`;
+    config.headers.Authorization = `Bearer ${token}`;
     console.log('Making request to:', config.url);
     return config;
   },
@@ -42,10 +75,77 @@ apiClient.interceptors.response.use(
   }
 );
 
+// Operations generated from the server's OpenAPI document
+const api = createClient(apiClient);
+
 const nmbApi = {
+  // Whether the UI runs in the desktop app rather than a browser, where
+  // file dialogs and the other desktop bindings are not available
+  isDesktop,
+
+  // Use an API token in a browser, where the desktop app cannot provide it
+  setApiToken: (token) => {
+    localStorage.setItem(TOKEN_KEY, token);
+    apiConfig = null;
+  },
+
+  hasApiToken: () => isDesktop() || Boolean(localStorage.getItem(TOKEN_KEY)),
+
+  // The WebSocket URL. Without topics every event is sent; since replays
+  // the buffered events after that sequence number.
+  webSocketUrl: async ({ topics = [], since = 0 } = {}) => {
+    const { url } = await getApiConfig();
+    const params = new URLSearchParams();
+    if (topics.length) {
+      params.set('topics', topics.join(','));
+    }
+    if (since) {
+      params.set('since', since);
+    }
+    const query = params.toString();
+    return `${url.replace(/^http/, 'ws')}/ws${query ? `?${query}` : ''}`;
+  },
+
+  // The WebSocket subprotocols, which carry the token as browsers cannot
+  // set headers on WebSocket connections
+  webSocketProtocols: async () => {
+    const { token } = await getApiConfig();
+    return ['nmb', `nmb.token.${token}`];
+  },
+
+  // Convert a log or scan.result event to a log line of {type, message, time}
+  eventToLog: (event) => {
+    if (event.topic === 'scan.result') {
+      return {
+        type: event.data.status === 'failed' ? 'error' : 'success',
+        message: event.data.message,
+        time: event.time,
+      };
+    }
+    return { type: event.data.level || 'info', message: event.data.message, time: event.time };
+  },
+
+  getSettings: async () => {
+    try {
+      const response = await api.getSettings();
+      return response.data;
+    } catch (error) {
+      throw new Error(`Failed to get settings: ${error.message}`);
+    }
+  },
+
+  saveSettings: async (settings) => {
+    try {
+      const response = await api.saveSettings({}, settings);
+      return response.data;
+    } catch (error) {
+      throw new Error(`Failed to save settings: ${error.message}`);
+    }
+  },
+
   startScan: async (scanConfig) => {
     try {
-      const response = await apiClient.post('/scan', scanConfig);
+      const response = await api.startScan({}, scanConfig);
       return response.data;
     } catch (error) {
       throw new Error(`Failed to start scan: ${error.message}`);
@@ -54,7 +154,7 @@ const nmbApi = {
 
   getSupportedPlugins: async () => {
     try {
-      const response = await apiClient.get('/supported-plugins');
+      const response = await api.getSupportedPlugins();
       return response.data;
     } catch (error) {
       throw new Error(`Failed to get supported plugins: ${error.message}`);
@@ -63,85 +163,201 @@ const nmbApi = {
 
   controlNessus: async (controlConfig) => {
     try {
-      const response = await apiClient.post('/nessus-controller', controlConfig);
+      const response = await api.runNessusController({}, controlConfig);
       return response.data;
     } catch (error) {
       throw new Error(`Failed to control Nessus: ${error.message}`);
     }
   },
   
+  // Credential vault; Nessus servers are referenced by profile name
+  getVaultStatus: async () => {
+    try {
+      const response = await api.getVaultStatus();
+      return response.data;
+    } catch (error) {
+      throw new Error(`Failed to get vault status: ${error.message}`);
+    }
+  },
+
+  unlockVault: async (passphrase) => {
+    try {
+      const response = await api.unlockVault({}, { passphrase });
+      return response.data;
+    } catch (error) {
+      throw new Error(`Failed to unlock vault: ${error.message}`);
+    }
+  },
+
+  lockVault: async () => {
+    try {
+      const response = await api.lockVault();
+      return response.data;
+    } catch (error) {
+      throw new Error(`Failed to lock vault: ${error.message}`);
+    }
+  },
+
+  getProfiles: async () => {
+    try {
+      const response = await api.getProfiles();
+      return response.data;
+    } catch (error) {
+      throw new Error(`Failed to get profiles: ${error.message}`);
+    }
+  },
+
+  // Secrets left empty keep their saved values
+  saveProfile: async (profile) => {
+    try {
+      const response = await api.saveProfile({ name: profile.name }, profile);
+      return response.data;
+    } catch (error) {
+      throw new Error(`Failed to save profile: ${error.message}`);
+    }
+  },
+
+  deleteProfile: async (name) => {
+    try {
+      const response = await api.deleteProfile({ name });
+      return response.data;
+    } catch (error) {
+      throw new Error(`Failed to delete profile: ${error.message}`);
+    }
+  },
+
   // New methods for enhanced Nessus Controller
-  getScans: async (host, user = 'bstg', pass = 'BulletH@x') => {
+  getScans: async (profile) => {
     try {
-      const response = await apiClient.get(`/nessus/scans?host=${encodeURIComponent(host)}&user=${encodeURIComponent(user)}&pass=${encodeURIComponent(pass)}`);
+      const response = await api.getScans({ profile });
       return response.data;
     } catch (error) {
       throw new Error(`Failed to get scans: ${error.message}`);
     }
   },
   
-  getScanDetail: async (scanId, host, user = 'bstg', pass = 'BulletH@x') => {
+  getScanDetail: async (scanId, profile) => {
     try {
-      const response = await apiClient.get(`/nessus/scan/${scanId}?host=${encodeURIComponent(host)}&user=${encodeURIComponent(user)}&pass=${encodeURIComponent(pass)}`);
+      const response = await api.getScanDetail({ id: scanId, profile });
       return response.data;
     } catch (error) {
       throw new Error(`Failed to get scan details: ${error.message}`);
     }
   },
   
-  controlScan: async (scanId, action, host, user = 'bstg', pass = 'BulletH@x') => {
+  controlScan: async (scanId, action, profile) => {
     try {
-      const response = await apiClient.post(`/nessus/scan/${scanId}/${action}?host=${encodeURIComponent(host)}&user=${encodeURIComponent(user)}&pass=${encodeURIComponent(pass)}`);
+      const response = await api.controlScan({ id: scanId, action, profile });
       return response.data;
     } catch (error) {
       throw new Error(`Failed to ${action} scan: ${error.message}`);
     }
   },
+
+  // Drill-down into a scan's results; history selects an earlier run
+  getScanHosts: async (scanId, profile) => {
+    try {
+      const response = await api.getScanHosts({ id: scanId, profile });
+      return response.data;
+    } catch (error) {
+      throw new Error(`Failed to get scan hosts: ${error.message}`);
+    }
+  },
+
+  getHostDetail: async (scanId, hostId, profile, history = '') => {
+    try {
+      const response = await api.getHostDetail({ id: scanId, host: hostId, profile, history });
+      return response.data;
+    } catch (error) {
+      throw new Error(`Failed to get host details: ${error.message}`);
+    }
+  },
+
+  getPluginOutput: async (scanId, hostId, pluginId, profile, history = '') => {
+    try {
+      const response = await api.getPluginOutput({ id: scanId, host: hostId, plugin: pluginId, profile, history });
+      return response.data;
+    } catch (error) {
+      throw new Error(`Failed to get plugin output: ${error.message}`);
+    }
+  },
+
+  getScanHistory: async (scanId, profile) => {
+    try {
+      const response = await api.getScanHistory({ id: scanId, profile });
+      return response.data;
+    } catch (error) {
+      throw new Error(`Failed to get scan history: ${error.message}`);
+    }
+  },
   
-  // WebSocket connection management
-  connectToWebSocket: (onMessage) => {
-    const wsProtocol = window.location.protocol === 'https:' ? 'wss' : 'ws';
-    const wsUrl = `${wsProtocol}://${window.location.hostname}:8080/ws`;
-    
-    const socket = new WebSocket(wsUrl);
-    
-    socket.onopen = () => {
-      console.log('WebSocket connection established');
-      
-      // Set up ping to keep connection alive
-      const pingInterval = setInterval(() => {
-        if (socket.readyState === WebSocket.OPEN) {
-          socket.send('ping');
-        } else {
-          clearInterval(pingInterval);
+  // WebSocket connection management. onMessage gets each event of the topics,
+  // or of every topic when none are given. The returned connection can be
+  // closed before the socket has been opened, and is not reconnected once
+  // closed; until then reconnects replay the events missed in between.
+  connectToWebSocket: (onMessage, topics = []) => {
+    const connection = {
+      socket: null,
+      closed: false,
+      lastSeq: 0,
+      close() {
+        this.closed = true;
+        if (this.socket) {
+          this.socket.close();
         }
-      }, 30000);
+      },
     };
+
+    const connect = async () => {
+      const wsUrl = await nmbApi.webSocketUrl({ topics, since: connection.lastSeq });
+      const protocols = await nmbApi.webSocketProtocols();
+      if (connection.closed) return;
+
+      const socket = new WebSocket(wsUrl, protocols);
+      connection.socket = socket;
     
-    socket.onmessage = (event) => {
-      try {
-        const data = JSON.parse(event.data);
-        onMessage(data);
-      } catch (error) {
-        console.error('Failed to parse WebSocket message:', error);
-      }
-    };
+      socket.onopen = () => {
+        console.log('WebSocket connection established');
+      
+        // Set up ping to keep connection alive
+        const pingInterval = setInterval(() => {
+          if (socket.readyState === WebSocket.OPEN) {
+            socket.send('ping');
+          } else {
+            clearInterval(pingInterval);
+          }
+        }, 30000);
+      };
     
-    socket.onerror = (error) => {
-      console.error('WebSocket error:', error);
-    };
+      socket.onmessage = (event) => {
+        try {
+          const data = JSON.parse(event.data);
+          if (data.seq <= connection.lastSeq) return;
+          connection.lastSeq = data.seq;
+          onMessage(data);
+        } catch (error) {
+          console.error('Failed to parse WebSocket message:', error);
+        }
+      };
     
-    socket.onclose = (event) => {
-      console.log('WebSocket connection closed:', event.code, event.reason);
+      socket.onerror = (error) => {
+        console.error('WebSocket error:', error);
+      };
+    
+      socket.onclose = (event) => {
+        console.log('WebSocket connection closed:', event.code, event.reason);
+        if (connection.closed) return;
       
-      // Attempt to reconnect after a delay
-      setTimeout(() => {
-        console.log('Attempting to reconnect WebSocket...');
-        nmbApi.connectToWebSocket(onMessage);
-      }, 5000);
+        // Attempt to reconnect after a delay
+        setTimeout(() => {
+          console.log('Attempting to reconnect WebSocket...');
+          connect();
+        }, 5000);
+      };
     };
-    
-    return socket;
+
+    connect();
+    return connection;
   }
 };
 
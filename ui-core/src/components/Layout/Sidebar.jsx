@@ -20,6 +20,7 @@ import {
   Image
 } from 'lucide-react';
 import { useNavigate, useLocation } from 'react-router-dom';
+import nmbApi from '../../api/nmbApi';
 
 const DrawerWidth = 240;
 
@@ -40,12 +41,12 @@ const Sidebar = () => {
     { text: 'Dashboard', icon: <Home size={20} />, path: '/' },
     { text: 'NMB Manager', icon: <Scan size={20} />, path: '/scan' },
     { text: 'Nessus Control', icon: <Shield size={20} />, path: '/nessus' },
-    { text: 'Nessus2Plextrac', icon: <Menu size={20} />, path: '/n2p' },
-    { text: 'Screenshot Editor', icon: <Image size={20} />, path: '/screenshots' },
-    { text: 'Plugin Manager', icon: <Activity size={20} />, path: '/plugins' },
+    { text: 'Nessus2Plextrac', icon: <Menu size={20} />, path: '/n2p', desktopOnly: true },
+    { text: 'Screenshot Editor', icon: <Image size={20} />, path: '/screenshots', desktopOnly: true },
+    { text: 'Plugin Manager', icon: <Activity size={20} />, path: '/plugins', desktopOnly: true },
     { text: 'Settings', icon: <Settings size={20} />, path: '/settings' },
 
-  ];
+  ].filter((item) => !item.desktopOnly || nmbApi.isDesktop());
   
   return (
     <StyledDrawer variant="permanent" anchor="left">
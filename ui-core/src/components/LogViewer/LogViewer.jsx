@@ -210,6 +210,7 @@
 import React, { useState, useEffect, useRef } from 'react';
 import { Box, Paper, Typography, IconButton } from '@mui/material';
 import { RotateCcw, Download, X } from 'lucide-react';
+import nmbApi from '../../api/nmbApi';
 
 const LogViewer = () => {
   const [logs, setLogs] = useState(() => {
@@ -222,6 +223,8 @@ const LogViewer = () => {
   const logContainerRef = useRef(null);
   const reconnectTimeoutRef = useRef(null);
   const pingIntervalRef = useRef(null);
+  // The last event received, so reconnects only replay the events missed
+  const lastSeqRef = useRef(Number(localStorage.getItem('websocketLastSeq')) || 0);
 
   // Update localStorage whenever logs change
   useEffect(() => {
@@ -252,13 +255,16 @@ const LogViewer = () => {
     }
   };
 
-  const connectWebSocket = () => {
+  const connectWebSocket = async () => {
     // Clean up any existing connection first
     cleanupWebSocket();
 
     try {
       console.log('Attempting to connect WebSocket...');
-      const ws = new WebSocket('ws://localhost:8080/ws');
+      const ws = new WebSocket(await nmbApi.webSocketUrl({
+        topics: ['log', 'scan.result'],
+        since: lastSeqRef.current,
+      }), await nmbApi.webSocketProtocols());
       
       ws.onopen = () => {
         console.log('WebSocket Connected');
@@ -296,7 +302,12 @@ const LogViewer = () => {
 
       ws.onmessage = (event) => {
         try {
-          const message = JSON.parse(event.data);
+          const data = JSON.parse(event.data);
+          if (data.seq <= lastSeqRef.current) return;
+          lastSeqRef.current = data.seq;
+          localStorage.setItem('websocketLastSeq', String(data.seq));
+
+          const message = nmbApi.eventToLog(data);
           setLogs(prev => {
             const updatedLogs = [...prev, message];
             localStorage.setItem('websocketLogs', JSON.stringify(updatedLogs));
@@ -22,39 +22,11 @@ import nmbApi from '../api/nmbApi';
 
 const SCAN_FORM_KEY = 'scanform_state';
 const SETTINGS_KEY = 'nmb_settings';
-const DRONE_LIST = [
-  "arkanoid", "asteroids", "bagman", "barbarian", "breakout", "civilization",
-  "contra", "crossbow", "defender", "digdug", "doom", "donkeykong", "duckhunt",
-  "galaga", "gauntlet", "gravitar", "journey", "joust", "jumpman", "junglehunt",
-  "kaboom", "karatechamp", "knockout", "kungfu", "megaman", "meltdown", "metalgear",
-  "millipede", "paperboy", "pitfall", "poleposition", "pong", "pooyan", "popeye",
-  "punchout", "qbert", "rampage", "sinistar", "spaceinvaders", "spyhunter",
-  "streetfighter", "tempest", "tron", "wizardry", "beserk", "zaxxon", "zork",
-  "doubledragon", "gorf", "ballblazer", "centipede", "combat", "hangon", "mario",
-  "foodfight", "excitebike", "jinks", "zelda", "frogger", "battlezone", "freeway",
-  "pacman", "pendrone", "tetris", "sonic", "burgertime", "diablo", "lemmings", "quake", "myst",
-  "fzero", "blasteroids", "outrun", "rampart", "castlevania", "ikari", "halo",
-  "simcity", "warcraft", "starwars", "choplifter", "gunfight", "metroid",
-  "ninjagaiden", "iceclimber", "icehockey", "radracer", "battletoads", "gradius",
-  "ninjaturtles", "fdl-aws-1", "hrd-1", "rsi-aws-1", "evi-lite-1", "evi-lite-2",
-  "hrd-lite-1", "trm-lite-1", "brain", "wily", "eggman", "ganon", "goro", "mantis",
-  "sigma", "wario", "blinky", "goomba", "kefka", "bison", "bowser", "kingboo",
-  "clyde", "mewtwo", "koopa", "akuma"
-];
-
-// Default credentials
-const DEFAULT_CREDENTIALS = {
-  username: 'bstg',
-  password: 'BulletH@x'
-};
-
 const ScanForm = () => {
   const initialState = {
     nessusFilePath: '',
     projectFolder: '',
-    remoteHost: '',
-    remoteUser: '',
-    remotePass: '',
+    profile: '',
     remoteKey: '',
     numWorkers: 4,
     configFilePath: '',
@@ -64,9 +36,15 @@ const ScanForm = () => {
 
   const [formData, setFormData] = useState(() => {
     const savedState = localStorage.getItem(SCAN_FORM_KEY);
-    return savedState ? JSON.parse(savedState) : initialState;
+    if (!savedState) return initialState;
+    // Credentials now come from a vault profile; drop any saved before
+    const { remoteHost, remoteUser, remotePass, ...saved } = JSON.parse(savedState);
+    return { ...initialState, ...saved };
   });
 
+  // Vault profiles, without their secrets
+  const [profiles, setProfiles] = useState([]);
+
   const [status, setStatus] = useState({
     open: false,
     message: '',
@@ -92,6 +70,12 @@ const ScanForm = () => {
     }
   }, []);
 
+  useEffect(() => {
+    nmbApi.getProfiles()
+      .then(response => setProfiles(response.profiles || []))
+      .catch(error => console.error('Error loading profiles:', error));
+  }, []);
+
   useEffect(() => {
     localStorage.setItem(SCAN_FORM_KEY, JSON.stringify(formData));
   }, [formData]);
@@ -105,12 +89,10 @@ const ScanForm = () => {
   };
   
 
-  const handleHostChange = (event, newValue) => {
+  const handleProfileChange = (event, newValue) => {
     setFormData(prev => ({
       ...prev,
-      remoteHost: newValue || '',
-      remoteUser: newValue ? DEFAULT_CREDENTIALS.username : '',
-      remotePass: newValue ? DEFAULT_CREDENTIALS.password : ''
+      profile: newValue || ''
     }));
   };
 
@@ -189,7 +171,6 @@ const ScanForm = () => {
     const errors = [];
     if (!formData.nessusFilePath) errors.push('Nessus file path is required');
     if (!formData.projectFolder) errors.push('Project folder is required');
-    if (formData.remoteHost && !formData.remoteUser) errors.push('Remote user is required when using remote host');
     if (formData.numWorkers < 1) errors.push('Number of workers must be at least 1');
     
     if (errors.length > 0) {
@@ -277,49 +258,21 @@ const ScanForm = () => {
             />
           </Grid>
 
-          <Grid item xs={12}>
+          <Grid item xs={12} md={6}>
             <Autocomplete
               fullWidth
-              options={DRONE_LIST}
-              value={formData.remoteHost}
-              onChange={handleHostChange}
+              options={profiles.map(profile => profile.name)}
+              value={formData.profile || null}
+              onChange={handleProfileChange}
               renderInput={(params) => (
                 <TextField
                   {...params}
-                  label="Remote Host"
-                  placeholder="Search for a drone..."
+                  label="Nessus Profile"
+                  placeholder="Search for a profile..."
+                  helperText="Server and credentials saved in the vault (see Settings)"
                   required={false}
                 />
               )}
-              freeSolo
-              renderOption={(props, option) => (
-                <Box component="li" {...props}>
-                  <Typography variant="body1">{option}</Typography>
-                </Box>
-              )}
-            />
-          </Grid>
-
-          <Grid item xs={12} md={6}>
-            <TextField
-              fullWidth
-              label="Remote User"
-              name="remoteUser"
-              value={formData.remoteUser}
-              onChange={handleChange}
-              disabled={!formData.remoteHost}
-            />
-          </Grid>
-
-          <Grid item xs={12} md={6}>
-            <TextField
-              fullWidth
-              type="password"
-              label="Remote Password"
-              name="remotePass"
-              value={formData.remotePass}
-              onChange={handleChange}
-              disabled={!formData.remoteHost}
             />
           </Grid>
 
@@ -330,14 +283,14 @@ const ScanForm = () => {
               name="remoteKey"
               value={formData.remoteKey}
               onChange={handleChange}
-              disabled={!formData.remoteHost}
+              disabled={!formData.profile}
               InputProps={{
                 endAdornment: (
                   <InputAdornment position="end">
                     <Tooltip title="Browse for SSH key">
                       <IconButton 
                         onClick={() => handleBrowseFile('key')}
-                        disabled={isLoading || !formData.remoteHost}
+                        disabled={isLoading || !formData.profile}
                         sx={{ mr: 1 }}
                       >
                         <Folder />
@@ -346,7 +299,7 @@ const ScanForm = () => {
                     <Tooltip title="Use default SSH key">
                       <IconButton
                         onClick={useDefaultSSHKey}
-                        disabled={isLoading || !formData.remoteHost}
+                        disabled={isLoading || !formData.profile}
                       >
                         <Key />
                       </IconButton>
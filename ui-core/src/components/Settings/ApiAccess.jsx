@@ -0,0 +1,60 @@
+// src/components/Settings/ApiAccess.jsx
+import React, { useState } from 'react';
+import {
+  Paper,
+  Typography,
+  TextField,
+  Button,
+  Box,
+  Alert,
+} from '@mui/material';
+import { Key } from 'lucide-react';
+import nmbApi from '../../api/nmbApi';
+
+// The API token for browsers, which `nmb server` prints on first start. The
+// desktop app reads it by itself.
+const ApiAccess = () => {
+  const [token, setToken] = useState('');
+  const [saved, setSaved] = useState(nmbApi.hasApiToken());
+
+  const handleSubmit = (e) => {
+    e.preventDefault();
+    nmbApi.setApiToken(token.trim());
+    setToken('');
+    setSaved(true);
+    // Reload so every page fetches its data with the token
+    window.location.reload();
+  };
+
+  return (
+    <Paper sx={{ p: 3, mb: 3 }}>
+      <Typography variant="h6" gutterBottom>API Access</Typography>
+      <Alert severity={saved ? 'success' : 'warning'} sx={{ mb: 2 }}>
+        {saved
+          ? 'This browser has an API token. Enter a new one if the server\'s token was replaced.'
+          : 'Enter the API token printed by nmb server, or read it from nmb/api_token in the config folder on the server.'}
+      </Alert>
+      <form onSubmit={handleSubmit}>
+        <Box sx={{ display: 'flex', gap: 2 }}>
+          <TextField
+            fullWidth
+            type="password"
+            label="API Token"
+            value={token}
+            onChange={(e) => setToken(e.target.value)}
+          />
+          <Button
+            type="submit"
+            variant="contained"
+            startIcon={<Key />}
+            disabled={!token.trim()}
+          >
+            Save
+          </Button>
+        </Box>
+      </form>
+    </Paper>
+  );
+};
+
+export default ApiAccess;
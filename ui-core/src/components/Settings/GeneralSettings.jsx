@@ -22,19 +22,26 @@ import {
   DialogContent,
   DialogActions,
   Snackbar,
+  Autocomplete,
 } from '@mui/material';
 import { Save, Folder, Plus, Trash2, Edit2 } from 'lucide-react';
+import nmbApi from '../../api/nmbApi';
 
 const GeneralSettings = () => {
   const [settings, setSettings] = useState({
-    defaultProjectFolder: '/evidence',
-    maxWorkers: 4,
+    defaultProjectFolder: 'output',
+    maxWorkers: 10,
     autoStart: true,
-    telemetry: true,
+    telemetry: false,
     sshKeyFile: '',
     drones: [],
+    plextracServers: ['report'],
+    nessusProfile: '',
   });
 
+  // Profile names from the vault, empty while it is locked
+  const [profiles, setProfiles] = useState([]);
+
   const [status, setStatus] = useState({
     open: false,
     message: '',
@@ -50,12 +57,12 @@ const GeneralSettings = () => {
   useEffect(() => {
     const loadSettings = async () => {
       try {
-        const response = await fetch('http://localhost:8080/api/settings');
-        const data = await response.json();
+        const data = await nmbApi.getSettings();
         // Ensure drones is always an array
         setSettings({
           ...data,
-          drones: data.drones || []  // Use empty array if drones is null/undefined
+          drones: data.drones || [],  // Use empty array if drones is null/undefined
+          plextracServers: data.plextracServers || []
         });
       } catch (error) {
         showStatus('Error loading settings', 'error');
@@ -66,17 +73,40 @@ const GeneralSettings = () => {
         }));
       }
     };
+    const loadProfiles = async () => {
+      try {
+        const vault = await nmbApi.getVaultStatus();
+        if (!vault.locked) {
+          const response = await nmbApi.getProfiles();
+          setProfiles((response.profiles || []).map(profile => profile.name));
+        }
+      } catch (error) {
+        // The default profile can still be typed in by hand
+      }
+    };
     loadSettings();
+    loadProfiles();
   }, []);
 
   const handleChange = (e) => {
     const { name, value, type, checked } = e.target;
+    let fieldValue = value;
+    if (type === 'checkbox') {
+      fieldValue = checked;
+    } else if (type === 'number') {
+      fieldValue = parseInt(value, 10) || 0;
+    }
     setSettings(prev => ({
       ...prev,
-      [name]: type === 'checkbox' ? checked : value
+      [name]: fieldValue
     }));
   };
 
+  const handlePlextracServers = (e) => {
+    const servers = e.target.value.split(',').map(server => server.trim());
+    setSettings(prev => ({ ...prev, plextracServers: servers }));
+  };
+
   const handleBrowseFile = async (type) => {
     try {
       let result;
@@ -113,18 +143,11 @@ const GeneralSettings = () => {
   const handleSubmit = async (e) => {
     e.preventDefault();
     try {
-      const response = await fetch('http://localhost:8080/api/settings', {
-        method: 'POST',
-        headers: {
-          'Content-Type': 'application/json',
-        },
-        body: JSON.stringify(settings),
+      await nmbApi.saveSettings({
+        ...settings,
+        plextracServers: settings.plextracServers.filter(server => server !== '')
       });
       
-      if (!response.ok) {
-        throw new Error('Failed to save settings');
-      }
-      
       showStatus('Settings saved successfully');
       
       // Also save to localStorage for local access
@@ -230,6 +253,32 @@ const GeneralSettings = () => {
             />
           </Grid>
 
+          <Grid item xs={12} md={6}>
+            <Autocomplete
+              freeSolo
+              options={profiles}
+              value={settings.nessusProfile || ''}
+              onInputChange={(_, value) => setSettings(prev => ({ ...prev, nessusProfile: value }))}
+              renderInput={(params) => (
+                <TextField
+                  {...params}
+                  label="Default Nessus Profile"
+                  helperText="Vault profile used when a request names no Nessus server"
+                />
+              )}
+            />
+          </Grid>
+
+          <Grid item xs={12}>
+            <TextField
+              fullWidth
+              label="Plextrac Servers"
+              value={settings.plextracServers.join(', ')}
+              onChange={handlePlextracServers}
+              helperText="Comma-separated instance names; the first one is the default"
+            />
+          </Grid>
+
           {/* <Grid item xs={12}>
             <Divider sx={{ my: 2 }} />
             <Box sx={{ display: 'flex', justifyContent: 'space-between', alignItems: 'center', mb: 2 }}>
@@ -0,0 +1,388 @@
+// src/components/Settings/NessusProfiles.jsx
+import React, { useState, useEffect } from 'react';
+import {
+  Paper,
+  Typography,
+  TextField,
+  Button,
+  Box,
+  Alert,
+  Grid,
+  IconButton,
+  InputAdornment,
+  List,
+  ListItem,
+  ListItemText,
+  ListItemSecondaryAction,
+  Dialog,
+  DialogTitle,
+  DialogContent,
+  DialogActions,
+  Snackbar,
+} from '@mui/material';
+import { Folder, Plus, Trash2, Edit2, Lock, Unlock } from 'lucide-react';
+import nmbApi from '../../api/nmbApi';
+
+const EMPTY_PROFILE = {
+  name: '',
+  url: '',
+  host: '',
+  username: '',
+  password: '',
+  accessKey: '',
+  secretKey: '',
+  keyFile: '',
+};
+
+// Nessus servers and their credentials, kept in the encrypted vault
+const NessusProfiles = () => {
+  const [vault, setVault] = useState({ locked: true, path: '' });
+  const [passphrase, setPassphrase] = useState('');
+  const [profiles, setProfiles] = useState([]);
+
+  const [status, setStatus] = useState({
+    open: false,
+    message: '',
+    severity: 'success'
+  });
+
+  const [profileDialog, setProfileDialog] = useState({
+    open: false,
+    mode: 'add',
+    profile: EMPTY_PROFILE
+  });
+
+  const showStatus = (message, severity = 'success') => {
+    setStatus({
+      open: true,
+      message,
+      severity
+    });
+  };
+
+  const loadVault = async () => {
+    try {
+      const data = await nmbApi.getVaultStatus();
+      setVault(data);
+      if (!data.locked) {
+        const response = await nmbApi.getProfiles();
+        setProfiles(response.profiles || []);
+      } else {
+        setProfiles([]);
+      }
+    } catch (error) {
+      showStatus(error.message, 'error');
+    }
+  };
+
+  useEffect(() => {
+    loadVault();
+  }, []);
+
+  const handleUnlock = async (e) => {
+    e.preventDefault();
+    try {
+      await nmbApi.unlockVault(passphrase);
+      setPassphrase('');
+      showStatus('Vault unlocked');
+      loadVault();
+    } catch (error) {
+      showStatus(error.message, 'error');
+    }
+  };
+
+  const handleLock = async () => {
+    try {
+      await nmbApi.lockVault();
+      showStatus('Vault locked');
+      loadVault();
+    } catch (error) {
+      showStatus(error.message, 'error');
+    }
+  };
+
+  const handleAddProfile = () => {
+    setProfileDialog({
+      open: true,
+      mode: 'add',
+      profile: EMPTY_PROFILE
+    });
+  };
+
+  // Secrets are never sent back by the server; leaving them empty keeps them
+  const handleEditProfile = (profile) => {
+    setProfileDialog({
+      open: true,
+      mode: 'edit',
+      profile: { ...EMPTY_PROFILE, ...profile }
+    });
+  };
+
+  const handleDeleteProfile = async (name) => {
+    if (!window.confirm(`Delete profile ${name}?`)) {
+      return;
+    }
+    try {
+      await nmbApi.deleteProfile(name);
+      showStatus('Profile removed');
+      loadVault();
+    } catch (error) {
+      showStatus(error.message, 'error');
+    }
+  };
+
+  const handleSaveProfile = async () => {
+    const { mode, profile } = profileDialog;
+    try {
+      await nmbApi.saveProfile(profile);
+      setProfileDialog(prev => ({ ...prev, open: false }));
+      showStatus(`Profile ${mode === 'add' ? 'added' : 'updated'} successfully`);
+      loadVault();
+    } catch (error) {
+      showStatus(error.message, 'error');
+    }
+  };
+
+  const handleBrowseKey = async () => {
+    try {
+      const result = await window.go.main.App.SelectFile("SSH Key");
+      if (result) {
+        setProfileField('keyFile', result);
+      }
+    } catch (error) {
+      showStatus(`Error selecting key: ${error.message}`, 'error');
+    }
+  };
+
+  const setProfileField = (field, value) => {
+    setProfileDialog(prev => ({
+      ...prev,
+      profile: { ...prev.profile, [field]: value }
+    }));
+  };
+
+  const describeProfile = (profile) => {
+    const server = profile.url || profile.host;
+    const auth = profile.hasApiKeys ? 'API keys' : profile.username;
+    return auth ? `${auth} @ ${server}` : server;
+  };
+
+  return (
+    <Paper sx={{ p: 3, mt: 3 }}>
+      <Box sx={{ display: 'flex', justifyContent: 'space-between', alignItems: 'center', mb: 2 }}>
+        <Typography variant="h6">Nessus Profiles</Typography>
+        {!vault.locked && (
+          <Box sx={{ display: 'flex', gap: 1 }}>
+            <Button
+              startIcon={<Plus />}
+              onClick={handleAddProfile}
+              variant="outlined"
+              size="small"
+            >
+              Add Profile
+            </Button>
+            <Button
+              startIcon={<Lock />}
+              onClick={handleLock}
+              size="small"
+            >
+              Lock
+            </Button>
+          </Box>
+        )}
+      </Box>
+
+      {vault.locked ? (
+        <form onSubmit={handleUnlock}>
+          <Alert severity="info" sx={{ mb: 2 }}>
+            Profiles are encrypted in {vault.path || 'the vault'}. Enter the master passphrase
+            to unlock them; the first passphrase entered creates the vault.
+          </Alert>
+          <Box sx={{ display: 'flex', gap: 2 }}>
+            <TextField
+              fullWidth
+              type="password"
+              label="Vault Passphrase"
+              value={passphrase}
+              onChange={(e) => setPassphrase(e.target.value)}
+            />
+            <Button
+              type="submit"
+              variant="contained"
+              startIcon={<Unlock />}
+              disabled={!passphrase}
+            >
+              Unlock
+            </Button>
+          </Box>
+        </form>
+      ) : profiles.length === 0 ? (
+        <Typography variant="body2" color="text.secondary">
+          No profiles yet. Add a Nessus server to use it for scans.
+        </Typography>
+      ) : (
+        <List>
+          {profiles.map((profile) => (
+            <ListItem
+              key={profile.name}
+              sx={{
+                bgcolor: 'background.paper',
+                mb: 1,
+                borderRadius: 1,
+                border: '1px solid',
+                borderColor: 'divider'
+              }}
+            >
+              <ListItemText
+                primary={profile.name}
+                secondary={describeProfile(profile)}
+              />
+              <ListItemSecondaryAction>
+                <IconButton
+                  edge="end"
+                  onClick={() => handleEditProfile(profile)}
+                  sx={{ mr: 1 }}
+                >
+                  <Edit2 />
+                </IconButton>
+                <IconButton
+                  edge="end"
+                  onClick={() => handleDeleteProfile(profile.name)}
+                  color="error"
+                >
+                  <Trash2 />
+                </IconButton>
+              </ListItemSecondaryAction>
+            </ListItem>
+          ))}
+        </List>
+      )}
+
+      {/* Profile Dialog */}
+      <Dialog
+        open={profileDialog.open}
+        onClose={() => setProfileDialog(prev => ({ ...prev, open: false }))}
+      >
+        <DialogTitle>
+          {profileDialog.mode === 'add' ? 'Add Nessus Profile' : 'Edit Nessus Profile'}
+        </DialogTitle>
+        <DialogContent>
+          <Grid container spacing={2} sx={{ mt: 1 }}>
+            <Grid item xs={12}>
+              <TextField
+                fullWidth
+                label="Profile Name"
+                value={profileDialog.profile.name}
+                disabled={profileDialog.mode === 'edit'}
+                onChange={(e) => setProfileField('name', e.target.value)}
+              />
+            </Grid>
+            <Grid item xs={12}>
+              <TextField
+                fullWidth
+                label="Nessus URL"
+                value={profileDialog.profile.url}
+                helperText="Defaults to https://<host>:8834"
+                onChange={(e) => setProfileField('url', e.target.value)}
+              />
+            </Grid>
+            <Grid item xs={12}>
+              <TextField
+                fullWidth
+                label="SSH Host"
+                value={profileDialog.profile.host}
+                helperText="Needed for discovery scans and exclusions on the Nessus host"
+                onChange={(e) => setProfileField('host', e.target.value)}
+              />
+            </Grid>
+            <Grid item xs={12} md={6}>
+              <TextField
+                fullWidth
+                label="Username"
+                value={profileDialog.profile.username}
+                onChange={(e) => setProfileField('username', e.target.value)}
+              />
+            </Grid>
+            <Grid item xs={12} md={6}>
+              <TextField
+                fullWidth
+                type="password"
+                label="Password"
+                value={profileDialog.profile.password}
+                placeholder={profileDialog.profile.hasPassword ? 'Unchanged' : ''}
+                onChange={(e) => setProfileField('password', e.target.value)}
+              />
+            </Grid>
+            <Grid item xs={12} md={6}>
+              <TextField
+                fullWidth
+                label="Access Key"
+                value={profileDialog.profile.accessKey}
+                placeholder={profileDialog.profile.hasApiKeys ? 'Unchanged' : ''}
+                onChange={(e) => setProfileField('accessKey', e.target.value)}
+              />
+            </Grid>
+            <Grid item xs={12} md={6}>
+              <TextField
+                fullWidth
+                type="password"
+                label="Secret Key"
+                value={profileDialog.profile.secretKey}
+                placeholder={profileDialog.profile.hasApiKeys ? 'Unchanged' : ''}
+                onChange={(e) => setProfileField('secretKey', e.target.value)}
+              />
+            </Grid>
+            <Grid item xs={12}>
+              <TextField
+                fullWidth
+                label="SSH Key File"
+                value={profileDialog.profile.keyFile}
+                onChange={(e) => setProfileField('keyFile', e.target.value)}
+                InputProps={{
+                  endAdornment: (
+                    <InputAdornment position="end">
+                      <IconButton onClick={handleBrowseKey}>
+                        <Folder />
+                      </IconButton>
+                    </InputAdornment>
+                  ),
+                }}
+              />
+            </Grid>
+          </Grid>
+        </DialogContent>
+        <DialogActions>
+          <Button
+            onClick={() => setProfileDialog(prev => ({ ...prev, open: false }))}
+          >
+            Cancel
+          </Button>
+          <Button
+            onClick={handleSaveProfile}
+            variant="contained"
+            disabled={!profileDialog.profile.name}
+          >
+            Save
+          </Button>
+        </DialogActions>
+      </Dialog>
+
+      <Snackbar
+        open={status.open}
+        autoHideDuration={6000}
+        onClose={() => setStatus(prev => ({ ...prev, open: false }))}
+        anchorOrigin={{ vertical: 'bottom', horizontal: 'right' }}
+      >
+        <Alert
+          severity={status.severity}
+          sx={{ width: '100%' }}
+          variant="filled"
+        >
+          {status.message}
+        </Alert>
+      </Snackbar>
+    </Paper>
+  );
+};
+
+export default NessusProfiles;
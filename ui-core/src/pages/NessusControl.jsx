@@ -64,33 +64,6 @@ import {
 } from 'lucide-react';
 import nmbApi from '../api/nmbApi';
 
-// Default credentials
-const DEFAULT_CREDENTIALS = {
-  username: 'bstg',
-  password: 'BulletH@x'
-};
-
-// Drone list
-const DRONE_LIST = [
-  "arkanoid", "asteroids", "bagman", "barbarian", "breakout", "civilization",
-  "contra", "crossbow", "defender", "digdug", "doom", "donkeykong", "duckhunt",
-  "galaga", "gauntlet", "gravitar", "journey", "joust", "jumpman", "junglehunt",
-  "kaboom", "karatechamp", "knockout", "kungfu", "megaman", "meltdown", "metalgear",
-  "millipede", "paperboy", "pitfall", "poleposition", "pong", "pooyan", "popeye",
-  "punchout", "qbert", "rampage", "sinistar", "spaceinvaders", "spyhunter",
-  "streetfighter", "tempest", "tron", "wizardry", "beserk", "zaxxon", "zork",
-  "doubledragon", "gorf", "ballblazer", "centipede", "combat", "hangon", "mario",
-  "foodfight", "excitebike", "jinks", "zelda", "frogger", "battlezone", "freeway",
-  "pacman", "pendrone", "tetris", "sonic", "burgertime", "diablo", "lemmings", "quake", "myst",
-  "fzero", "blasteroids", "outrun", "rampart", "castlevania", "ikari", "halo",
-  "simcity", "warcraft", "starwars", "choplifter", "gunfight", "metroid",
-  "ninjagaiden", "iceclimber", "icehockey", "radracer", "battletoads", "gradius",
-  "ninjaturtles", "fdl-aws-1", "hrd-1", "rsi-aws-1", "evi-lite-1", "evi-lite-2",
-  "hrd-lite-1", "trm-lite-1", "brain", "wily", "eggman", "ganon", "goro", "mantis",
-  "sigma", "wario", "blinky", "goomba", "kefka", "bison", "bowser", "kingboo",
-  "clyde", "mewtwo", "koopa", "akuma"
-];
-
 // Define field requirements for each mode
 const MODE_FIELDS = {
   create: ['projectName', 'targetsFile'],
@@ -120,30 +93,11 @@ const FIELD_CONFIG = {
     browsable: true,
     helperText: 'Select a file containing target IPs or hostnames (one per line)',
   },
-  remoteHost: {
-    label: 'Remote Host',
-    type: 'text',
-    group: 'remote',
-    helperText: 'The drone/server to run the scan from',
-  },
-  remoteUser: {
-    label: 'Remote User',
+  profile: {
+    label: 'Nessus Profile',
     type: 'text',
     group: 'remote',
-    helperText: 'Username for SSH connection',
-  },
-  remotePass: {
-    label: 'Remote Password',
-    type: 'password',
-    group: 'remote',
-    helperText: 'Password for SSH connection',
-  },
-  remoteKey: {
-    label: 'SSH Key File',
-    type: 'file',
-    browsable: true,
-    group: 'remote',
-    helperText: 'Optional: SSH key file for authentication',
+    helperText: 'Nessus server and credentials saved in the vault (see Settings)',
   },
   discovery: {
     label: 'Discovery Mode',
@@ -179,10 +133,7 @@ const NessusControl = () => {
   // Form data state
   const [controlData, setControlData] = useState({
     nessusMode: '',
-    remoteHost: '',
-    remoteUser: 'bstg',
-    remotePass: 'BulletH@x',
-    remoteKey: '',
+    profile: '',
     projectName: '',
     targetsFile: '',
     projectFolder: '',
@@ -206,6 +157,9 @@ const NessusControl = () => {
   // Scans data
   const [existingScans, setExistingScans] = useState([]);
 
+  // Vault profiles, without their secrets
+  const [profiles, setProfiles] = useState([]);
+
   const submitWithData = async (data) => {
     setIsLoading(true);
     try {
@@ -247,8 +201,8 @@ const NessusControl = () => {
       
       // Refresh scans list after a delay
       setTimeout(() => {
-        if (data.remoteHost) {
-          fetchScans(data.remoteHost);
+        if (data.profile) {
+          fetchScans(data.profile);
         }
       }, 2000);
     } catch (error) {
@@ -268,31 +222,23 @@ const NessusControl = () => {
     const handleWebSocketMessage = (data) => {
       console.log('WebSocket message received:', data);
       
-      if (data.type === 'scans_list') {
-        try {
-          const scans = JSON.parse(data.message);
-          setExistingScans(scans);
-        } catch (error) {
-          console.error('Failed to parse scans list:', error);
-        }
-      } else if (data.type === 'scan_update') {
+      if (data.topic === 'nessus.status' && data.data.scans) {
+        setExistingScans(data.data.scans);
+      } else if (data.topic === 'scan.progress') {
         // Update for a specific scan
-        const parts = data.message.split(':');
-        if (parts.length >= 2) {
-          const scanId = parts[0].replace('Scan ', '').trim();
-          setScanUpdates(prev => ({
-            ...prev,
-            [scanId]: data.message
-          }));
-        }
-      } else if (data.type === 'scan_complete') {
-        // Refresh scans when a scan is completed
-        if (controlData.remoteHost) {
-          fetchScans(controlData.remoteHost);
+        setScanUpdates(prev => ({
+          ...prev,
+          [data.data.scanId]: data.data
+        }));
+      } else if (data.topic === 'scan.result') {
+        // Refresh scans when a scan or export is done
+        if (controlData.profile) {
+          fetchScans(controlData.profile);
         }
-      } else {
+        setLogMessages(prev => [...prev.slice(-99), nmbApi.eventToLog(data)]);
+      } else if (data.topic === 'log') {
         // Add other log messages
-        setLogMessages(prev => [...prev.slice(-99), data]);
+        setLogMessages(prev => [...prev.slice(-99), nmbApi.eventToLog(data)]);
       }
     };
     
@@ -308,24 +254,37 @@ const NessusControl = () => {
     };
   }, []);
   
-  // Fetch scans when remote host changes
+  // Load the vault profiles
+  useEffect(() => {
+    const loadProfiles = async () => {
+      try {
+        const response = await nmbApi.getProfiles();
+        setProfiles(response.profiles || []);
+      } catch (error) {
+        setStatus({
+          open: true,
+          message: `${error.message}. Unlock the vault in Settings to use saved profiles.`,
+          severity: 'warning'
+        });
+      }
+    };
+    loadProfiles();
+  }, []);
+
+  // Fetch scans when the profile changes
   useEffect(() => {
-    if (controlData.remoteHost) {
-      fetchScans(controlData.remoteHost);
+    if (controlData.profile) {
+      fetchScans(controlData.profile);
     }
-  }, [controlData.remoteHost]);
+  }, [controlData.profile]);
   
   // Function to fetch scans
-  const fetchScans = async (host) => {
-    if (!host) return;
+  const fetchScans = async (profile) => {
+    if (!profile) return;
     
     setIsLoading(true);
     try {
-      const response = await nmbApi.getScans(
-        host, 
-        controlData.remoteUser, 
-        controlData.remotePass
-      );
+      const response = await nmbApi.getScans(profile);
       
       if (response && response.scans) {
         setExistingScans(response.scans);
@@ -375,15 +334,13 @@ const NessusControl = () => {
     }
   };
 
-  const handleHostChange = (event, newValue) => {
+  const handleProfileChange = (event, newValue) => {
     setControlData(prev => ({
       ...prev,
-      remoteHost: newValue || '',
-      remoteUser: newValue ? DEFAULT_CREDENTIALS.username : '',
-      remotePass: newValue ? DEFAULT_CREDENTIALS.password : ''
+      profile: newValue || ''
     }));
     
-    // Clear existing scans when changing host
+    // Clear existing scans when changing profile
     setExistingScans([]);
     
     // Show loading indicator
@@ -407,8 +364,6 @@ const NessusControl = () => {
       // Call the appropriate Wails function based on type
       if (type === 'directory') {
         result = await window.go.main.App.SelectDirectory();
-      } else if (type === 'key') {
-        result = await window.go.main.App.SelectFile("SSH Key");
       } else {
         result = await window.go.main.App.SelectFile("All Files");
       }
@@ -416,8 +371,7 @@ const NessusControl = () => {
       if (result) {
         const fieldMap = {
           file: 'targetsFile',
-          directory: 'projectFolder',
-          key: 'remoteKey'
+          directory: 'projectFolder'
         };
   
         setControlData(prev => ({
@@ -474,10 +428,10 @@ const NessusControl = () => {
   };
 
   const handleScanAction = async (scanId, action) => {
-    if (!controlData.remoteHost) {
+    if (!controlData.profile) {
       setStatus({
         open: true,
-        message: 'Please select a remote host first',
+        message: 'Please select a Nessus profile first',
         severity: 'warning'
       });
       return;
@@ -488,9 +442,7 @@ const NessusControl = () => {
       const response = await nmbApi.controlScan(
         scanId, 
         action, 
-        controlData.remoteHost,
-        controlData.remoteUser,
-        controlData.remotePass
+        controlData.profile
       );
       
       setStatus({
@@ -501,7 +453,7 @@ const NessusControl = () => {
       
       // Refresh scans after a short delay
       setTimeout(() => {
-        fetchScans(controlData.remoteHost);
+        fetchScans(controlData.profile);
       }, 1000);
       
     } catch (error) {
@@ -516,10 +468,10 @@ const NessusControl = () => {
   };
 
   const handleExportScan = async (scanId) => {
-    if (!controlData.remoteHost) {
+    if (!controlData.profile) {
       setStatus({
         open: true,
-        message: 'Please select a remote host first',
+        message: 'Please select a Nessus profile first',
         severity: 'warning'
       });
       return;
@@ -530,9 +482,7 @@ const NessusControl = () => {
       const response = await nmbApi.controlScan(
         scanId, 
         'export', 
-        controlData.remoteHost,
-        controlData.remoteUser,
-        controlData.remotePass
+        controlData.profile
       );
       
       setStatus({
@@ -552,10 +502,10 @@ const NessusControl = () => {
   };
 
   const handleDeleteScan = async (scanId) => {
-    if (!controlData.remoteHost) {
+    if (!controlData.profile) {
       setStatus({
         open: true,
-        message: 'Please select a remote host first',
+        message: 'Please select a Nessus profile first',
         severity: 'warning'
       });
       return;
@@ -570,9 +520,7 @@ const NessusControl = () => {
       const response = await nmbApi.controlScan(
         scanId, 
         'delete', 
-        controlData.remoteHost,
-        controlData.remoteUser,
-        controlData.remotePass
+        controlData.profile
       );
       
       setStatus({
@@ -582,7 +530,7 @@ const NessusControl = () => {
       });
       
       // Refresh scans
-      fetchScans(controlData.remoteHost);
+      fetchScans(controlData.profile);
       
     } catch (error) {
       setStatus({
@@ -734,7 +682,7 @@ const NessusControl = () => {
                 <InputAdornment position="end">
                   <Tooltip title={`Browse for ${config.label}`}>
                     <IconButton 
-                      onClick={() => handleBrowseFile(fieldName === 'remoteKey' ? 'key' : fieldName === 'projectFolder' ? 'directory' : 'file')}
+                      onClick={() => handleBrowseFile(fieldName === 'projectFolder' ? 'directory' : 'file')}
                       disabled={isLoading}
                     >
                       <Folder />
@@ -747,13 +695,13 @@ const NessusControl = () => {
         );
 
       default:
-        if (fieldName === 'remoteHost') {
+        if (fieldName === 'profile') {
           return (
             <Autocomplete
               fullWidth
-              options={DRONE_LIST}
-              value={controlData.remoteHost}
-              onChange={handleHostChange}
+              options={profiles.map(profile => profile.name)}
+              value={controlData.profile || null}
+              onChange={handleProfileChange}
               renderInput={(params) => (
                 <TextField
                   {...params}
@@ -761,15 +709,22 @@ const NessusControl = () => {
                   label={config.label}
                   required={config.required}
                   helperText={config.helperText}
-                  placeholder="Search for a drone..."
+                  placeholder="Search for a profile..."
                 />
               )}
-              freeSolo
-              renderOption={(props, option) => (
-                <Box component="li" {...props}>
-                  <Typography variant="body1">{option}</Typography>
-                </Box>
-              )}
+              renderOption={(props, option) => {
+                const profile = profiles.find(p => p.name === option) || {};
+                return (
+                  <Box component="li" {...props}>
+                    <Box>
+                      <Typography variant="body1">{option}</Typography>
+                      <Typography variant="caption" color="text.secondary">
+                        {profile.url || profile.host}
+                      </Typography>
+                    </Box>
+                  </Box>
+                );
+              }}
             />
           );
         }
@@ -784,7 +739,7 @@ const NessusControl = () => {
             onChange={handleChange}
             required={config.required}
             helperText={hasError ? fieldErrors[fieldName] : config.helperText}
-            disabled={isLoading || (fieldName === 'remoteUser' || fieldName === 'remotePass' ? !controlData.remoteHost : false)}
+            disabled={isLoading}
             error={hasError}
             inputProps={config.pattern ? { pattern: config.pattern } : undefined}
           />
@@ -802,8 +757,7 @@ const NessusControl = () => {
       modeFields.push('discovery');
     }
   
-    const remoteFields = ['remoteHost', 'remoteUser', 'remotePass', 'remoteKey'];
-    return [...modeFields, ...remoteFields];
+    return [...modeFields, 'profile'];
   };
 
   const renderFormFields = () => {
@@ -871,10 +825,7 @@ const NessusControl = () => {
               Configure how the scan will be executed.
             </Typography>
             
-            {renderField('remoteHost')}
-            {renderField('remoteUser')}
-            {renderField('remotePass')}
-            {renderField('remoteKey')}
+            {renderField('profile')}
             {renderField('projectFolder')}
             
             <Box sx={{ display: 'flex', justifyContent: 'space-between', mt: 2 }}>
@@ -882,7 +833,7 @@ const NessusControl = () => {
               <Button 
                 variant="contained" 
                 onClick={handleNextStep}
-                disabled={!controlData.remoteHost}
+                disabled={!controlData.profile}
               >
                 Next
               </Button>
@@ -921,15 +872,9 @@ const NessusControl = () => {
                   </TableRow>
                   <TableRow>
                     <TableCell component="th" sx={{ fontWeight: 'bold' }}>
-                      Remote Host
-                    </TableCell>
-                    <TableCell>{controlData.remoteHost}</TableCell>
-                  </TableRow>
-                  <TableRow>
-                    <TableCell component="th" sx={{ fontWeight: 'bold' }}>
-                      Remote User
+                      Nessus Profile
                     </TableCell>
-                    <TableCell>{controlData.remoteUser}</TableCell>
+                    <TableCell>{controlData.profile}</TableCell>
                   </TableRow>
                   <TableRow>
                     <TableCell component="th" sx={{ fontWeight: 'bold' }}>
@@ -969,17 +914,17 @@ const NessusControl = () => {
       <Box>
         <Box sx={{ display: 'flex', justifyContent: 'space-between', alignItems: 'center', mb: 3 }}>
           <Typography variant="h6">
-            {controlData.remoteHost ? 
-              `Scans on ${controlData.remoteHost}` : 
-              'Select a host to view scans'}
+            {controlData.profile ? 
+              `Scans on ${controlData.profile}` : 
+              'Select a profile to view scans'}
           </Typography>
           <Box sx={{ display: 'flex', gap: 2 }}>
             <Button 
               variant="outlined" 
               startIcon={<RefreshCw />}
-              onClick={() => fetchScans(controlData.remoteHost)}
+              onClick={() => fetchScans(controlData.profile)}
               size="small"
-              disabled={!controlData.remoteHost || isLoading}
+              disabled={!controlData.profile || isLoading}
             >
               Refresh
             </Button>
@@ -988,19 +933,19 @@ const NessusControl = () => {
               startIcon={<Plus />}
               onClick={() => setActiveTab(1)}
               size="small"
-              disabled={!controlData.remoteHost}
+              disabled={!controlData.profile}
             >
               New Scan
             </Button>
           </Box>
         </Box>
         
-        {!controlData.remoteHost ? (
+        {!controlData.profile ? (
           <Paper sx={{ p: 3, textAlign: 'center' }}>
             <Typography variant="body1" color="text.secondary">
-              Please select a remote host to view and manage scans.
+              Please select a Nessus profile to view and manage scans.
             </Typography>
-            {renderField('remoteHost')}
+            {renderField('profile')}
           </Paper>
         ) : existingScans.length === 0 ? (
           <Paper sx={{ p: 3, textAlign: 'center' }}>
@@ -2,6 +2,9 @@
 import React from 'react';
 import { Box, Container, Typography } from '@mui/material';
 import GeneralSettings from '../components/Settings/GeneralSettings';
+import NessusProfiles from '../components/Settings/NessusProfiles';
+import ApiAccess from '../components/Settings/ApiAccess';
+import nmbApi from '../api/nmbApi';
 
 // Make sure GeneralSettings is exported correctly
 const SettingsPage = () => {
@@ -17,7 +20,9 @@ const SettingsPage = () => {
     >
       <Container maxWidth="lg">
         <Typography variant="h4" sx={{ mb: 4 }}>Settings</Typography>
+        {!nmbApi.isDesktop() && <ApiAccess />}
         <GeneralSettings />
+        <NessusProfiles />
       </Container>
     </Box>
   );
@@ -1,5 +1,6 @@
 // Cynhyrchwyd y ffeil hon yn awtomatig. PEIDIWCH Â MODIWL
 // This file is automatically generated. DO NOT EDIT
+import {api} from '../models';
 import {main} from '../models';
 import {plugin} from '../models';
 
@@ -27,6 +28,8 @@ export function DeleteCategory(arg1:string):Promise<void>;
 
 export function FilterPluginsByName(arg1:string,arg2:string):Promise<Array<plugin.PluginInfo>>;
 
+export function GetAPIConfig():Promise<api.ClientConfig>;
+
 export function GetCSVPath():Promise<string>;
 
 export function GetCategories():Promise<Array<string>>;
@@ -50,6 +50,10 @@ export function FilterPluginsByName(arg1, arg2) {
   return window['go']['main']['App']['FilterPluginsByName'](arg1, arg2);
 }
 
+export function GetAPIConfig() {
+  return window['go']['main']['App']['GetAPIConfig']();
+}
+
 export function GetCSVPath() {
   return window['go']['main']['App']['GetCSVPath']();
 }
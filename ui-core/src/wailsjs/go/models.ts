@@ -1,3 +1,22 @@
+export namespace api {
+	
+	export class ClientConfig {
+	    url: string;
+	    token: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new ClientConfig(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.url = source["url"];
+	        this.token = source["token"];
+	    }
+	}
+
+}
+
 export namespace main {
 	
 	export class BulkUpdateRequest {